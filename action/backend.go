@@ -0,0 +1,215 @@
+package action
+
+import (
+	"fmt"
+	"net"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// 防火墙中使用的表、集合与链名称
+const (
+	nftTable   = "loginfopush"
+	nftSet4    = "blocklist4"
+	nftSet6    = "blocklist6"
+	ipsetName4 = "loginfopush4"
+	ipsetName6 = "loginfopush6"
+	chainName  = "LOGINFOPUSH"
+)
+
+// Runner 执行防火墙命令，测试时可替换为记录命令的实现
+type Runner interface {
+	Run(name string, args ...string) error
+}
+
+// ExecRunner 通过 os/exec 执行命令
+type ExecRunner struct{}
+
+// Run 执行命令，失败时错误中包含命令输出
+func (ExecRunner) Run(name string, args ...string) error {
+	out, err := exec.Command(name, args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("%s %s: %v: %s", name, strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// Backend 防火墙后端
+type Backend interface {
+	// Setup 创建表、集合与规则，重复调用不会产生重复规则
+	Setup() error
+	// Block 封禁 IP，d 为 0 表示不由防火墙自动过期
+	Block(ip string, d time.Duration) error
+	// Unblock 解除封禁，IP 未封禁时不报错
+	Unblock(ip string) error
+	// Expires 防火墙是否会按 Block 的时长自动解除封禁
+	Expires() bool
+}
+
+// NewBackend 按名称创建后端，name 为空时使用 nftables
+func NewBackend(name string, runner Runner) (Backend, error) {
+	switch name {
+	case "", "nftables":
+		return &nftBackend{run: runner}, nil
+	case "ipset":
+		return &ipsetBackend{run: runner}, nil
+	case "iptables":
+		return &iptablesBackend{run: runner}, nil
+	}
+	return nil, fmt.Errorf("未知的防火墙后端: %s", name)
+}
+
+// isIPv6 判断是否为 IPv6 地址
+func isIPv6(ip string) bool {
+	parsed := net.ParseIP(ip)
+	return parsed != nil && parsed.To4() == nil
+}
+
+// seconds 以秒为单位的时长，不足一秒按一秒计
+func seconds(d time.Duration) string {
+	s := int64((d + time.Second - 1) / time.Second)
+	return strconv.FormatInt(s, 10)
+}
+
+// nftBackend 使用 nftables 的带超时集合
+type nftBackend struct {
+	run Runner
+}
+
+func (b *nftBackend) Setup() error {
+	cmds := [][]string{
+		{"add", "table", "inet", nftTable},
+		{"add", "set", "inet", nftTable, nftSet4, "{ type ipv4_addr; flags timeout; }"},
+		{"add", "set", "inet", nftTable, nftSet6, "{ type ipv6_addr; flags timeout; }"},
+		{"add", "chain", "inet", nftTable, "input", "{ type filter hook input priority -10; policy accept; }"},
+		{"flush", "chain", "inet", nftTable, "input"},
+		{"add", "rule", "inet", nftTable, "input", "ip", "saddr", "@" + nftSet4, "drop"},
+		{"add", "rule", "inet", nftTable, "input", "ip6", "saddr", "@" + nftSet6, "drop"},
+	}
+	for _, args := range cmds {
+		if err := b.run.Run("nft", args...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *nftBackend) set(ip string) string {
+	if isIPv6(ip) {
+		return nftSet6
+	}
+	return nftSet4
+}
+
+func (b *nftBackend) Block(ip string, d time.Duration) error {
+	elem := "{ " + ip + " }"
+	if d > 0 {
+		elem = "{ " + ip + " timeout " + seconds(d) + "s }"
+	}
+	// 已存在的元素不会更新超时，先删除再添加
+	b.Unblock(ip)
+	return b.run.Run("nft", "add", "element", "inet", nftTable, b.set(ip), elem)
+}
+
+func (b *nftBackend) Unblock(ip string) error {
+	err := b.run.Run("nft", "delete", "element", "inet", nftTable, b.set(ip), "{ "+ip+" }")
+	if err != nil && strings.Contains(err.Error(), "No such file or directory") {
+		return nil
+	}
+	return err
+}
+
+func (b *nftBackend) Expires() bool { return true }
+
+// ipsetBackend 使用 ipset 集合与一条 iptables 规则
+type ipsetBackend struct {
+	run Runner
+}
+
+func (b *ipsetBackend) Setup() error {
+	sets := []struct {
+		name, family, iptables string
+	}{
+		{ipsetName4, "inet", "iptables"},
+		{ipsetName6, "inet6", "ip6tables"},
+	}
+	for _, s := range sets {
+		if err := b.run.Run("ipset", "create", s.name, "hash:ip", "family", s.family, "timeout", "0", "-exist"); err != nil {
+			return err
+		}
+		rule := []string{"INPUT", "-m", "set", "--match-set", s.name, "src", "-j", "DROP"}
+		if b.run.Run(s.iptables, append([]string{"-C"}, rule...)...) == nil {
+			continue
+		}
+		if err := b.run.Run(s.iptables, append([]string{"-I"}, rule...)...); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *ipsetBackend) set(ip string) string {
+	if isIPv6(ip) {
+		return ipsetName6
+	}
+	return ipsetName4
+}
+
+func (b *ipsetBackend) Block(ip string, d time.Duration) error {
+	return b.run.Run("ipset", "add", b.set(ip), ip, "timeout", seconds(d), "-exist")
+}
+
+func (b *ipsetBackend) Unblock(ip string) error {
+	return b.run.Run("ipset", "del", b.set(ip), ip, "-exist")
+}
+
+func (b *ipsetBackend) Expires() bool { return true }
+
+// iptablesBackend 在独立的链中为每个 IP 添加一条 DROP 规则，过期由 Manager 负责解除
+type iptablesBackend struct {
+	run Runner
+}
+
+func (b *iptablesBackend) Setup() error {
+	for _, cmd := range []string{"iptables", "ip6tables"} {
+		if b.run.Run(cmd, "-n", "-L", chainName) != nil {
+			if err := b.run.Run(cmd, "-N", chainName); err != nil {
+				return err
+			}
+		}
+		if b.run.Run(cmd, "-C", "INPUT", "-j", chainName) == nil {
+			continue
+		}
+		if err := b.run.Run(cmd, "-I", "INPUT", "-j", chainName); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *iptablesBackend) cmd(ip string) string {
+	if isIPv6(ip) {
+		return "ip6tables"
+	}
+	return "iptables"
+}
+
+func (b *iptablesBackend) Block(ip string, d time.Duration) error {
+	cmd := b.cmd(ip)
+	if b.run.Run(cmd, "-C", chainName, "-s", ip, "-j", "DROP") == nil {
+		return nil
+	}
+	return b.run.Run(cmd, "-I", chainName, "-s", ip, "-j", "DROP")
+}
+
+func (b *iptablesBackend) Unblock(ip string) error {
+	cmd := b.cmd(ip)
+	if b.run.Run(cmd, "-C", chainName, "-s", ip, "-j", "DROP") != nil {
+		return nil
+	}
+	return b.run.Run(cmd, "-D", chainName, "-s", ip, "-j", "DROP")
+}
+
+func (b *iptablesBackend) Expires() bool { return false }
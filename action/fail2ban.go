@@ -0,0 +1,55 @@
+package action
+
+import (
+	"fmt"
+	"loginfopush/fail2ban"
+	"strings"
+	"time"
+)
+
+// defaultJail fail2ban 后端默认使用的 jail
+const defaultJail = "sshd"
+
+// fail2banBackend 通过 fail2ban 控制套接字在 jail 中封禁与解封，由 fail2ban 的动作操作防火墙
+//
+// 封禁时长以 Manager 的 bantime 为准，到期后由 Manager 解封，jail 的 bantime 更短时 fail2ban 会提前解封。
+type fail2banBackend struct {
+	client *fail2ban.Client
+	jail   string
+}
+
+// NewFail2banBackend 创建 fail2ban 后端，jail 为空时使用 sshd
+func NewFail2banBackend(client *fail2ban.Client, jail string) Backend {
+	if jail == "" {
+		jail = defaultJail
+	}
+	return &fail2banBackend{client: client, jail: jail}
+}
+
+func (b *fail2banBackend) Setup() error {
+	if err := b.client.Ping(); err != nil {
+		return err
+	}
+	if _, err := b.client.JailStatus(b.jail); err != nil {
+		return fmt.Errorf("fail2ban jail %s 不可用: %v", b.jail, err)
+	}
+	return nil
+}
+
+func (b *fail2banBackend) Block(ip string, d time.Duration) error {
+	return b.client.BanIP(b.jail, ip)
+}
+
+func (b *fail2banBackend) Unblock(ip string) error {
+	err := b.client.UnbanIP(b.jail, ip)
+	if err != nil && strings.Contains(err.Error(), "not banned") {
+		// fail2ban 已按 jail 的 bantime 自行解封
+		return nil
+	}
+	return err
+}
+
+func (b *fail2banBackend) Expires() bool { return false }
+
+// Jail 封禁使用的 jail，fail2ban 日志中该 jail 的对应记录由 Manager.Echo 识别
+func (b *fail2banBackend) Jail() string { return b.jail }
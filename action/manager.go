@@ -0,0 +1,387 @@
+package action
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"loginfopush/config"
+	"loginfopush/logger"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// 默认值
+const (
+	defaultThreshold = 5
+	defaultWindow    = 10 * time.Minute
+	defaultBanTime   = time.Hour
+	defaultStateFile = "bans.json" // 位于默认状态目录下
+	expireInterval   = 30 * time.Second
+	echoWindow       = time.Minute // fail2ban 日志中的回显须在操作后的这段时间内出现
+)
+
+// Ban 一条封禁记录
+type Ban struct {
+	IP       string    `json:"ip"`
+	Reason   string    `json:"reason"`
+	Attempts int       `json:"attempts"`
+	Start    time.Time `json:"start"`
+	Until    time.Time `json:"until"`
+}
+
+// Manager 统计登录失败并在超过阈值时封禁来源 IP
+type Manager struct {
+	mu        sync.Mutex
+	backend   Backend
+	threshold int
+	window    time.Duration
+	banTime   time.Duration
+	allow     []*net.IPNet
+	stateFile string
+	failures  map[string][]time.Time
+	bans      map[string]*Ban
+	echoes    map[string]time.Time // 通过 fail2ban 后端发起的封禁与解封，键为 "ban IP" 或 "unban IP"
+	onExpire  func(Ban)
+}
+
+// jailBackend 在 fail2ban jail 中封禁的后端，每次封禁与解封都会在 fail2ban 日志中产生对应的记录
+type jailBackend interface {
+	Jail() string
+}
+
+// NewManager 按配置创建封禁管理器，runner 为空时直接执行系统命令
+func NewManager(cfg *config.ActionConfig, runner Runner) (*Manager, error) {
+	if runner == nil {
+		runner = ExecRunner{}
+	}
+	backend, err := NewBackend(cfg.Backend, runner)
+	if err != nil {
+		return nil, err
+	}
+	return NewManagerWithBackend(cfg, backend)
+}
+
+// NewManagerWithBackend 使用指定的防火墙后端创建封禁管理器，如 NewFail2banBackend
+func NewManagerWithBackend(cfg *config.ActionConfig, backend Backend) (*Manager, error) {
+	m := &Manager{
+		backend:   backend,
+		threshold: cfg.Threshold,
+		window:    time.Duration(cfg.Window),
+		banTime:   time.Duration(cfg.BanTime),
+		stateFile: cfg.StateFile,
+		failures:  make(map[string][]time.Time),
+		bans:      make(map[string]*Ban),
+		echoes:    make(map[string]time.Time),
+	}
+	if m.threshold == 0 {
+		m.threshold = defaultThreshold
+	}
+	if m.window == 0 {
+		m.window = defaultWindow
+	}
+	if m.banTime == 0 {
+		m.banTime = defaultBanTime
+	}
+	if m.stateFile == "" {
+		m.stateFile = filepath.Join(config.DefaultStateDir, defaultStateFile)
+	}
+
+	for _, s := range cfg.Allow {
+		_, ipNet, err := config.ParseCIDR(s)
+		if err != nil {
+			return nil, err
+		}
+		m.allow = append(m.allow, ipNet)
+	}
+	m.allow = append(m.allow, localNetworks()...)
+	return m, nil
+}
+
+// OnExpire 设置封禁到期解除后的回调
+func (m *Manager) OnExpire(fn func(Ban)) {
+	m.onExpire = fn
+}
+
+// Start 初始化防火墙并恢复保存的封禁，随后定期解除到期的封禁直到 stop 关闭
+func (m *Manager) Start(stop <-chan struct{}) error {
+	if err := m.backend.Setup(); err != nil {
+		return fmt.Errorf("初始化防火墙失败: %v", err)
+	}
+	if err := m.restore(time.Now()); err != nil {
+		return err
+	}
+
+	go func() {
+		ticker := time.NewTicker(expireInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case now := <-ticker.C:
+				m.expire(now)
+			}
+		}
+	}()
+	return nil
+}
+
+// RecordFailure 记录一次登录失败，达到阈值时封禁并返回封禁记录
+func (m *Manager) RecordFailure(ip, reason string, now time.Time) (*Ban, error) {
+	if net.ParseIP(ip) == nil || m.Allowed(ip) {
+		return nil, nil
+	}
+
+	m.mu.Lock()
+	if _, banned := m.bans[ip]; banned {
+		m.mu.Unlock()
+		return nil, nil
+	}
+	times := m.failures[ip][:0]
+	for _, t := range m.failures[ip] {
+		if now.Sub(t) < m.window {
+			times = append(times, t)
+		}
+	}
+	times = append(times, now)
+	m.failures[ip] = times
+	attempts := len(times)
+	m.mu.Unlock()
+
+	if attempts < m.threshold {
+		return nil, nil
+	}
+	return m.Ban(ip, reason, attempts, now)
+}
+
+// Ban 封禁 IP，本机地址与白名单中的 IP 不会被封禁
+func (m *Manager) Ban(ip, reason string, attempts int, now time.Time) (*Ban, error) {
+	if net.ParseIP(ip) == nil {
+		return nil, fmt.Errorf("无效的 IP 地址 %q", ip)
+	}
+	if m.Allowed(ip) {
+		return nil, fmt.Errorf("IP %s 在白名单中，不会被封禁", ip)
+	}
+	ban := &Ban{
+		IP:       ip,
+		Reason:   reason,
+		Attempts: attempts,
+		Start:    now,
+		Until:    now.Add(m.banTime),
+	}
+	m.mu.Lock()
+	m.expectEcho("ban", ip, now)
+	m.mu.Unlock()
+	if err := m.backend.Block(ip, m.banTime); err != nil {
+		return nil, fmt.Errorf("封禁 IP %s 失败: %v", ip, err)
+	}
+
+	m.mu.Lock()
+	delete(m.failures, ip)
+	m.bans[ip] = ban
+	err := m.save()
+	m.mu.Unlock()
+
+	result := *ban
+	return &result, err
+}
+
+// Unban 解除封禁
+func (m *Manager) Unban(ip string) error {
+	m.mu.Lock()
+	m.expectEcho("unban", ip, time.Now())
+	m.mu.Unlock()
+	if err := m.backend.Unblock(ip); err != nil {
+		return fmt.Errorf("解封 IP %s 失败: %v", ip, err)
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	delete(m.bans, ip)
+	return m.save()
+}
+
+// Bans 当前的封禁列表，按开始时间排序
+func (m *Manager) Bans() []Ban {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	bans := make([]Ban, 0, len(m.bans))
+	for _, b := range m.bans {
+		bans = append(bans, *b)
+	}
+	sort.Slice(bans, func(i, j int) bool { return bans[i].Start.Before(bans[j].Start) })
+	return bans
+}
+
+// Allowed 判断 IP 是否受白名单保护
+func (m *Manager) Allowed(ip string) bool {
+	parsed := net.ParseIP(ip)
+	if parsed == nil {
+		return false
+	}
+	for _, ipNet := range m.allow {
+		if ipNet.Contains(parsed) {
+			return true
+		}
+	}
+	return false
+}
+
+// expire 解除到期的封禁
+func (m *Manager) expire(now time.Time) {
+	var expired []Ban
+	m.mu.Lock()
+	for ip, b := range m.bans {
+		if !now.Before(b.Until) {
+			expired = append(expired, *b)
+			delete(m.bans, ip)
+			if !m.backend.Expires() {
+				m.expectEcho("unban", ip, now)
+			}
+		}
+	}
+	for key, t := range m.echoes {
+		if now.Sub(t) >= echoWindow {
+			delete(m.echoes, key)
+		}
+	}
+	for ip, times := range m.failures {
+		if len(times) == 0 || now.Sub(times[len(times)-1]) >= m.window {
+			delete(m.failures, ip)
+		}
+	}
+	if len(expired) > 0 {
+		if err := m.save(); err != nil {
+			logger.Warnf("%v", err)
+		}
+	}
+	m.mu.Unlock()
+
+	for _, b := range expired {
+		// nftables 与 ipset 的集合元素会自行过期
+		if !m.backend.Expires() {
+			if err := m.backend.Unblock(b.IP); err != nil {
+				logger.Warnf("解封 IP %s 失败: %v", b.IP, err)
+				continue
+			}
+		}
+		if m.onExpire != nil {
+			m.onExpire(b)
+		}
+	}
+}
+
+// restore 从封禁列表恢复未到期的封禁，启动前重建的防火墙规则中已不包含这些 IP
+func (m *Manager) restore(now time.Time) error {
+	data, err := ioutil.ReadFile(m.stateFile)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("读取封禁列表失败: %v", err)
+	}
+	var bans []Ban
+	if err := json.Unmarshal(data, &bans); err != nil {
+		return fmt.Errorf("解析封禁列表 %s 失败: %v", m.stateFile, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	for i := range bans {
+		b := bans[i]
+		if !now.Before(b.Until) {
+			if !m.backend.Expires() {
+				m.backend.Unblock(b.IP)
+			}
+			continue
+		}
+		if m.Allowed(b.IP) {
+			m.backend.Unblock(b.IP)
+			continue
+		}
+		m.expectEcho("ban", b.IP, now)
+		if err := m.backend.Block(b.IP, b.Until.Sub(now)); err != nil {
+			logger.Warnf("恢复封禁 IP %s 失败: %v", b.IP, err)
+			continue
+		}
+		m.bans[b.IP] = &b
+	}
+	return m.save()
+}
+
+// expectEcho 使用 fail2ban 后端时记录即将发起的封禁或解封，调用方需持有锁
+func (m *Manager) expectEcho(action, ip string, now time.Time) {
+	if _, ok := m.backend.(jailBackend); ok {
+		m.echoes[action+" "+ip] = now
+	}
+}
+
+// Echo 判断 fail2ban 日志中 jail 的封禁或解封（action 为 ban 或 unban）是否由本管理器通过 fail2ban 后端发起。
+// 这些操作已产生过 ban、unban 事件，每次操作只匹配一条日志
+func (m *Manager) Echo(jail, action, ip string, now time.Time) bool {
+	b, ok := m.backend.(jailBackend)
+	if !ok || b.Jail() != jail {
+		return false
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	key := action + " " + ip
+	t, ok := m.echoes[key]
+	if !ok {
+		return false
+	}
+	delete(m.echoes, key)
+	return now.Sub(t) < echoWindow
+}
+
+// save 保存封禁列表，调用方需持有锁
+func (m *Manager) save() error {
+	bans := make([]Ban, 0, len(m.bans))
+	for _, b := range m.bans {
+		bans = append(bans, *b)
+	}
+	sort.Slice(bans, func(i, j int) bool { return bans[i].Start.Before(bans[j].Start) })
+
+	data, err := json.MarshalIndent(bans, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(m.stateFile), 0755); err != nil {
+		return fmt.Errorf("保存封禁列表失败: %v", err)
+	}
+	tmp := m.stateFile + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("保存封禁列表失败: %v", err)
+	}
+	if err := os.Rename(tmp, m.stateFile); err != nil {
+		return fmt.Errorf("保存封禁列表失败: %v", err)
+	}
+	return nil
+}
+
+// localNetworks 本机回环与网卡地址，防止封禁自己
+func localNetworks() []*net.IPNet {
+	nets := []*net.IPNet{
+		{IP: net.IPv4(127, 0, 0, 0), Mask: net.CIDRMask(8, 32)},
+		{IP: net.IPv6loopback, Mask: net.CIDRMask(128, 128)},
+	}
+	addrs, err := net.InterfaceAddrs()
+	if err != nil {
+		return nets
+	}
+	for _, addr := range addrs {
+		ipNet, ok := addr.(*net.IPNet)
+		if !ok {
+			continue
+		}
+		bits := 32
+		if ipNet.IP.To4() == nil {
+			bits = 128
+		}
+		nets = append(nets, &net.IPNet{IP: ipNet.IP, Mask: net.CIDRMask(bits, bits)})
+	}
+	return nets
+}
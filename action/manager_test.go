@@ -0,0 +1,358 @@
+package action
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"loginfopush/config"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recordingRunner 记录执行的命令，并模拟 iptables 的规则检查：-C/-L 在规则存在时成功，-I/-N 添加，-D 删除
+type recordingRunner struct {
+	mu    sync.Mutex
+	cmds  []string
+	rules map[string]bool
+}
+
+func newRecordingRunner() *recordingRunner {
+	return &recordingRunner{rules: make(map[string]bool)}
+}
+
+func (r *recordingRunner) Run(name string, args ...string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.cmds = append(r.cmds, name+" "+strings.Join(args, " "))
+
+	if len(args) == 0 {
+		return nil
+	}
+	key := name + " " + strings.Join(args[1:], " ")
+	switch args[0] {
+	case "-C":
+		if !r.rules[key] {
+			return errNotFound
+		}
+	case "-n":
+		if !r.rules[name+" "+strings.Join(args[2:], " ")] {
+			return errNotFound
+		}
+	case "-I", "-N":
+		r.rules[key] = true
+	case "-D":
+		delete(r.rules, key)
+	}
+	return nil
+}
+
+// take 返回并清空已记录的命令
+func (r *recordingRunner) take() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	cmds := r.cmds
+	r.cmds = nil
+	return cmds
+}
+
+// errNotFound 模拟规则不存在时 iptables 的非零退出
+var errNotFound = &runError{"rule does not exist"}
+
+type runError struct{ msg string }
+
+func (e *runError) Error() string { return e.msg }
+
+// newTestManager 创建使用 iptables 后端与临时封禁列表的管理器
+func newTestManager(t *testing.T, cfg config.ActionConfig) (*Manager, *recordingRunner) {
+	t.Helper()
+	if cfg.Backend == "" {
+		cfg.Backend = "iptables"
+	}
+	if cfg.StateFile == "" {
+		cfg.StateFile = filepath.Join(t.TempDir(), "bans.json")
+	}
+	runner := newRecordingRunner()
+	m, err := NewManager(&cfg, runner)
+	if err != nil {
+		t.Fatalf("NewManager() = %v", err)
+	}
+	return m, runner
+}
+
+// readState 读取封禁列表文件
+func readState(t *testing.T, path string) []Ban {
+	t.Helper()
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatalf("读取封禁列表失败: %v", err)
+	}
+	var bans []Ban
+	if err := json.Unmarshal(data, &bans); err != nil {
+		t.Fatalf("解析封禁列表失败: %v", err)
+	}
+	return bans
+}
+
+func TestRecordFailureThreshold(t *testing.T) {
+	m, runner := newTestManager(t, config.ActionConfig{
+		Threshold: 3,
+		Window:    config.Duration(10 * time.Minute),
+		BanTime:   config.Duration(time.Hour),
+	})
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	ip := "203.0.113.7"
+
+	for i := 0; i < 2; i++ {
+		ban, err := m.RecordFailure(ip, "ssh", now.Add(time.Duration(i)*time.Minute))
+		if ban != nil || err != nil {
+			t.Fatalf("第 %d 次失败: ban = %v, err = %v", i+1, ban, err)
+		}
+	}
+	if cmds := runner.take(); len(cmds) != 0 {
+		t.Fatalf("未达到阈值时执行了命令: %q", cmds)
+	}
+
+	ban, err := m.RecordFailure(ip, "ssh", now.Add(2*time.Minute))
+	if err != nil || ban == nil {
+		t.Fatalf("第 3 次失败: ban = %v, err = %v", ban, err)
+	}
+	if ban.Attempts != 3 || !ban.Until.Equal(now.Add(2*time.Minute+time.Hour)) {
+		t.Errorf("ban = %+v", ban)
+	}
+	want := []string{
+		"iptables -C LOGINFOPUSH -s 203.0.113.7 -j DROP",
+		"iptables -I LOGINFOPUSH -s 203.0.113.7 -j DROP",
+	}
+	if cmds := runner.take(); !reflect.DeepEqual(cmds, want) {
+		t.Errorf("commands = %q, want %q", cmds, want)
+	}
+
+	// 已封禁的 IP 不再计数
+	if ban, _ := m.RecordFailure(ip, "ssh", now.Add(3*time.Minute)); ban != nil {
+		t.Errorf("已封禁的 IP 再次封禁: %+v", ban)
+	}
+}
+
+func TestRecordFailureWindow(t *testing.T) {
+	m, runner := newTestManager(t, config.ActionConfig{
+		Threshold: 3,
+		Window:    config.Duration(10 * time.Minute),
+	})
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	// 每 6 分钟一次失败，窗口内最多两次，不会封禁
+	for i := 0; i < 5; i++ {
+		ban, err := m.RecordFailure("203.0.113.8", "ssh", now.Add(time.Duration(i)*6*time.Minute))
+		if ban != nil || err != nil {
+			t.Fatalf("第 %d 次失败: ban = %v, err = %v", i+1, ban, err)
+		}
+	}
+	if cmds := runner.take(); len(cmds) != 0 {
+		t.Errorf("窗口外的失败被累计: %q", cmds)
+	}
+}
+
+func TestAllowlist(t *testing.T) {
+	m, runner := newTestManager(t, config.ActionConfig{
+		Threshold: 1,
+		Allow:     []string{"192.0.2.0/24", "2001:db8::1"},
+	})
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+
+	for _, ip := range []string{"192.0.2.44", "2001:db8::1", "127.0.0.1", "::1", "not-an-ip"} {
+		if ban, err := m.RecordFailure(ip, "ssh", now); ban != nil || err != nil {
+			t.Errorf("RecordFailure(%s) = %v, %v", ip, ban, err)
+		}
+		if _, err := m.Ban(ip, "manual", 0, now); err == nil {
+			t.Errorf("Ban(%s) 没有返回错误", ip)
+		}
+	}
+	if cmds := runner.take(); len(cmds) != 0 {
+		t.Errorf("白名单 IP 执行了命令: %q", cmds)
+	}
+
+	if ban, err := m.RecordFailure("198.51.100.1", "ssh", now); err != nil || ban == nil {
+		t.Errorf("白名单外的 IP 未被封禁: %v, %v", ban, err)
+	}
+}
+
+func TestExpireUnblocksIptables(t *testing.T) {
+	m, runner := newTestManager(t, config.ActionConfig{
+		Threshold: 1,
+		BanTime:   config.Duration(time.Hour),
+	})
+	var expired []Ban
+	m.OnExpire(func(b Ban) { expired = append(expired, b) })
+
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	if _, err := m.RecordFailure("203.0.113.9", "ssh", now); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := m.RecordFailure("2001:db8::9", "ssh", now.Add(30*time.Minute)); err != nil {
+		t.Fatal(err)
+	}
+	runner.take()
+
+	// 第一个封禁到期，第二个未到期
+	m.expire(now.Add(time.Hour))
+	want := []string{
+		"iptables -C LOGINFOPUSH -s 203.0.113.9 -j DROP",
+		"iptables -D LOGINFOPUSH -s 203.0.113.9 -j DROP",
+	}
+	if cmds := runner.take(); !reflect.DeepEqual(cmds, want) {
+		t.Errorf("commands = %q, want %q", cmds, want)
+	}
+	if len(expired) != 1 || expired[0].IP != "203.0.113.9" {
+		t.Errorf("expired = %+v", expired)
+	}
+	if bans := m.Bans(); len(bans) != 1 || bans[0].IP != "2001:db8::9" {
+		t.Errorf("Bans() = %+v", bans)
+	}
+	if bans := readState(t, m.stateFile); len(bans) != 1 || bans[0].IP != "2001:db8::9" {
+		t.Errorf("封禁列表 = %+v", bans)
+	}
+
+	m.expire(now.Add(2 * time.Hour))
+	want = []string{
+		"ip6tables -C LOGINFOPUSH -s 2001:db8::9 -j DROP",
+		"ip6tables -D LOGINFOPUSH -s 2001:db8::9 -j DROP",
+	}
+	if cmds := runner.take(); !reflect.DeepEqual(cmds, want) {
+		t.Errorf("commands = %q, want %q", cmds, want)
+	}
+	if bans := readState(t, m.stateFile); len(bans) != 0 {
+		t.Errorf("封禁列表 = %+v", bans)
+	}
+}
+
+func TestRestore(t *testing.T) {
+	stateFile := filepath.Join(t.TempDir(), "state", "bans.json")
+	now := time.Now()
+	saved := []Ban{
+		{IP: "203.0.113.10", Attempts: 5, Start: now.Add(-time.Minute), Until: now.Add(time.Hour)},
+		{IP: "203.0.113.11", Attempts: 5, Start: now.Add(-2 * time.Hour), Until: now.Add(-time.Hour)},
+		{IP: "192.0.2.12", Attempts: 5, Start: now.Add(-time.Minute), Until: now.Add(time.Hour)},
+	}
+	m, runner := newTestManager(t, config.ActionConfig{
+		Backend:   "nftables",
+		StateFile: stateFile,
+		Allow:     []string{"192.0.2.0/24"},
+	})
+	if err := os.MkdirAll(filepath.Dir(stateFile), 0755); err != nil {
+		t.Fatal(err)
+	}
+	data, _ := json.Marshal(saved)
+	if err := ioutil.WriteFile(stateFile, data, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	stop := make(chan struct{})
+	defer close(stop)
+	if err := m.Start(stop); err != nil {
+		t.Fatalf("Start() = %v", err)
+	}
+
+	var blocked []string
+	for _, cmd := range runner.take() {
+		if strings.HasPrefix(cmd, "nft add element") {
+			blocked = append(blocked, cmd)
+		}
+	}
+	if len(blocked) != 1 || !strings.Contains(blocked[0], "{ 203.0.113.10 timeout ") {
+		t.Errorf("恢复的封禁 = %q，只应恢复未到期且不在白名单中的 IP", blocked)
+	}
+	if bans := m.Bans(); len(bans) != 1 || bans[0].IP != "203.0.113.10" {
+		t.Errorf("Bans() = %+v", bans)
+	}
+	if bans := readState(t, stateFile); len(bans) != 1 || bans[0].IP != "203.0.113.10" {
+		t.Errorf("封禁列表 = %+v", bans)
+	}
+}
+
+func TestRestoreMissingStateFile(t *testing.T) {
+	m, _ := newTestManager(t, config.ActionConfig{})
+	if err := m.restore(time.Now()); err != nil {
+		t.Fatalf("restore() = %v", err)
+	}
+	if bans := m.Bans(); len(bans) != 0 {
+		t.Errorf("Bans() = %+v", bans)
+	}
+}
+
+// jailRecorder 模拟 fail2ban 后端，封禁与解封都会在 fail2ban 日志中留下记录
+type jailRecorder struct {
+	jail string
+}
+
+func (b *jailRecorder) Setup() error                           { return nil }
+func (b *jailRecorder) Block(ip string, d time.Duration) error { return nil }
+func (b *jailRecorder) Unblock(ip string) error                { return nil }
+func (b *jailRecorder) Expires() bool                          { return false }
+func (b *jailRecorder) Jail() string                           { return b.jail }
+
+func TestEcho(t *testing.T) {
+	m, err := NewManagerWithBackend(&config.ActionConfig{
+		Threshold: 1,
+		BanTime:   config.Duration(time.Hour),
+		StateFile: filepath.Join(t.TempDir(), "bans.json"),
+	}, &jailRecorder{jail: "sshd"})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Date(2024, 1, 1, 10, 0, 0, 0, time.UTC)
+	if _, err := m.RecordFailure("203.0.113.9", "ssh", now); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name   string
+		jail   string
+		action string
+		ip     string
+		at     time.Time
+		want   bool
+	}{
+		{"其他 jail", "recidive", "ban", "203.0.113.9", now, false},
+		{"其他 IP", "sshd", "ban", "203.0.113.10", now, false},
+		{"尚未解封", "sshd", "unban", "203.0.113.9", now, false},
+		{"封禁的回显", "sshd", "ban", "203.0.113.9", now.Add(time.Second), true},
+		// 每次操作只匹配一条日志，之后 fail2ban 自己的封禁照常通知
+		{"重复的封禁", "sshd", "ban", "203.0.113.9", now.Add(2 * time.Second), false},
+	}
+	for _, tt := range tests {
+		if got := m.Echo(tt.jail, tt.action, tt.ip, tt.at); got != tt.want {
+			t.Errorf("%s: Echo(%s, %s, %s) = %v, want %v", tt.name, tt.jail, tt.action, tt.ip, got, tt.want)
+		}
+	}
+
+	// 到期解封后 fail2ban 记录的 Unban 同样是回显
+	m.expire(now.Add(time.Hour))
+	if !m.Echo("sshd", "unban", "203.0.113.9", now.Add(time.Hour+time.Second)) {
+		t.Error("到期解封的回显没有被识别")
+	}
+
+	// 超过时间窗口的记录不再匹配
+	if _, err := m.Ban("203.0.113.11", "manual", 0, now); err != nil {
+		t.Fatal(err)
+	}
+	if m.Echo("sshd", "ban", "203.0.113.11", now.Add(echoWindow)) {
+		t.Error("超过时间窗口的记录被识别为回显")
+	}
+}
+
+func TestEchoOtherBackends(t *testing.T) {
+	m, _ := newTestManager(t, config.ActionConfig{Threshold: 1})
+	now := time.Now()
+	if _, err := m.RecordFailure("203.0.113.9", "ssh", now); err != nil {
+		t.Fatal(err)
+	}
+	// 防火墙后端的封禁不会出现在 fail2ban 日志中
+	if m.Echo("sshd", "ban", "203.0.113.9", now) || len(m.echoes) != 0 {
+		t.Errorf("iptables 后端记录了回显: %v", m.echoes)
+	}
+}
@@ -1,53 +1,202 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"loginfopush/config"
 	_func "loginfopush/func"
+	"loginfopush/func/monitors"
+	"loginfopush/logger"
 	"os"
 	"os/signal"
+	"runtime"
+	"strings"
 	"syscall"
 )
 
+// version 版本号，构建时通过 -ldflags "-X main.version=v1.2.3" 设置
+var version = "dev"
+
+// usage 帮助信息
+const usage = `用法: loginfopush [命令] [选项]
+
+命令:
+  run          运行监控服务（默认）
+  validate     检查配置文件
+  events       查询事件历史
+  replay       回放日志文件，查看会产生的事件与消息
+  test-notify  发送示例事件，检查各通知渠道是否可用
+  version      显示版本信息
+
+通用选项:
+  -c, --config     配置文件路径，默认依次查找:
+                   $XDG_CONFIG_HOME/loginfopush/config.json
+                   /etc/loginfopush/config.json
+                   ./config/config.json
+                   <程序所在目录>/config/config.json
+  --state-dir      状态目录，保存封禁列表与事件历史，默认 /var/lib/loginfopush
+  --log-level      日志级别: debug、info、warn、error，默认 info
+
+使用 "loginfopush <命令> -h" 查看命令的选项。
+`
+
+// command 子命令
+type command struct {
+	run  func(args []string) error
+	fail string // 失败时的提示
+}
+
+// commands 子命令列表
+var commands = map[string]command{
+	"run":         {runDaemon, "运行监控服务失败"},
+	"validate":    {runValidate, "配置检查未通过"},
+	"events":      {runEvents, "查询事件失败"},
+	"replay":      {runReplay, "回放日志失败"},
+	"test-notify": {runTestNotify, "测试通知失败"},
+	"version":     {runVersion, "显示版本失败"},
+}
+
 func main() {
+	name, args := "run", os.Args[1:]
+	if len(args) > 0 {
+		switch {
+		case args[0] == "help" || args[0] == "-h" || args[0] == "--help":
+			fmt.Print(usage)
+			return
+		case !strings.HasPrefix(args[0], "-"):
+			name, args = args[0], args[1:]
+		}
+	}
+
+	cmd, ok := commands[name]
+	if !ok {
+		fmt.Fprintf(os.Stderr, "未知的命令: %s\n\n%s", name, usage)
+		os.Exit(2)
+	}
+	if err := cmd.run(args); err != nil {
+		if err == flag.ErrHelp {
+			return
+		}
+		fmt.Fprintf(os.Stderr, "%s: %v\n", cmd.fail, err)
+		os.Exit(1)
+	}
+}
+
+// globalFlags 各子命令共用的选项
+type globalFlags struct {
+	config   string
+	stateDir string
+	logLevel string
+}
+
+// register 在子命令的 FlagSet 上注册通用选项
+func (g *globalFlags) register(fs *flag.FlagSet) {
+	fs.StringVar(&g.config, "c", "", "配置文件路径")
+	fs.StringVar(&g.config, "config", "", "配置文件路径")
+	fs.StringVar(&g.stateDir, "state-dir", "", "状态目录，默认 /var/lib/loginfopush")
+	fs.StringVar(&g.logLevel, "log-level", "info", "日志级别: debug、info、warn、error")
+}
+
+// load 设置日志级别并加载配置，--state-dir 优先于配置文件中的 state_dir
+func (g *globalFlags) load() (*config.Config, string, error) {
+	if err := logger.SetLevel(g.logLevel); err != nil {
+		return nil, "", err
+	}
+	path := g.config
+	if path == "" {
+		found, err := config.FindConfig()
+		if err != nil {
+			return nil, "", err
+		}
+		path = found
+	}
+	cfg, err := config.LoadConfig(path)
+	if err != nil {
+		return nil, "", fmt.Errorf("加载配置失败: %v", err)
+	}
+	if g.stateDir != "" {
+		cfg.StateDir = g.stateDir
+	}
+	return cfg, path, nil
+}
+
+// newFlagSet 创建子命令的 FlagSet 并注册通用选项
+func newFlagSet(name, help string, g *globalFlags) *flag.FlagSet {
+	fs := flag.NewFlagSet(name, flag.ContinueOnError)
+	fs.Usage = func() {
+		fmt.Fprint(fs.Output(), help)
+		fs.PrintDefaults()
+	}
+	g.register(fs)
+	return fs
+}
+
+// runDaemon 执行 run 子命令，运行监控服务直到收到退出信号
+func runDaemon(args []string) error {
+	var g globalFlags
+	fs := newFlagSet("run", "用法: loginfopush run [选项]\n\n运行监控服务。\n\n选项:\n", &g)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
 	// 加载配置文件
-	cfg, err := config.LoadConfig("")
+	cfg, path, err := g.load()
 	if err != nil {
-		fmt.Printf("加载配置失败: %v\n", err)
-		os.Exit(1)
+		return err
 	}
+	logger.Infof("使用配置文件: %s", path)
 
 	// 初始化监控系统
 	if err := _func.InitMonitor(cfg); err != nil {
-		fmt.Printf("初始化监控系统失败: %v\n", err)
-		os.Exit(1)
+		return fmt.Errorf("初始化监控系统失败: %v", err)
 	}
 
 	// 创建一个通道来接收系统信号
 	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
 
-	// 启动错误通道
-	errChan := make(chan error)
+	// 启动监控
+	if err := _func.StartMonitor(); err != nil {
+		return err
+	}
 
-	// 在后台启动监控
-	go func() {
-		errChan <- _func.StartMonitor()
-	}()
+	logger.Infof("F2B 监控服务已启动...")
+	logger.Infof("按 Ctrl+C 停止服务")
 
-	fmt.Println("F2B 监控服务已启动...")
-	fmt.Println("按 Ctrl+C 停止服务")
+	// 等待退出信号，停止监控并发送尚未发送的消息
+	sig := <-sigChan
+	logger.Infof("收到信号 %v，正在停止服务...", sig)
+	_func.StopMonitor()
 
-	// 等待信号或错误
-	select {
-	case err := <-errChan:
-		if err != nil {
-			fmt.Printf("监控服务发生错误: %v\n", err)
-			os.Exit(1)
-		}
-	case sig := <-sigChan:
-		fmt.Printf("\n收到信号 %v，正在停止服务...\n", sig)
+	logger.Infof("服务已停止")
+	return nil
+}
+
+// runValidate 执行 validate 子命令，检查配置文件能否加载
+func runValidate(args []string) error {
+	var g globalFlags
+	fs := newFlagSet("validate", "用法: loginfopush validate [选项]\n\n检查配置文件。\n\n选项:\n", &g)
+	if err := fs.Parse(args); err != nil {
+		return err
 	}
 
-	fmt.Println("服务已停止")
+	cfg, path, err := g.load()
+	if err != nil {
+		return err
+	}
+	if _, err := monitors.CustomLogConfigs(cfg); err != nil {
+		return err
+	}
+	fmt.Printf("配置文件 %s 检查通过\n", path)
+	return nil
+}
+
+// runVersion 执行 version 子命令
+func runVersion(args []string) error {
+	fs := flag.NewFlagSet("version", flag.ContinueOnError)
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	fmt.Printf("loginfopush %s (%s %s/%s)\n", version, runtime.Version(), runtime.GOOS, runtime.GOARCH)
+	return nil
 }
@@ -0,0 +1,67 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"strings"
+)
+
+// ActionBackends 支持的防火墙后端
+var ActionBackends = []string{"nftables", "ipset", "iptables", ActionBackendFail2ban}
+
+// ActionBackendFail2ban 通过 fail2ban 控制套接字封禁，套接字路径与超时使用 fail2ban 配置
+const ActionBackendFail2ban = "fail2ban"
+
+// validateAction 校验主动封禁配置
+func validateAction(c *Config, v *validator) {
+	a := c.Action
+	if a == nil {
+		return
+	}
+	if a.Backend != "" {
+		valid := false
+		for _, b := range ActionBackends {
+			if a.Backend == b {
+				valid = true
+			}
+		}
+		if !valid {
+			v.add("action.backend", "未知的防火墙后端 %q，可选: %s", a.Backend, strings.Join(ActionBackends, "、"))
+		}
+	}
+	if a.Threshold < 0 {
+		v.add("action.threshold", "失败次数阈值不能为负数")
+	}
+	if a.Window < 0 {
+		v.add("action.window", "时间窗口不能为负数")
+	}
+	if a.BanTime < 0 {
+		v.add("action.bantime", "封禁时长不能为负数")
+	}
+	for i, s := range a.Allow {
+		if _, _, err := ParseCIDR(s); err != nil {
+			v.add(fmt.Sprintf("action.allow[%d]", i), "%v", err)
+		}
+	}
+}
+
+// ParseCIDR 解析 IP 或网段，单个 IP 视为 /32 或 /128
+func ParseCIDR(s string) (net.IP, *net.IPNet, error) {
+	s = strings.TrimSpace(s)
+	if !strings.Contains(s, "/") {
+		ip := net.ParseIP(s)
+		if ip == nil {
+			return nil, nil, fmt.Errorf("无效的 IP 地址 %q", s)
+		}
+		if ip.To4() != nil {
+			s += "/32"
+		} else {
+			s += "/128"
+		}
+	}
+	ip, ipNet, err := net.ParseCIDR(s)
+	if err != nil {
+		return nil, nil, fmt.Errorf("无效的网段 %q", s)
+	}
+	return ip, ipNet, nil
+}
@@ -0,0 +1,165 @@
+package config
+
+import (
+	"fmt"
+	"loginfopush/schedule"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// builtinEventTypes 内置事件类型，由内置日志解析产生
+var builtinEventTypes = map[EventType]bool{
+	EventTypeBan:         true,
+	EventTypeFailure:     true,
+	EventTypeSuccess:     true,
+	EventTypeReport:      true,
+	EventTypeLogout:      true,
+	EventTypeLongSession: true,
+	EventTypeSudo:        true,
+	EventTypeSu:          true,
+	EventTypeUnban:       true,
+}
+
+// eventTypeName 自定义事件类型名称格式
+var eventTypeName = regexp.MustCompile(`^[a-z][a-z0-9_]*$`)
+
+// Builtin 判断是否为内置事件类型
+func (t EventType) Builtin() bool {
+	return builtinEventTypes[t]
+}
+
+// EventTypes 返回内置事件类型与配置中定义的所有事件类型
+func (c *Config) EventTypes() []EventType {
+	seen := make(map[EventType]bool)
+	var types []EventType
+	add := func(t EventType) {
+		if !seen[t] {
+			seen[t] = true
+			types = append(types, t)
+		}
+	}
+	for t := range builtinEventTypes {
+		add(t)
+	}
+	for _, evt := range c.Events {
+		add(evt.Type)
+	}
+	sort.Slice(types, func(i, j int) bool { return types[i] < types[j] })
+	return types
+}
+
+// EventFields 返回规则表达式可以引用的字段，包含内置字段与自定义事件的正则命名分组
+func (c *Config) EventFields() []string {
+	fields := append([]string(nil), RuleFields...)
+	for _, evt := range c.Events {
+		if evt.Source == nil {
+			continue
+		}
+		for _, pattern := range evt.Source.Patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				continue
+			}
+			for _, name := range re.SubexpNames() {
+				if name != "" {
+					fields = append(fields, name)
+				}
+			}
+		}
+	}
+	return fields
+}
+
+// validateEvents 校验事件类型、通知渠道、模板、发送计划与日志来源
+func validateEvents(c *Config, v *validator) {
+	seen := make(map[EventType]string)
+	for _, name := range sortedKeys(c.Events) {
+		evt := c.Events[name]
+		path := "events." + name
+
+		if evt.Type == "" {
+			v.add(path+".type", "事件类型不能为空")
+		} else if !evt.Type.Builtin() && !eventTypeName.MatchString(string(evt.Type)) {
+			v.add(path+".type", "事件类型 %q 只能包含小写字母、数字和下划线，且以字母开头", evt.Type)
+		} else if other, ok := seen[evt.Type]; ok {
+			v.add(path+".type", "事件类型 %s 与 events.%s 重复", evt.Type, other)
+		} else {
+			seen[evt.Type] = name
+		}
+
+		checkNotifierRefs(c, v, path+".notifiers", evt.Notifiers)
+		for _, severity := range sortedKeys(evt.SeverityNotifiers) {
+			sevPath := path + ".severity_notifiers." + severity
+			if !Severity(severity).Valid() {
+				v.add(sevPath, "无效的严重程度 %q", severity)
+			}
+			checkNotifierRefs(c, v, sevPath, evt.SeverityNotifiers[Severity(severity)])
+		}
+		if evt.Severity != "" && !evt.Severity.Valid() {
+			v.add(path+".severity", "无效的严重程度 %q", evt.Severity)
+		}
+		if evt.MinSeverity != "" && !evt.MinSeverity.Valid() {
+			v.add(path+".min_severity", "无效的严重程度 %q", evt.MinSeverity)
+		}
+
+		checkTemplate(v, path+".template", evt.Template)
+		if evt.Schedule != "" {
+			if evt.Type != EventTypeReport {
+				v.add(path+".schedule", "仅 report 事件支持发送计划")
+			} else if _, err := schedule.Parse(evt.Schedule); err != nil {
+				v.add(path+".schedule", "%v", err)
+			}
+		}
+		if d := evt.Digest; d != nil {
+			checkTemplate(v, path+".digest.template", d.Template)
+			if d.Schedule != "" {
+				if _, err := schedule.Parse(d.Schedule); err != nil {
+					v.add(path+".digest.schedule", "%v", err)
+				}
+			}
+		}
+
+		for i, a := range evt.AllowCommands {
+			if evt.Type != EventTypeSudo {
+				v.add(path+".allow_commands", "仅 sudo 事件支持命令白名单")
+				break
+			}
+			if _, err := regexp.Compile(a.Command); err != nil {
+				v.add(fmt.Sprintf("%s.allow_commands[%d].command", path, i), "%v", err)
+			}
+		}
+
+		if evt.Source == nil {
+			if evt.Type != "" && !evt.Type.Builtin() {
+				v.add(path+".source", "自定义事件类型 %s 需要配置日志来源", evt.Type)
+			}
+			continue
+		}
+		if evt.Type == EventTypeReport {
+			v.add(path+".source", "report 事件不支持日志来源")
+			continue
+		}
+		if strings.TrimSpace(evt.Source.Path) == "" {
+			v.add(path+".source.path", "日志路径不能为空")
+		}
+		if len(evt.Source.Patterns) == 0 {
+			v.add(path+".source.patterns", "至少需要一个匹配规则")
+		}
+		for i, pattern := range evt.Source.Patterns {
+			if _, err := regexp.Compile(pattern); err != nil {
+				v.add(fmt.Sprintf("%s.source.patterns[%d]", path, i), "%v", err)
+			}
+		}
+	}
+}
+
+// checkTemplate 预先解析消息模板，解析结果缓存供发送时使用
+func checkTemplate(v *validator, path, text string) {
+	if text == "" {
+		return
+	}
+	if _, err := ParseTemplate(text); err != nil {
+		v.add(path, "%v", err)
+	}
+}
@@ -0,0 +1,121 @@
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// loadConfig 将配置内容写入临时文件后加载，测试结束后恢复 GlobalConfig
+func loadConfig(t *testing.T, text string) (*Config, error) {
+	t.Helper()
+	old := GlobalConfig
+	t.Cleanup(func() { GlobalConfig = old })
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	if err := ioutil.WriteFile(path, []byte(text), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return LoadConfig(path)
+}
+
+// errorPaths 校验错误中的所有 JSON 路径，err 不是 ValidationErrors 时返回 nil
+func errorPaths(err error) []string {
+	errs, ok := err.(ValidationErrors)
+	if !ok {
+		return nil
+	}
+	var paths []string
+	for _, e := range errs {
+		paths = append(paths, e.Path)
+	}
+	return paths
+}
+
+func TestCustomEventValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		events string
+		paths  []string // 为空表示配置有效
+	}{
+		{
+			name: "命名分组",
+			events: `{"web": {"type": "web_login", "enabled": true, "source": {
+				"path": "/var/log/web.log", "patterns": ["login (?P<user>\\w+) from (?P<ip>[0-9.]+)"]}}}`,
+		},
+		{
+			name: "无效的正则",
+			events: `{"web": {"type": "web_login", "enabled": true, "source": {
+				"path": "/var/log/web.log", "patterns": ["ok", "(?P<ip>[0-9.]+", "a{2,1}"]}}}`,
+			paths: []string{"events.web.source.patterns[1]", "events.web.source.patterns[2]"},
+		},
+		{
+			name: "与内置事件类型重复",
+			events: `{
+				"ban": {"type": "ban", "enabled": true},
+				"web_ban": {"type": "ban", "enabled": true, "source": {"path": "/var/log/web.log", "patterns": ["banned (?P<ip>\\S+)"]}}}`,
+			paths: []string{"events.web_ban.type"},
+		},
+		{
+			// 没有配置同类型的内置事件时，自定义日志可以产生内置类型的事件
+			name: "使用内置事件类型",
+			events: `{"web_ban": {"type": "ban", "enabled": true, "source": {
+				"path": "/var/log/web.log", "patterns": ["banned (?P<ip>\\S+)"]}}}`,
+		},
+		{
+			name:   "自定义事件缺少日志来源",
+			events: `{"web": {"type": "web_login", "enabled": true}}`,
+			paths:  []string{"events.web.source"},
+		},
+		{
+			name: "无效的事件类型名称",
+			events: `{"web": {"type": "Web-Login", "enabled": true, "source": {
+				"path": "/var/log/web.log", "patterns": ["login"]}}}`,
+			paths: []string{"events.web.type"},
+		},
+		{
+			name:   "日志来源不完整",
+			events: `{"web": {"type": "web_login", "enabled": true, "source": {"path": " "}}}`,
+			paths:  []string{"events.web.source.path", "events.web.source.patterns"},
+		},
+		{
+			name: "report 事件不支持日志来源",
+			events: `{"report": {"type": "report", "enabled": true, "source": {
+				"path": "/var/log/web.log", "patterns": ["login"]}}}`,
+			paths: []string{"events.report.source"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadConfig(t, `{"notifiers": {}, "events": `+tt.events+`}`)
+			if len(tt.paths) == 0 {
+				if err != nil {
+					t.Errorf("LoadConfig() = %v", err)
+				}
+				return
+			}
+			if got := errorPaths(err); !reflect.DeepEqual(got, tt.paths) {
+				t.Errorf("LoadConfig() = %v, want paths %v", err, tt.paths)
+			}
+		})
+	}
+}
+
+func TestEventFields(t *testing.T) {
+	cfg, err := loadConfig(t, `{"notifiers": {}, "events": {
+		"web": {"type": "web_login", "enabled": true, "source": {
+			"path": "/var/log/web.log", "patterns": ["login (?P<user>\\w+) from (?P<ip>[0-9.]+)", "status=(?P<status>\\d+)"]}}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	fields := make(map[string]bool)
+	for _, f := range cfg.EventFields() {
+		fields[f] = true
+	}
+	for _, f := range []string{"user", "ip", "status", "type"} {
+		if !fields[f] {
+			t.Errorf("EventFields() 缺少 %s", f)
+		}
+	}
+}
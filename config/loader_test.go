@@ -0,0 +1,107 @@
+package config
+
+import (
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLoadConfigReportsAllErrors(t *testing.T) {
+	missing := filepath.Join(t.TempDir(), "missing.pem")
+	_, err := loadConfig(t, `{
+		"server": {"name": 1},
+		"http": {"timeout": "-1s", "proxy": "ftp://proxy.example.com", "ca_file": "`+missing+`"},
+		"dedup": {"enabled": true, "window": "abc", "keys": ["ip", "foo"]},
+		"notifiers": {
+			"tg": {"type": "telegram", "enabled": true, "rate_limit": {"rate": -1, "burst": "5"},
+				"http": {"proxy": "socks5://"},
+				"config": {"webhook_url": "https://api.telegram.org", "chat_id": 42}}
+		},
+		"events": {
+			"fail": {"type": "fail", "enabled": "yes", "notifiers": ["tg", 1]}
+		}
+	}`)
+
+	// 先报告类型错误，再报告其他问题；类型错误的值不再重复报告
+	want := []string{
+		"dedup.window",
+		"events.fail.enabled",
+		"events.fail.notifiers[1]",
+		"notifiers.tg.rate_limit.burst",
+		"notifiers.tg.config.chat_id",
+		"server.name",
+		"notifiers.tg.rate_limit.rate",
+		"http.timeout",
+		"http.proxy",
+		"http.ca_file",
+		"notifiers.tg.http.proxy",
+		"dedup.keys[1]",
+	}
+	if got := errorPaths(err); !reflect.DeepEqual(got, want) {
+		t.Errorf("LoadConfig() = %v\nwant paths %v", err, want)
+	}
+}
+
+func TestLoadConfigTypeErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		path    string
+		message string
+	}{
+		{"字符串", `{"server": {"name": 1}}`, "server.name", "应为字符串"},
+		{"布尔值", `{"store": {"enabled": "true"}}`, "store.enabled", "应为布尔值"},
+		{"整数", `{"notifiers": {"tg": {"type": "telegram", "rate_limit": {"burst": 1.5}}}}`, "notifiers.tg.rate_limit.burst", "应为整数"},
+		{"数组", `{"dedup": {"keys": "ip"}}`, "dedup.keys", "应为数组"},
+		{"对象", `{"events": []}`, "events", "应为对象"},
+		{"时间长度", `{"dedup": {"window": "5 minutes"}}`, "dedup.window", "无效的时间长度"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := loadConfig(t, tt.text)
+			errs, ok := err.(ValidationErrors)
+			if !ok || len(errs) != 1 || errs[0].Path != tt.path || !strings.Contains(errs[0].Message, tt.message) {
+				t.Errorf("LoadConfig() = %v, want %s: %s", err, tt.path, tt.message)
+			}
+		})
+	}
+}
+
+func TestLoadConfigKeepsValidValues(t *testing.T) {
+	// 类型错误的值被移除，同一对象中的其他配置照常解析
+	cfg, err := loadConfig(t, `{"dedup": {"enabled": true, "window": "2m", "keys": 1}}`)
+	if got := errorPaths(err); !reflect.DeepEqual(got, []string{"dedup.keys"}) {
+		t.Fatalf("LoadConfig() = %v", err)
+	}
+	if cfg != nil {
+		t.Errorf("配置有问题时返回了 %+v", cfg)
+	}
+
+	cfg, err = loadConfig(t, `{"dedup": {"enabled": true, "window": 120, "keys": ["IP", "user"]}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.Dedup.Window.Std() != 2*time.Minute || !reflect.DeepEqual(cfg.Dedup.Keys, []string{"IP", "user"}) {
+		t.Errorf("dedup = %+v", cfg.Dedup)
+	}
+}
+
+func TestLoadConfigSyntaxError(t *testing.T) {
+	_, err := loadConfig(t, "{\n  \"server\": {\"name\": \"web\",}\n}")
+	if err == nil || !strings.Contains(err.Error(), "第 2 行") {
+		t.Errorf("LoadConfig() = %v, want 第 2 行的语法错误", err)
+	}
+}
+
+func TestLoadConfigLargeIntegers(t *testing.T) {
+	// 数字不经过 float64 转换
+	cfg, err := loadConfig(t, `{"notifiers": {"app": {"type": "wecom", "config": {"mode": "app", "agent_id": 9007199254740993}}}}`)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := cfg.Notifiers["app"].Config.(WeComConfig).AgentID; got != 9007199254740993 {
+		t.Errorf("agent_id = %d", got)
+	}
+}
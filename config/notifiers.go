@@ -0,0 +1,213 @@
+package config
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"reflect"
+	"strings"
+)
+
+// notifierConfigTypes 各通知类型对应的具体配置结构
+var notifierConfigTypes = map[NotifierType]reflect.Type{
+	NotifierTypeFCM:      reflect.TypeOf(FCMConfig{}),
+	NotifierTypeTelegram: reflect.TypeOf(TelegramConfig{}),
+	NotifierTypeBark:     reflect.TypeOf(BarkConfig{}),
+	NotifierTypeWeCom:    reflect.TypeOf(WeComConfig{}),
+	NotifierTypeWxPusher: reflect.TypeOf(WxPusherConfig{}),
+	NotifierTypeExec:     reflect.TypeOf(ExecConfig{}),
+	NotifierTypeFile:     reflect.TypeOf(FileConfig{}),
+	NotifierTypeSyslog:   reflect.TypeOf(SyslogConfig{}),
+}
+
+// convertNotifiers 将通知渠道的 config 转换为对应类型的配置结构
+func convertNotifiers(c *Config, v *validator) {
+	for _, name := range sortedKeys(c.Notifiers) {
+		n := c.Notifiers[name]
+		t, ok := notifierConfigTypes[n.Type]
+		if !ok {
+			v.add("notifiers."+name+".type", "不支持的通知类型 %q", n.Type)
+			continue
+		}
+		data, _ := json.Marshal(n.Config)
+		ptr := reflect.New(t)
+		if err := json.Unmarshal(data, ptr.Interface()); err != nil {
+			v.add("notifiers."+name+".config", "解析 %s 配置失败: %v", n.Type, err)
+			continue
+		}
+		n.Config = ptr.Elem().Interface()
+		c.Notifiers[name] = n
+	}
+}
+
+// validateNotifiers 检查已启用通知渠道的必填项，未启用的渠道不会创建，不做检查
+func validateNotifiers(c *Config, v *validator) {
+	for _, name := range sortedKeys(c.Notifiers) {
+		n := c.Notifiers[name]
+		if !n.Enabled {
+			continue
+		}
+		if n.RateLimit != nil {
+			if n.RateLimit.Rate < 0 {
+				v.add("notifiers."+name+".rate_limit.rate", "不能为负数")
+			}
+			if n.RateLimit.Burst < 0 {
+				v.add("notifiers."+name+".rate_limit.burst", "不能为负数")
+			}
+		}
+
+		path := "notifiers." + name + ".config"
+		required := func(field, value string) {
+			if strings.TrimSpace(value) == "" {
+				v.add(path+"."+field, "不能为空")
+			}
+		}
+
+		switch cfg := n.Config.(type) {
+		case FCMConfig:
+			switch cfg.Mode {
+			case "", FCMModeWebhook:
+				required("webhook_url", cfg.WebhookURL)
+				required("device_token", cfg.DeviceToken)
+			case FCMModeV1:
+				required("service_account_file", cfg.ServiceAccountFile)
+				if (cfg.DeviceToken == "") == (cfg.Topic == "") {
+					v.add(path, "v1 模式需要且只能配置 device_token 或 topic 其中之一")
+				}
+				if cfg.TTL < 0 {
+					v.add(path+".ttl", "不能为负数")
+				}
+			default:
+				v.add(path+".mode", "不支持的 FCM 模式 %q", cfg.Mode)
+			}
+		case TelegramConfig:
+			required("webhook_url", cfg.WebhookURL)
+			required("chat_id", cfg.ChatID)
+			for i, s := range cfg.SilentSeverities {
+				if !s.Valid() {
+					v.add(fmt.Sprintf("%s.silent_severities[%d]", path, i), "无效的严重程度 %q", s)
+				}
+			}
+		case BarkConfig:
+			if cfg.DeviceToken == "" && len(cfg.DeviceTokens) == 0 {
+				v.add(path+".device_token", "device_token 与 device_tokens 至少配置一个")
+			}
+		case WeComConfig:
+			switch cfg.Mode {
+			case "", WeComModeWecomchan:
+				required("webhook_url", cfg.WebhookURL)
+				required("send_key", cfg.SendKey)
+			case WeComModeRobot:
+				if cfg.WebhookURL == "" && cfg.Key == "" {
+					v.add(path, "群机器人需要配置 webhook_url 或 key")
+				}
+			case WeComModeApp:
+				required("corp_id", cfg.CorpID)
+				required("corp_secret", cfg.CorpSecret)
+				if cfg.AgentID == 0 {
+					v.add(path+".agent_id", "不能为空")
+				}
+			default:
+				v.add(path+".mode", "不支持的 WeCom 模式 %q", cfg.Mode)
+			}
+		case WxPusherConfig:
+			required("app_token", cfg.AppToken)
+			if len(cfg.UIDs) == 0 && len(cfg.TopicIDs) == 0 {
+				v.add(path+".uids", "uids 与 topic_ids 至少配置一个")
+			}
+		case ExecConfig:
+			required("command", cfg.Command)
+		case FileConfig:
+			required("path", cfg.Path)
+		case SyslogConfig:
+			switch cfg.Network {
+			case "", "unix":
+			case "udp", "tcp", "tls":
+				required("address", cfg.Address)
+			default:
+				v.add(path+".network", "不支持的 syslog 传输方式 %q", cfg.Network)
+			}
+		}
+	}
+}
+
+// validateHTTP 检查全局与已启用通知渠道的 HTTP 客户端配置，证书文件在加载配置时即读取检查
+func validateHTTP(c *Config, v *validator) {
+	if c.HTTP != nil {
+		checkHTTP(v, "http", *c.HTTP)
+	}
+	for _, name := range sortedKeys(c.Notifiers) {
+		n := c.Notifiers[name]
+		if n.Enabled && n.HTTP != nil {
+			checkHTTP(v, "notifiers."+name+".http", *n.HTTP)
+		}
+	}
+}
+
+// checkHTTP 检查一份 HTTP 客户端配置
+func checkHTTP(v *validator, path string, cfg HTTPConfig) {
+	if cfg.Timeout < 0 {
+		v.add(path+".timeout", "不能为负数")
+	}
+
+	switch cfg.Proxy {
+	case "", "direct":
+	default:
+		u, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			v.add(path+".proxy", "无效的代理地址: %v", err)
+			break
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+			if u.Host == "" {
+				v.add(path+".proxy", "代理地址 %q 缺少主机", cfg.Proxy)
+			}
+		default:
+			v.add(path+".proxy", "不支持的代理协议 %q", u.Scheme)
+		}
+	}
+
+	if cfg.CAFile != "" {
+		pem, err := ioutil.ReadFile(cfg.CAFile)
+		if err != nil {
+			v.add(path+".ca_file", "读取 CA 证书失败: %v", err)
+		} else if !x509.NewCertPool().AppendCertsFromPEM(pem) {
+			v.add(path+".ca_file", "%s 中没有有效的证书", cfg.CAFile)
+		}
+	}
+	switch {
+	case cfg.CertFile == "" && cfg.KeyFile == "":
+	case cfg.CertFile == "":
+		v.add(path+".cert_file", "配置 key_file 时不能为空")
+	case cfg.KeyFile == "":
+		v.add(path+".key_file", "配置 cert_file 时不能为空")
+	default:
+		if _, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile); err != nil {
+			v.add(path+".cert_file", "加载客户端证书失败: %v", err)
+		}
+	}
+}
+
+// validateDedup 检查重复告警抑制配置，keys 可以引用内置事件字段与自定义事件的命名分组
+func validateDedup(c *Config, v *validator) {
+	if c.Dedup == nil {
+		return
+	}
+	if c.Dedup.Window < 0 {
+		v.add("dedup.window", "不能为负数")
+	}
+	// 与事件字段的查找一致，忽略大小写
+	fields := make(map[string]bool)
+	for _, f := range c.EventFields() {
+		fields[strings.ToLower(f)] = true
+	}
+	for i, key := range c.Dedup.Keys {
+		if !fields[strings.ToLower(key)] {
+			v.add(fmt.Sprintf("dedup.keys[%d]", i), "未知的事件字段 %q", key)
+		}
+	}
+}
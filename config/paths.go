@@ -0,0 +1,52 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// DefaultStateDir 默认状态目录，不随工作目录变化，重新安装程序时也不会被删除
+const DefaultStateDir = "/var/lib/loginfopush"
+
+// SearchPaths 未指定配置文件时依次查找的路径:
+// $XDG_CONFIG_HOME/loginfopush/config.json、/etc/loginfopush/config.json、
+// 工作目录下的 config/config.json 和程序所在目录下的 config/config.json
+func SearchPaths() []string {
+	var paths []string
+	configHome := os.Getenv("XDG_CONFIG_HOME")
+	if configHome == "" {
+		if home, err := os.UserHomeDir(); err == nil {
+			configHome = filepath.Join(home, ".config")
+		}
+	}
+	if configHome != "" {
+		paths = append(paths, filepath.Join(configHome, "loginfopush", "config.json"))
+	}
+	paths = append(paths, "/etc/loginfopush/config.json", DefaultConfigPath)
+	if exe, err := os.Executable(); err == nil {
+		paths = append(paths, filepath.Join(filepath.Dir(exe), DefaultConfigPath))
+	}
+	return paths
+}
+
+// FindConfig 返回第一个存在的配置文件路径
+func FindConfig() (string, error) {
+	paths := SearchPaths()
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil && !info.IsDir() {
+			return path, nil
+		}
+	}
+	return "", fmt.Errorf("未找到配置文件，已查找: %s", strings.Join(paths, ", "))
+}
+
+// StatePath 状态目录下的文件路径
+func (c *Config) StatePath(name string) string {
+	dir := c.StateDir
+	if dir == "" {
+		dir = DefaultStateDir
+	}
+	return filepath.Join(dir, name)
+}
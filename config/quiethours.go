@@ -0,0 +1,73 @@
+package config
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Weekdays 星期缩写
+var Weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// ParseClock 解析 "HH:MM" 为当天的分钟数
+func ParseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("无效的时间 %q，格式应为 HH:MM", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// validateQuietHours 校验免打扰规则
+func validateQuietHours(c *Config, v *validator) {
+	for i, r := range c.QuietHours {
+		path := fmt.Sprintf("quiet_hours[%d]", i)
+
+		start, startErr := ParseClock(r.Start)
+		if startErr != nil {
+			v.add(path+".start", "%v", startErr)
+		}
+		end, endErr := ParseClock(r.End)
+		if endErr != nil {
+			v.add(path+".end", "%v", endErr)
+		}
+		if startErr == nil && endErr == nil && start == end {
+			v.add(path, "开始时间与结束时间不能相同")
+		}
+
+		if r.Timezone != "" {
+			if _, err := time.LoadLocation(r.Timezone); err != nil {
+				v.add(path+".timezone", "%v", err)
+			}
+		}
+		for j, d := range r.Days {
+			if _, ok := Weekdays[strings.ToLower(d)]; !ok {
+				v.add(fmt.Sprintf("%s.days[%d]", path, j), "无效的星期 %q", d)
+			}
+		}
+		for j, s := range r.Severities {
+			if !s.Valid() {
+				v.add(fmt.Sprintf("%s.severities[%d]", path, j), "无效的严重程度 %q", s)
+			}
+		}
+
+		switch r.Action {
+		case QuietActionMute, QuietActionDelay:
+		case QuietActionReroute:
+			if len(r.Notifiers) == 0 {
+				v.add(path+".notifiers", "reroute 需要配置 notifiers")
+			}
+		default:
+			v.add(path+".action", "不支持的动作 %q", r.Action)
+		}
+		checkNotifierRefs(c, v, path+".notifiers", r.Notifiers)
+	}
+}
@@ -0,0 +1,34 @@
+package config
+
+import (
+	"fmt"
+	"loginfopush/rules"
+)
+
+// RuleFields 规则表达式中可以引用的内置事件字段，自定义事件的命名分组见 Config.EventFields
+var RuleFields = []string{
+	"type", "ip", "user", "location", "country", "asn", "severity", "details", "raw", "time", "tags",
+	"pid", "login_time", "duration", "duration_seconds",
+	"tty", "pwd", "target_user", "command", "result", "reason",
+	"port", "method", "invalid_user",
+	"jail", "action", "attempts", "bantime", "ban_count",
+}
+
+// validateRules 校验路由规则，表达式、严重程度、通知渠道与模板错误在加载配置时即报告
+func validateRules(c *Config, v *validator) {
+	fields := c.EventFields()
+	for i, r := range c.Rules {
+		path := fmt.Sprintf("rules[%d]", i)
+		if _, err := rules.Compile(r.When, fields); err != nil {
+			v.add(path+".when", "%v", err)
+		}
+		if r.Severity != "" && !r.Severity.Valid() {
+			v.add(path+".severity", "无效的严重程度 %q", r.Severity)
+		}
+		checkNotifierRefs(c, v, path+".notifiers", r.Notifiers)
+		checkTemplate(v, path+".template", r.Template)
+		if !r.Drop && r.Severity == "" && len(r.Tags) == 0 && len(r.Notifiers) == 0 && r.Template == "" && !r.Stop {
+			v.add(path, "至少需要一个动作（drop/severity/tags/notifiers/template/stop）")
+		}
+	}
+}
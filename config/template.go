@@ -0,0 +1,22 @@
+package config
+
+import (
+	"sync"
+	"text/template"
+)
+
+// templateCache 已解析的模板，键为模板文本
+var templateCache sync.Map
+
+// ParseTemplate 解析消息模板，相同文本只解析一次；加载配置时会预先解析所有模板
+func ParseTemplate(text string) (*template.Template, error) {
+	if t, ok := templateCache.Load(text); ok {
+		return t.(*template.Template), nil
+	}
+	t, err := template.New("message").Parse(text)
+	if err != nil {
+		return nil, err
+	}
+	templateCache.Store(text, t)
+	return t, nil
+}
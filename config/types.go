@@ -1,5 +1,7 @@
 package config
 
+import "time"
+
 // NotifierType 通知渠道类型
 type NotifierType string
 
@@ -9,17 +11,57 @@ const (
 	NotifierTypeBark     NotifierType = "bark"     // Bark
 	NotifierTypeWeCom    NotifierType = "wecom"    // WeCom
 	NotifierTypeWxPusher NotifierType = "wxpusher" // WxPusher
+	NotifierTypeExec     NotifierType = "exec"     // 执行外部命令
+	NotifierTypeFile     NotifierType = "file"     // 写入 JSON Lines 文件
+	NotifierTypeSyslog   NotifierType = "syslog"   // 发送 RFC 5424 syslog
 )
 
+// Sink 判断是否为归档类渠道，归档渠道接收所有事件，不经过路由规则、去重、免打扰与频率限制
+func (t NotifierType) Sink() bool {
+	return t == NotifierTypeFile || t == NotifierTypeSyslog
+}
+
 // EventType 事件类型
 type EventType string
 
 const (
-	EventTypeBan     EventType = "ban"     // IP 被封禁
-	EventTypeFailure EventType = "fail"    // 登录失败
-	EventTypeSuccess EventType = "success" // 登录成功
+	EventTypeBan         EventType = "ban"          // IP 被封禁
+	EventTypeFailure     EventType = "fail"         // 登录失败
+	EventTypeSuccess     EventType = "success"      // 登录成功
+	EventTypeReport      EventType = "report"       // 定时安全报告
+	EventTypeLogout      EventType = "logout"       // SSH 登出
+	EventTypeLongSession EventType = "long_session" // SSH 会话持续时间超过阈值
+	EventTypeSudo        EventType = "sudo"         // sudo 执行命令
+	EventTypeSu          EventType = "su"           // su 切换用户
+	EventTypeUnban       EventType = "unban"        // IP 被 fail2ban 解封
 )
 
+// Severity 事件严重程度
+type Severity string
+
+const (
+	SeverityInfo     Severity = "info"     // 一般
+	SeverityWarning  Severity = "warning"  // 警告
+	SeverityCritical Severity = "critical" // 严重
+)
+
+// severityRanks 严重程度排序
+var severityRanks = map[Severity]int{
+	SeverityInfo:     1,
+	SeverityWarning:  2,
+	SeverityCritical: 3,
+}
+
+// Rank 返回严重程度的排序值，未知取值返回 0
+func (s Severity) Rank() int {
+	return severityRanks[s]
+}
+
+// Valid 判断严重程度是否有效
+func (s Severity) Valid() bool {
+	return s.Rank() > 0
+}
+
 // ServerConfig 服务器配置
 type ServerConfig struct {
 	Name string `json:"name"` // 服务器名称
@@ -28,54 +70,333 @@ type ServerConfig struct {
 
 // NotifierConfig 通知渠道配置
 type NotifierConfig struct {
-	Type    NotifierType `json:"type"`    // 通知类型
-	Enabled bool         `json:"enabled"` // 是否启用
-	Config  interface{}  `json:"config"`  // 具体配置（不同渠道的配置不同）
+	Type      NotifierType     `json:"type"`                 // 通知类型
+	Enabled   bool             `json:"enabled"`              // 是否启用
+	Config    interface{}      `json:"config"`               // 具体配置（不同渠道的配置不同）
+	HTTP      *HTTPConfig      `json:"http,omitempty"`       // HTTP 客户端配置，覆盖全局配置中的同名字段
+	RateLimit *RateLimitConfig `json:"rate_limit,omitempty"` // 发送频率限制
+}
+
+// RateLimitConfig 令牌桶限流配置
+type RateLimitConfig struct {
+	Rate  float64 `json:"rate"`  // 每分钟允许发送的消息数
+	Burst int     `json:"burst"` // 突发容量，默认与 rate 相同
+}
+
+// DedupConfig 重复告警抑制配置
+type DedupConfig struct {
+	Enabled bool     `json:"enabled"` // 是否启用
+	Window  Duration `json:"window"`  // 抑制窗口，默认 60s
+	Keys    []string `json:"keys"`    // 去重字段: type/ip/user/location/details 等事件字段，默认 type、ip、user
+	Summary *bool    `json:"summary"` // 窗口结束时是否发送 "N 条相似事件已抑制" 汇总，默认 true
+}
+
+// Fail2banConfig fail2ban 控制套接字配置
+type Fail2banConfig struct {
+	Enabled bool     `json:"enabled"` // 是否启用
+	Socket  string   `json:"socket"`  // 控制套接字路径，默认 /var/run/fail2ban/fail2ban.sock
+	Timeout Duration `json:"timeout"` // 单条命令超时，默认 5s
+}
+
+// ActionConfig 主动封禁配置，登录失败次数超过阈值时通过防火墙封禁来源 IP
+type ActionConfig struct {
+	Enabled   bool     `json:"enabled"`    // 是否启用
+	Backend   string   `json:"backend"`    // 防火墙后端: nftables、ipset、iptables、fail2ban，默认 nftables
+	Jail      string   `json:"jail"`       // fail2ban 后端使用的 jail，默认 sshd
+	Threshold int      `json:"threshold"`  // 时间窗口内的失败次数阈值，默认 5
+	Window    Duration `json:"window"`     // 失败计数时间窗口，默认 10m
+	BanTime   Duration `json:"bantime"`    // 封禁时长，默认 1h
+	Allow     []string `json:"allow"`      // 永不封禁的 IP 或网段，本机地址始终不会被封禁
+	StateFile string   `json:"state_file"` // 封禁列表保存路径，默认为状态目录下的 bans.json
+}
+
+// StoreConfig 事件历史存储配置
+type StoreConfig struct {
+	Enabled       *bool  `json:"enabled"`        // 是否启用，默认 true
+	Dir           string `json:"dir"`            // 存储目录，默认为状态目录下的 events
+	RetentionDays int    `json:"retention_days"` // 保留天数，默认 30，-1 表示永久保留
 }
 
+// HTTPConfig HTTP 客户端配置
+type HTTPConfig struct {
+	Timeout            Duration `json:"timeout,omitempty"`              // 请求超时，默认 10s
+	Proxy              string   `json:"proxy,omitempty"`                // 代理地址，支持 http://、https://、socks5://，"direct" 表示不使用代理
+	CAFile             string   `json:"ca_file,omitempty"`              // 自定义 CA 证书文件（PEM）
+	CertFile           string   `json:"cert_file,omitempty"`            // 客户端证书文件（PEM）
+	KeyFile            string   `json:"key_file,omitempty"`             // 客户端私钥文件（PEM）
+	InsecureSkipVerify *bool    `json:"insecure_skip_verify,omitempty"` // 是否跳过服务端证书校验
+}
+
+// FCM 推送模式
+const (
+	FCMModeWebhook = "webhook" // 通过 FCM Toolbox 的云函数转发
+	FCMModeV1      = "v1"      // 直接调用 FCM HTTP v1 API
+)
+
 // FCMConfig FCM 配置
 type FCMConfig struct {
-	WebhookURL  string `json:"webhook_url"`  // FCM Webhook URL
-	DeviceToken string `json:"device_token"` // FCM 设备 Token
+	Mode               string   `json:"mode"`                 // 推送模式: webhook（默认）/v1
+	WebhookURL         string   `json:"webhook_url"`          // FCM Webhook URL（webhook 模式）
+	DeviceToken        string   `json:"device_token"`         // FCM 设备 Token
+	Topic              string   `json:"topic"`                // 推送主题（v1 模式，与 device_token 二选一）
+	ServiceAccountFile string   `json:"service_account_file"` // 服务账号 JSON 文件路径（v1 模式）
+	ProjectID          string   `json:"project_id"`           // 项目 ID，为空时读取服务账号中的 project_id
+	Endpoint           string   `json:"endpoint"`             // FCM API 地址，默认 https://fcm.googleapis.com
+	TokenURL           string   `json:"token_url"`            // OAuth2 Token 地址，为空时使用服务账号中的 token_uri
+	TTL                Duration `json:"ttl"`                  // 消息在 FCM 的保留时长（v1 模式），为空时使用 FCM 默认的 4 周
 }
 
 // TelegramConfig Telegram 配置
 type TelegramConfig struct {
-	WebhookURL string `json:"webhook_url"` // Telegram Bot API URL
-	ChatID     string `json:"chat_id"`     // 聊天 ID
+	WebhookURL       string     `json:"webhook_url"`       // Telegram Bot API URL
+	ChatID           string     `json:"chat_id"`           // 聊天 ID
+	SilentSeverities []Severity `json:"silent_severities"` // 静默发送的严重程度，默认不静默
 }
 
 // BarkConfig Bark 配置
 type BarkConfig struct {
-	WebhookURL  string `json:"webhook_url"`  // Telegram Bot API URL
-	DeviceToken string `json:"device_token"` // 聊天 ID
+	WebhookURL     string                `json:"webhook_url"`          // Bark 服务端地址，如 https://api.day.app
+	DeviceToken    string                `json:"device_token"`         // 设备 Key
+	DeviceTokens   []string              `json:"device_tokens"`        // 多个设备 Key（与 device_token 合并）
+	Group          string                `json:"group"`                // 消息分组
+	Level          string                `json:"level"`                // 默认中断级别: active/timeSensitive/passive/critical
+	Levels         map[string]string     `json:"levels"`               // 事件类型到中断级别的映射
+	SeverityLevels map[string]string     `json:"severity_levels"`      // 严重程度到中断级别的映射
+	Sound          string                `json:"sound"`                // 铃声
+	Icon           string                `json:"icon"`                 // 自定义图标 URL
+	URL            string                `json:"url"`                  // 点击通知跳转的 URL
+	IsArchive      *bool                 `json:"is_archive,omitempty"` // 是否保存消息，未设置时使用客户端设置
+	Encryption     *BarkEncryptionConfig `json:"encryption,omitempty"` // 端到端加密配置
+}
+
+// BarkEncryptionConfig Bark 加密推送配置
+type BarkEncryptionConfig struct {
+	Mode string `json:"mode"` // 加密模式: CBC/ECB
+	Key  string `json:"key"`  // 密钥，长度 16/24/32 分别对应 AES128/AES192/AES256
+	IV   string `json:"iv"`   // CBC 模式的 IV（16 位），为空时每条消息随机生成
 }
 
+// WeCom 推送模式
+const (
+	WeComModeWecomchan = "wecomchan" // 第三方 wecomchan 转发服务
+	WeComModeRobot     = "robot"     // 群机器人 Webhook
+	WeComModeApp       = "app"       // 自建应用消息
+)
+
 // WeComConfig WeCom 配置
 type WeComConfig struct {
-	WebhookURL string `json:"webhook_url"` // Telegram Bot API URL
-	SendKey    string `json:"send_key"`    // 聊天 ID
+	Mode       string `json:"mode"`        // 推送模式: wecomchan（默认）/robot/app
+	WebhookURL string `json:"webhook_url"` // wecomchan 地址，或群机器人完整 Webhook 地址
+	SendKey    string `json:"send_key"`    // wecomchan 的 sendkey
+	MsgType    string `json:"msg_type"`    // 消息类型: text（默认）/markdown/template_card（仅群机器人）/textcard（仅应用）
+
+	// 群机器人
+	Key                 string   `json:"key"`                   // 群机器人 Webhook key，webhook_url 为空时使用
+	MentionedList       []string `json:"mentioned_list"`        // 需要 @ 的成员 userid，@all 表示所有人
+	MentionedMobileList []string `json:"mentioned_mobile_list"` // 需要 @ 的成员手机号
+	CardURL             string   `json:"card_url"`              // 模板卡片 / 文本卡片点击跳转地址
+
+	// 自建应用
+	CorpID     string `json:"corp_id"`     // 企业 ID
+	CorpSecret string `json:"corp_secret"` // 应用 Secret
+	AgentID    int64  `json:"agent_id"`    // 应用 AgentId
+	ToUser     string `json:"to_user"`     // 接收成员，多个用 | 分隔，@all 表示全部
+	ToParty    string `json:"to_party"`    // 接收部门
+	ToTag      string `json:"to_tag"`      // 接收标签
+	APIBase    string `json:"api_base"`    // 企业微信 API 地址，默认 https://qyapi.weixin.qq.com
 }
 
 // WxPusherConfig WxPusher 配置
 type WxPusherConfig struct {
-	AppToken string   `json:"app_token"` // WxPusher 应用 Token
-	UIDs     []string `json:"uids"`      // 接收消息的用户 ID 列表
+	AppToken    string   `json:"app_token"`    // WxPusher 应用 Token
+	UIDs        []string `json:"uids"`         // 接收消息的用户 ID 列表
+	TopicIDs    []int    `json:"topic_ids"`    // 接收消息的主题 ID 列表（与 uids 至少配置一个）
+	BaseURL     string   `json:"base_url"`     // API 地址，默认 https://wxpusher.zjiecode.com
+	ContentType string   `json:"content_type"` // 内容类型: text（默认）/html/markdown
+	URL         string   `json:"url"`          // 点击消息跳转的地址
+}
+
+// ExecConfig 外部命令通知配置，消息内容写入标准输入，事件字段作为环境变量
+type ExecConfig struct {
+	Command        string            `json:"command"`         // 可执行文件路径，不经过 shell
+	Args           []string          `json:"args"`            // 命令参数
+	WorkDir        string            `json:"work_dir"`        // 工作目录，默认为当前目录
+	Env            map[string]string `json:"env"`             // 额外的环境变量
+	Timeout        Duration          `json:"timeout"`         // 执行超时，默认 30s
+	MaxConcurrency int               `json:"max_concurrency"` // 同时执行的最大数量，默认 4
+	SuccessCodes   []int             `json:"success_codes"`   // 视为成功的退出码，默认 [0]
+}
+
+// FileConfig JSON Lines 文件输出配置
+type FileConfig struct {
+	Path       string `json:"path"`        // 文件路径
+	MaxSizeMB  int    `json:"max_size_mb"` // 单个文件的最大大小（MB），超过后轮转，默认 100
+	MaxBackups int    `json:"max_backups"` // 保留的轮转文件数量，默认 5
+}
+
+// SyslogConfig syslog 输出配置，TLS 证书与超时使用通知器的 http 配置
+type SyslogConfig struct {
+	Network  string `json:"network"`  // 传输方式: unix（默认，本机 /dev/log）/udp/tcp/tls
+	Address  string `json:"address"`  // 服务器地址，如 "siem.example.com:6514"；unix 方式默认 /dev/log
+	Facility string `json:"facility"` // 设施: auth/authpriv（默认）/daemon/user/local0-local7
+	AppName  string `json:"app_name"` // APP-NAME，默认 loginfopush
+	Hostname string `json:"hostname"` // HOSTNAME，默认为本机主机名
+}
+
+// 免打扰规则动作
+const (
+	QuietActionMute    = "mute"    // 丢弃
+	QuietActionDelay   = "delay"   // 延迟到时间窗口结束后发送
+	QuietActionReroute = "reroute" // 改为发送到指定的通知渠道
+)
+
+// QuietHoursRule 免打扰时间窗口规则
+type QuietHoursRule struct {
+	Start      string      `json:"start"`       // 开始时间，如 "23:00"
+	End        string      `json:"end"`         // 结束时间，如 "07:00"，早于开始时间表示跨天
+	Timezone   string      `json:"timezone"`    // 时区，如 "Asia/Shanghai"，默认本地时区
+	Days       []string    `json:"days"`        // 生效的星期（按窗口开始日计算）: mon/tue/wed/thu/fri/sat/sun，默认每天
+	EventTypes []EventType `json:"event_types"` // 匹配的事件类型，为空表示所有事件
+	Severities []Severity  `json:"severities"`  // 匹配的严重程度，为空表示所有
+	Action     string      `json:"action"`      // 动作: mute/delay/reroute
+	Notifiers  []string    `json:"notifiers"`   // reroute 时使用的通知渠道
 }
 
 // EventConfig 事件配置
 type EventConfig struct {
-	Type      EventType `json:"type"`      // 事件类型
-	Enabled   bool      `json:"enabled"`   // 是否启用
-	Title     string    `json:"title"`     // 通知标题
-	Template  string    `json:"template"`  // 消息模板
-	Icon      string    `json:"icon"`      // 显示图标
-	Notifiers []string  `json:"notifiers"` // 使用的通知渠道
+	Type              EventType             `json:"type"`                         // 事件类型
+	Enabled           bool                  `json:"enabled"`                      // 是否启用
+	Title             string                `json:"title"`                        // 通知标题
+	Template          string                `json:"template"`                     // 消息模板
+	Icon              string                `json:"icon"`                         // 显示图标
+	Notifiers         []string              `json:"notifiers"`                    // 使用的通知渠道
+	Digest            *DigestConfig         `json:"digest,omitempty"`             // 汇总推送配置，启用后不再逐条推送
+	Schedule          string                `json:"schedule,omitempty"`           // 发送计划（仅 report 事件），默认每天 09:00
+	Threshold         Duration              `json:"threshold,omitempty"`          // 会话时长阈值（仅 long_session 事件），默认 4h
+	AllowCommands     []AllowCommandConfig  `json:"allow_commands,omitempty"`     // 不推送的例行命令（仅 sudo 事件）
+	Severity          Severity              `json:"severity,omitempty"`           // 事件默认严重程度
+	MinSeverity       Severity              `json:"min_severity,omitempty"`       // 低于该严重程度的事件不推送
+	SeverityNotifiers map[Severity][]string `json:"severity_notifiers,omitempty"` // 按严重程度覆盖通知渠道
+	Source            *EventSourceConfig    `json:"source,omitempty"`             // 日志来源，自定义事件类型必须配置
+}
+
+// AllowCommandConfig sudo 命令白名单
+type AllowCommandConfig struct {
+	User    string `json:"user"`    // 执行命令的用户，为空表示所有用户
+	Command string `json:"command"` // 匹配完整命令行的正则表达式，如 "^/usr/bin/systemctl status "
+}
+
+// EventSourceConfig 事件的日志来源
+type EventSourceConfig struct {
+	Path     string   `json:"path"`     // 日志文件路径
+	Patterns []string `json:"patterns"` // 正则表达式，任一匹配即触发事件；命名分组作为事件字段，ip、user 分组同时填充对应字段
+}
+
+// SeverityConfig 严重程度判定规则，规则只会提升事件的严重程度
+type SeverityConfig struct {
+	RootLogin            Severity `json:"root_login"`             // root 登录成功，默认 critical
+	PasswordAuth         Severity `json:"password_auth"`          // 密码方式登录成功，默认 warning
+	AllowedCountries     []string `json:"allowed_countries"`      // 常用国家/地区，为空时不判定未知地区
+	UnknownCountry       Severity `json:"unknown_country"`        // 来自常用地区之外的事件，默认 warning
+	SuccessAfterFailures Severity `json:"success_after_failures"` // 同一 IP 失败后登录成功，默认 critical
+	FailureWindow        Duration `json:"failure_window"`         // 判定失败后成功的时间窗口，默认 1h
+}
+
+// DigestConfig 汇总推送配置
+type DigestConfig struct {
+	Enabled   bool   `json:"enabled"`    // 是否启用
+	Schedule  string `json:"schedule"`   // 类 cron 计划，如 "0 * * * *"、"@daily"，默认 "@hourly"
+	Title     string `json:"title"`      // 汇总标题，为空时使用事件标题
+	Template  string `json:"template"`   // 汇总模板，为空时使用内置模板
+	MaxEvents int    `json:"max_events"` // 最多保留的事件明细数，默认 100，超出部分只计数
+	TopN      int    `json:"top_n"`      // 排行榜条数，默认 5
+}
+
+// RuleConfig 路由与过滤规则，按顺序对每个事件求值
+type RuleConfig struct {
+	When      string   `json:"when"`                // 条件表达式，如 user == "root" && country != "CN"
+	Drop      bool     `json:"drop,omitempty"`      // 丢弃事件，不再推送
+	Severity  Severity `json:"severity,omitempty"`  // 设置严重程度
+	Tags      []string `json:"tags,omitempty"`      // 追加标签
+	Notifiers []string `json:"notifiers,omitempty"` // 改为发送到指定的通知渠道
+	Template  string   `json:"template,omitempty"`  // 覆盖消息模板
+	Stop      bool     `json:"stop,omitempty"`      // 命中后不再匹配后续规则
 }
 
 // Config 总配置结构
 type Config struct {
-	Server    ServerConfig              `json:"server"`    // 服务器配置
-	Notifiers map[string]NotifierConfig `json:"notifiers"` // 通知渠道配置
-	Events    map[string]EventConfig    `json:"events"`    // 事件配置
+	Server     ServerConfig              `json:"server"`                // 服务器配置
+	StateDir   string                    `json:"state_dir,omitempty"`   // 状态目录，保存封禁列表与事件历史，默认 /var/lib/loginfopush
+	HTTP       *HTTPConfig               `json:"http,omitempty"`        // 全局 HTTP 客户端配置
+	Dedup      *DedupConfig              `json:"dedup,omitempty"`       // 重复告警抑制配置
+	QuietHours []QuietHoursRule          `json:"quiet_hours,omitempty"` // 免打扰时间窗口规则，按顺序匹配
+	Severity   *SeverityConfig           `json:"severity,omitempty"`    // 严重程度判定规则
+	Rules      []RuleConfig              `json:"rules,omitempty"`       // 路由与过滤规则，按顺序匹配
+	Fail2ban   *Fail2banConfig           `json:"fail2ban,omitempty"`    // fail2ban 控制套接字
+	Action     *ActionConfig             `json:"action,omitempty"`      // 主动封禁
+	Store      *StoreConfig              `json:"store,omitempty"`       // 事件历史存储
+	Notifiers  map[string]NotifierConfig `json:"notifiers"`             // 通知渠道配置
+	Events     map[string]EventConfig    `json:"events"`                // 事件配置
+}
+
+// FindEvent 查找已启用的事件配置
+func (c *Config) FindEvent(t EventType) (EventConfig, bool) {
+	for _, evt := range c.Events {
+		if evt.Type == t && evt.Enabled {
+			return evt, true
+		}
+	}
+	return EventConfig{}, false
+}
+
+// LookupEvent 查找事件配置，优先返回已启用的配置，未启用的事件同样可以找到
+func (c *Config) LookupEvent(t EventType) (EventConfig, bool) {
+	if evt, ok := c.FindEvent(t); ok {
+		return evt, true
+	}
+	for _, evt := range c.Events {
+		if evt.Type == t {
+			return evt, true
+		}
+	}
+	return EventConfig{}, false
+}
+
+// DefaultRetentionDays 事件历史默认保留天数
+const DefaultRetentionDays = 30
+
+// StoreEnabled 判断是否保存事件历史，未配置时默认启用
+func (c *Config) StoreEnabled() bool {
+	return c.Store == nil || c.Store.Enabled == nil || *c.Store.Enabled
+}
+
+// StoreDir 事件历史存储目录
+func (c *Config) StoreDir() string {
+	if c.Store == nil || c.Store.Dir == "" {
+		return c.StatePath("events")
+	}
+	return c.Store.Dir
+}
+
+// StoreRetention 事件历史保留时长，0 表示永久保留
+func (c *Config) StoreRetention() time.Duration {
+	days := DefaultRetentionDays
+	if c.Store != nil && c.Store.RetentionDays != 0 {
+		days = c.Store.RetentionDays
+	}
+	if days < 0 {
+		return 0
+	}
+	return time.Duration(days) * 24 * time.Hour
+}
+
+// ActionEnabled 判断是否启用主动封禁
+func (c *Config) ActionEnabled() bool {
+	return c.Action != nil && c.Action.Enabled
+}
+
+// EventEnabled 判断事件类型是否启用
+func (c *Config) EventEnabled(t EventType) bool {
+	_, ok := c.FindEvent(t)
+	return ok
 }
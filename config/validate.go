@@ -0,0 +1,293 @@
+package config
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ValidationError 配置中的一个问题
+type ValidationError struct {
+	Path    string // JSON 路径，如 events.ban.notifiers[0]
+	Message string // 问题描述
+}
+
+// Error 实现 error 接口
+func (e ValidationError) Error() string {
+	if e.Path == "" {
+		return e.Message
+	}
+	return e.Path + ": " + e.Message
+}
+
+// ValidationErrors 配置中的所有问题，按发现顺序排列
+type ValidationErrors []ValidationError
+
+// Error 每个问题一行
+func (e ValidationErrors) Error() string {
+	lines := make([]string, len(e))
+	for i, err := range e {
+		lines[i] = "  " + err.Error()
+	}
+	return fmt.Sprintf("配置有 %d 个问题:\n%s", len(e), strings.Join(lines, "\n"))
+}
+
+// validator 收集校验过程中发现的问题
+type validator struct {
+	errs ValidationErrors
+	bad  map[string]bool // 类型错误的路径，其值已被移除，不再报告由此引起的其他问题
+}
+
+// add 记录一个问题
+func (v *validator) add(path, format string, args ...interface{}) {
+	if v.bad[path] {
+		return
+	}
+	v.errs = append(v.errs, ValidationError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+// markBad 记录类型错误的路径
+func (v *validator) markBad(path string) {
+	if v.bad == nil {
+		v.bad = make(map[string]bool)
+	}
+	v.bad[path] = true
+}
+
+// err 没有问题时返回 nil
+func (v *validator) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+// parse 解析并校验配置文件内容。语法错误直接返回；类型错误的值在记录问题后移除，
+// 其余配置照常解析并继续校验，一次报告所有问题
+func parse(data []byte) (*Config, error) {
+	if err := json.Unmarshal(data, new(json.RawMessage)); err != nil {
+		if syntax, ok := err.(*json.SyntaxError); ok {
+			line, col := position(data, syntax.Offset)
+			return nil, fmt.Errorf("解析配置文件失败: 第 %d 行第 %d 列: %v", line, col, err)
+		}
+		return nil, fmt.Errorf("解析配置文件失败: %v", err)
+	}
+
+	// 数字保持原样，避免大整数经 float64 转换后失真
+	var raw interface{}
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.UseNumber()
+	if err := dec.Decode(&raw); err != nil {
+		return nil, fmt.Errorf("解析配置文件失败: %v", err)
+	}
+
+	v := &validator{}
+	c := &Config{}
+	if checkValue(v, "", raw, reflect.TypeOf(Config{})) {
+		clean, _ := json.Marshal(raw)
+		dec := json.NewDecoder(bytes.NewReader(clean))
+		dec.UseNumber()
+		if err := dec.Decode(c); err != nil {
+			v.add("", "解析配置文件失败: %v", err)
+			return nil, v.err()
+		}
+	}
+
+	convertNotifiers(c, v)
+	validateNotifiers(c, v)
+	validateHTTP(c, v)
+	validateDedup(c, v)
+	validateEvents(c, v)
+	validateQuietHours(c, v)
+	validateRules(c, v)
+	validateAction(c, v)
+	return c, v.err()
+}
+
+// position 将字节偏移转换为从 1 开始的行号与列号
+func position(data []byte, offset int64) (line, col int) {
+	if offset > int64(len(data)) {
+		offset = int64(len(data))
+	}
+	before := data[:offset]
+	line = bytes.Count(before, []byte("\n")) + 1
+	col = int(offset) - bytes.LastIndexByte(before, '\n')
+	return line, col
+}
+
+// checkValue 按配置结构检查未知的配置项与值的类型，通知渠道的 config 按其 type 对应的结构检查。
+// 有问题的值从所在对象中删除（数组元素置为 null），返回 false 表示 value 本身类型错误
+func checkValue(v *validator, path string, value interface{}, t reflect.Type) bool {
+	if value == nil {
+		return true
+	}
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+
+	// Duration 等自定义解析的类型直接尝试解析
+	if reflect.PtrTo(t).Implements(unmarshalerType) {
+		data, _ := json.Marshal(value)
+		if err := reflect.New(t).Interface().(json.Unmarshaler).UnmarshalJSON(data); err != nil {
+			v.add(path, "%v", err)
+			v.markBad(path)
+			return false
+		}
+		return true
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return typeError(v, path, "对象", value)
+		}
+		fields := jsonFields(t)
+		for _, key := range sortedKeys(obj) {
+			field, ok := lookupJSONField(fields, key)
+			if !ok {
+				v.add(joinPath(path, key), "未知的配置项")
+				delete(obj, key)
+				continue
+			}
+			if !checkValue(v, joinPath(path, key), obj[key], field) {
+				delete(obj, key)
+			}
+		}
+
+		// 通知渠道的具体配置由 type 决定
+		if t == reflect.TypeOf(NotifierConfig{}) {
+			typ, _ := obj["type"].(string)
+			if configType, ok := notifierConfigTypes[NotifierType(typ)]; ok {
+				if !checkValue(v, joinPath(path, "config"), obj["config"], configType) {
+					delete(obj, "config")
+				}
+			}
+		}
+	case reflect.Map:
+		obj, ok := value.(map[string]interface{})
+		if !ok {
+			return typeError(v, path, "对象", value)
+		}
+		for _, key := range sortedKeys(obj) {
+			if !checkValue(v, joinPath(path, key), obj[key], t.Elem()) {
+				delete(obj, key)
+			}
+		}
+	case reflect.Slice:
+		items, ok := value.([]interface{})
+		if !ok {
+			return typeError(v, path, "数组", value)
+		}
+		for i, item := range items {
+			if !checkValue(v, fmt.Sprintf("%s[%d]", path, i), item, t.Elem()) {
+				items[i] = nil
+			}
+		}
+	case reflect.String:
+		if _, ok := value.(string); !ok {
+			return typeError(v, path, "字符串", value)
+		}
+	case reflect.Bool:
+		if _, ok := value.(bool); !ok {
+			return typeError(v, path, "布尔值", value)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, ok := value.(json.Number)
+		if !ok {
+			return typeError(v, path, "整数", value)
+		}
+		if _, err := strconv.ParseInt(n.String(), 10, t.Bits()); err != nil {
+			return typeError(v, path, "整数", value)
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, ok := value.(json.Number)
+		if !ok {
+			return typeError(v, path, "非负整数", value)
+		}
+		if _, err := strconv.ParseUint(n.String(), 10, t.Bits()); err != nil {
+			return typeError(v, path, "非负整数", value)
+		}
+	case reflect.Float32, reflect.Float64:
+		if _, ok := value.(json.Number); !ok {
+			return typeError(v, path, "数字", value)
+		}
+	}
+	return true
+}
+
+// unmarshalerType json.Unmarshaler 接口类型
+var unmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// typeError 记录类型错误，总是返回 false
+func typeError(v *validator, path, want string, value interface{}) bool {
+	data, _ := json.Marshal(value)
+	v.add(path, "类型错误，应为%s，实际为 %s", want, data)
+	v.markBad(path)
+	return false
+}
+
+// jsonFields 结构体字段的 JSON 名称与类型
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := make(map[string]reflect.Type, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		f := t.Field(i)
+		if f.PkgPath != "" {
+			continue
+		}
+		name := strings.Split(f.Tag.Get("json"), ",")[0]
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = f.Name
+		}
+		fields[name] = f.Type
+	}
+	return fields
+}
+
+// lookupJSONField 与 encoding/json 一致，精确匹配失败时忽略大小写匹配
+func lookupJSONField(fields map[string]reflect.Type, key string) (reflect.Type, bool) {
+	if t, ok := fields[key]; ok {
+		return t, true
+	}
+	for name, t := range fields {
+		if strings.EqualFold(name, key) {
+			return t, true
+		}
+	}
+	return nil, false
+}
+
+// joinPath 拼接 JSON 路径
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// sortedKeys 按名称排序的键，保证问题的输出顺序稳定
+func sortedKeys(m interface{}) []string {
+	rv := reflect.ValueOf(m)
+	keys := make([]string, 0, rv.Len())
+	for _, k := range rv.MapKeys() {
+		keys = append(keys, k.String())
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// checkNotifierRefs 检查引用的通知渠道是否已配置
+func checkNotifierRefs(c *Config, v *validator, path string, names []string) {
+	for i, name := range names {
+		if _, ok := c.Notifiers[name]; !ok {
+			v.add(fmt.Sprintf("%s[%d]", path, i), "未配置的通知渠道 %q", name)
+		}
+	}
+}
@@ -0,0 +1,143 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"loginfopush/store"
+	"os"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// eventsUsage events 子命令的帮助信息
+const eventsUsage = `用法: loginfopush events [选项]
+
+查询保存的事件历史，例如:
+  loginfopush events --since 7d --type success
+  loginfopush events --ip 1.2.3.0/24 --json
+
+选项:
+`
+
+// runEvents 执行 events 子命令
+func runEvents(args []string) error {
+	var g globalFlags
+	fs := newFlagSet("events", eventsUsage, &g)
+	since := fs.String("since", "24h", "起始时间: 时长（如 30m、24h、7d）表示多久以前，或日期时间（如 2024-01-02、2024-01-02 15:04）")
+	until := fs.String("until", "", "结束时间，格式同 --since，默认为当前时间")
+	types := fs.String("type", "", "事件类型，多个用逗号分隔")
+	ip := fs.String("ip", "", "IP 地址或网段")
+	user := fs.String("user", "", "用户名")
+	limit := fs.Int("limit", 100, "最多显示最近的条数，0 表示不限")
+	asJSON := fs.Bool("json", false, "以 JSON Lines 格式输出")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, _, err := g.load()
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	filter := store.Filter{IP: *ip, User: *user, Limit: *limit}
+	if filter.Since, err = parseTime(*since, now); err != nil {
+		return fmt.Errorf("--since: %v", err)
+	}
+	if filter.Until, err = parseTime(*until, now); err != nil {
+		return fmt.Errorf("--until: %v", err)
+	}
+	for _, t := range strings.Split(*types, ",") {
+		if t = strings.TrimSpace(t); t != "" {
+			filter.Types = append(filter.Types, t)
+		}
+	}
+
+	s, err := store.OpenReadOnly(cfg.StoreDir())
+	if err != nil {
+		return err
+	}
+	defer s.Close()
+	records, err := s.Query(filter)
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		enc := json.NewEncoder(os.Stdout)
+		for _, r := range records {
+			if err := enc.Encode(r); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	printEvents(records)
+	return nil
+}
+
+// printEvents 以表格形式输出事件
+func printEvents(records []store.Record) {
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "时间\t类型\t严重程度\tIP\t用户\t位置\t状态\t详情")
+	for _, r := range records {
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\t%s\n",
+			r.Time.Format("2006-01-02 15:04:05"),
+			r.Type,
+			orDash(r.Severity),
+			orDash(r.IP),
+			orDash(r.User),
+			orDash(r.Location),
+			deliveryStatus(r),
+			strings.ReplaceAll(r.Details, "\n", " "),
+		)
+	}
+	w.Flush()
+	fmt.Printf("共 %d 条\n", len(records))
+}
+
+// deliveryStatus 事件状态，发送失败的通知渠道附在后面
+func deliveryStatus(r store.Record) string {
+	var failed []string
+	for _, d := range r.Deliveries {
+		if d.Status != "sent" {
+			failed = append(failed, d.Notifier+":"+d.Status)
+		}
+	}
+	if len(failed) == 0 {
+		return orDash(r.Status)
+	}
+	return r.Status + "(" + strings.Join(failed, ",") + ")"
+}
+
+// orDash 空值显示为 "-"
+func orDash(s string) string {
+	if s == "" {
+		return "-"
+	}
+	return s
+}
+
+// parseTime 解析时间参数，时长表示多久以前，支持 d 作为天的单位
+func parseTime(s string, now time.Time) (time.Time, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	if strings.HasSuffix(s, "d") {
+		if days, err := strconv.Atoi(strings.TrimSuffix(s, "d")); err == nil {
+			return now.Add(-time.Duration(days) * 24 * time.Hour), nil
+		}
+	}
+	if d, err := time.ParseDuration(s); err == nil {
+		return now.Add(-d), nil
+	}
+	for _, layout := range []string{time.RFC3339, "2006-01-02 15:04:05", "2006-01-02 15:04", "2006-01-02"} {
+		if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+			return t, nil
+		}
+	}
+	return time.Time{}, fmt.Errorf("无法解析时间 %q", s)
+}
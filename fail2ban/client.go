@@ -0,0 +1,231 @@
+package fail2ban
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// DefaultSocket fail2ban 控制套接字的默认路径
+const DefaultSocket = "/var/run/fail2ban/fail2ban.sock"
+
+// defaultTimeout 单条命令的默认超时
+const defaultTimeout = 5 * time.Second
+
+// 控制协议的命令结束与关闭标记
+var (
+	endCommand   = []byte("<F2B_END_COMMAND>")
+	closeCommand = []byte("<F2B_CLOSE_COMMAND>")
+)
+
+// JailStatus jail 的当前状态
+type JailStatus struct {
+	Name            string   // jail 名称
+	CurrentlyFailed int      // 当前失败次数
+	TotalFailed     int      // 累计失败次数
+	CurrentlyBanned int      // 当前封禁的 IP 数
+	TotalBanned     int      // 累计封禁次数
+	BannedIPs       []string // 当前封禁的 IP
+}
+
+// Client fail2ban 控制套接字客户端，每条命令使用一个独立连接
+type Client struct {
+	socket  string
+	timeout time.Duration
+}
+
+// NewClient 创建客户端，socket 为空时使用默认路径，timeout 为 0 时使用默认超时
+func NewClient(socket string, timeout time.Duration) *Client {
+	if socket == "" {
+		socket = DefaultSocket
+	}
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	return &Client{socket: socket, timeout: timeout}
+}
+
+// Command 发送一条命令并返回结果，fail2ban 返回错误码时转换为 error
+func (c *Client) Command(args ...string) (interface{}, error) {
+	payload, err := encodePickle(args)
+	if err != nil {
+		return nil, err
+	}
+
+	conn, err := net.DialTimeout("unix", c.socket, c.timeout)
+	if err != nil {
+		return nil, fmt.Errorf("连接 fail2ban 失败: %v", err)
+	}
+	defer conn.Close()
+	conn.SetDeadline(time.Now().Add(c.timeout))
+
+	if _, err := conn.Write(append(payload, endCommand...)); err != nil {
+		return nil, fmt.Errorf("发送 fail2ban 命令失败: %v", err)
+	}
+
+	var resp []byte
+	buf := make([]byte, 4096)
+	for !bytes.HasSuffix(resp, endCommand) {
+		n, err := conn.Read(buf)
+		resp = append(resp, buf[:n]...)
+		if err != nil {
+			if bytes.HasSuffix(resp, endCommand) {
+				break
+			}
+			return nil, fmt.Errorf("读取 fail2ban 响应失败: %v", err)
+		}
+	}
+	// 通知服务端关闭连接，失败不影响已收到的结果
+	conn.Write(append(closeCommand, endCommand...))
+
+	v, err := decodePickle(bytes.TrimSuffix(resp, endCommand))
+	if err != nil {
+		return nil, fmt.Errorf("解析 fail2ban 响应失败: %v", err)
+	}
+	reply, ok := v.([]interface{})
+	if !ok || len(reply) != 2 {
+		return nil, fmt.Errorf("无效的 fail2ban 响应: %v", v)
+	}
+	if code, _ := reply[0].(int64); code != 0 {
+		return nil, fmt.Errorf("fail2ban 命令 %q 失败: %v", strings.Join(args, " "), reply[1])
+	}
+	return reply[1], nil
+}
+
+// Ping 检查 fail2ban 服务是否可用
+func (c *Client) Ping() error {
+	v, err := c.Command("ping")
+	if err != nil {
+		return err
+	}
+	if v != "pong" {
+		return fmt.Errorf("无效的 ping 响应: %v", v)
+	}
+	return nil
+}
+
+// Jails 获取所有 jail 名称
+func (c *Client) Jails() ([]string, error) {
+	v, err := c.Command("status")
+	if err != nil {
+		return nil, err
+	}
+	list := statusValue(v, "Jail list")
+	var jails []string
+	for _, name := range strings.Split(toString(list), ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			jails = append(jails, name)
+		}
+	}
+	return jails, nil
+}
+
+// JailStatus 获取 jail 的失败与封禁统计
+func (c *Client) JailStatus(jail string) (JailStatus, error) {
+	v, err := c.Command("status", jail)
+	if err != nil {
+		return JailStatus{}, err
+	}
+	filter := statusValue(v, "Filter")
+	actions := statusValue(v, "Actions")
+
+	status := JailStatus{
+		Name:            jail,
+		CurrentlyFailed: toInt(statusValue(filter, "Currently failed")),
+		TotalFailed:     toInt(statusValue(filter, "Total failed")),
+		CurrentlyBanned: toInt(statusValue(actions, "Currently banned")),
+		TotalBanned:     toInt(statusValue(actions, "Total banned")),
+	}
+	if ips, ok := statusValue(actions, "Banned IP list").([]interface{}); ok {
+		for _, ip := range ips {
+			status.BannedIPs = append(status.BannedIPs, toString(ip))
+		}
+	}
+	return status, nil
+}
+
+// Status 获取所有 jail 的状态
+func (c *Client) Status() ([]JailStatus, error) {
+	jails, err := c.Jails()
+	if err != nil {
+		return nil, err
+	}
+	statuses := make([]JailStatus, 0, len(jails))
+	for _, jail := range jails {
+		status, err := c.JailStatus(jail)
+		if err != nil {
+			return nil, err
+		}
+		statuses = append(statuses, status)
+	}
+	return statuses, nil
+}
+
+// BanTime 获取 jail 的封禁时长
+func (c *Client) BanTime(jail string) (time.Duration, error) {
+	v, err := c.Command("get", jail, "bantime")
+	if err != nil {
+		return 0, err
+	}
+	switch x := v.(type) {
+	case int64:
+		return time.Duration(x) * time.Second, nil
+	case float64:
+		return time.Duration(x * float64(time.Second)), nil
+	}
+	return 0, fmt.Errorf("无效的封禁时长: %v", v)
+}
+
+// BanIP 在 jail 中封禁 IP
+func (c *Client) BanIP(jail, ip string) error {
+	_, err := c.Command("set", jail, "banip", ip)
+	return err
+}
+
+// UnbanIP 在 jail 中解封 IP
+func (c *Client) UnbanIP(jail, ip string) error {
+	_, err := c.Command("set", jail, "unbanip", ip)
+	return err
+}
+
+// statusValue 在 status 命令返回的 [(名称, 值), ...] 列表中查找值
+func statusValue(v interface{}, key string) interface{} {
+	list, _ := v.([]interface{})
+	for _, item := range list {
+		pair, ok := item.([]interface{})
+		if ok && len(pair) == 2 && pair[0] == key {
+			return pair[1]
+		}
+	}
+	return nil
+}
+
+// toString 将响应中的值转换为字符串
+func toString(v interface{}) string {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return x
+	case []byte:
+		return string(x)
+	}
+	return fmt.Sprint(v)
+}
+
+// toInt 将响应中的值转换为整数
+func toInt(v interface{}) int {
+	switch x := v.(type) {
+	case int64:
+		return int(x)
+	case float64:
+		return int(x)
+	case string:
+		n, _ := strconv.Atoi(x)
+		return n
+	}
+	return 0
+}
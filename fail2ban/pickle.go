@@ -0,0 +1,566 @@
+package fail2ban
+
+import (
+	"bytes"
+	"encoding/binary"
+	"fmt"
+	"math"
+	"math/big"
+	"strings"
+)
+
+// pickle 操作码，只实现 fail2ban 控制协议用到的部分
+const (
+	opMark           = '('
+	opStop           = '.'
+	opPop            = '0'
+	opPopMark        = '1'
+	opDup            = '2'
+	opBinInt         = 'J'
+	opBinInt1        = 'K'
+	opBinInt2        = 'M'
+	opNone           = 'N'
+	opBinUnicode     = 'X'
+	opAppend         = 'a'
+	opBuild          = 'b'
+	opGlobal         = 'c'
+	opDict           = 'd'
+	opEmptyDict      = '}'
+	opAppends        = 'e'
+	opBinGet         = 'h'
+	opLongBinGet     = 'j'
+	opList           = 'l'
+	opEmptyList      = ']'
+	opBinPut         = 'q'
+	opLongBinPut     = 'r'
+	opSetItem        = 's'
+	opTuple          = 't'
+	opEmptyTuple     = ')'
+	opSetItems       = 'u'
+	opBinFloat       = 'G'
+	opReduce         = 'R'
+	opBinString      = 'T'
+	opShortBinString = 'U'
+	opBinBytes       = 'B'
+	opShortBinBytes  = 'C'
+	opProto          = 0x80
+	opNewObj         = 0x81
+	opTuple1         = 0x85
+	opTuple2         = 0x86
+	opTuple3         = 0x87
+	opNewTrue        = 0x88
+	opNewFalse       = 0x89
+	opLong1          = 0x8a
+	opLong4          = 0x8b
+	opShortBinUni    = 0x8c
+	opBinUnicode8    = 0x8d
+	opBinBytes8      = 0x8e
+	opEmptySet       = 0x8f
+	opAddItems       = 0x90
+	opFrozenSet      = 0x91
+	opNewObjEx       = 0x92
+	opStackGlobal    = 0x93
+	opMemoize        = 0x94
+	opFrame          = 0x95
+	opByteArray8     = 0x96
+)
+
+// Object 无法还原为基本类型的 Python 对象，如 fail2ban 返回的异常
+type Object struct {
+	Class string        // 模块与类名，如 "builtins.ValueError"
+	Args  []interface{} // 构造参数
+}
+
+// String 输出为 Python 风格的 "ValueError('...')"
+func (o *Object) String() string {
+	name := o.Class
+	if i := strings.LastIndex(name, "."); i >= 0 {
+		name = name[i+1:]
+	}
+	args := make([]string, len(o.Args))
+	for i, arg := range o.Args {
+		if s, ok := arg.(string); ok {
+			args[i] = fmt.Sprintf("%q", s)
+		} else {
+			args[i] = fmt.Sprint(arg)
+		}
+	}
+	return fmt.Sprintf("%s(%s)", name, strings.Join(args, ", "))
+}
+
+// global GLOBAL 与 STACK_GLOBAL 引用的类
+type global string
+
+// mark MARK 在栈上的占位
+type mark struct{}
+
+// encodePickle 以 pickle 协议 2 编码命令参数，支持字符串、整数与列表
+func encodePickle(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	buf.Write([]byte{opProto, 2})
+	if err := encodeValue(&buf, v); err != nil {
+		return nil, err
+	}
+	buf.WriteByte(opStop)
+	return buf.Bytes(), nil
+}
+
+// encodeValue 编码单个值
+func encodeValue(buf *bytes.Buffer, v interface{}) error {
+	switch x := v.(type) {
+	case nil:
+		buf.WriteByte(opNone)
+	case bool:
+		if x {
+			buf.WriteByte(opNewTrue)
+		} else {
+			buf.WriteByte(opNewFalse)
+		}
+	case string:
+		buf.WriteByte(opBinUnicode)
+		binary.Write(buf, binary.LittleEndian, uint32(len(x)))
+		buf.WriteString(x)
+	case int:
+		return encodeValue(buf, int64(x))
+	case int64:
+		if x >= math.MinInt32 && x <= math.MaxInt32 {
+			buf.WriteByte(opBinInt)
+			binary.Write(buf, binary.LittleEndian, int32(x))
+			return nil
+		}
+		buf.WriteByte(opLong1)
+		buf.WriteByte(8)
+		binary.Write(buf, binary.LittleEndian, x)
+	case []string:
+		list := make([]interface{}, len(x))
+		for i, s := range x {
+			list[i] = s
+		}
+		return encodeValue(buf, list)
+	case []interface{}:
+		buf.WriteByte(opEmptyList)
+		if len(x) == 0 {
+			return nil
+		}
+		buf.WriteByte(opMark)
+		for _, item := range x {
+			if err := encodeValue(buf, item); err != nil {
+				return err
+			}
+		}
+		buf.WriteByte(opAppends)
+	default:
+		return fmt.Errorf("不支持编码的类型 %T", v)
+	}
+	return nil
+}
+
+// unpickler pickle 解码状态
+type unpickler struct {
+	data  []byte
+	pos   int
+	stack []interface{}
+	memo  map[int]interface{}
+}
+
+// decodePickle 解码 pickle 数据，结果为 string、int64、float64、bool、nil、[]byte、
+// []interface{}（列表、元组与集合）、map[string]interface{} 或 *Object
+func decodePickle(data []byte) (interface{}, error) {
+	u := &unpickler{data: data, memo: make(map[int]interface{})}
+	for {
+		if u.pos >= len(u.data) {
+			return nil, fmt.Errorf("pickle 数据不完整")
+		}
+		op := u.data[u.pos]
+		u.pos++
+		if op == opStop {
+			if len(u.stack) != 1 {
+				return nil, fmt.Errorf("pickle 结束时栈深度为 %d", len(u.stack))
+			}
+			return u.stack[0], nil
+		}
+		if err := u.step(op); err != nil {
+			return nil, fmt.Errorf("pickle 偏移 %d: %v", u.pos-1, err)
+		}
+	}
+}
+
+// step 执行一个操作码
+func (u *unpickler) step(op byte) error {
+	switch op {
+	case opProto:
+		_, err := u.read(1)
+		return err
+	case opFrame:
+		_, err := u.read(8)
+		return err
+	case opMark:
+		u.push(mark{})
+	case opPop:
+		_, err := u.pop()
+		return err
+	case opPopMark:
+		_, err := u.popMark()
+		return err
+	case opDup:
+		top, err := u.top()
+		if err != nil {
+			return err
+		}
+		u.push(top)
+	case opNone:
+		u.push(nil)
+	case opNewTrue:
+		u.push(true)
+	case opNewFalse:
+		u.push(false)
+	case opBinInt:
+		b, err := u.read(4)
+		if err != nil {
+			return err
+		}
+		u.push(int64(int32(binary.LittleEndian.Uint32(b))))
+	case opBinInt1:
+		b, err := u.read(1)
+		if err != nil {
+			return err
+		}
+		u.push(int64(b[0]))
+	case opBinInt2:
+		b, err := u.read(2)
+		if err != nil {
+			return err
+		}
+		u.push(int64(binary.LittleEndian.Uint16(b)))
+	case opLong1, opLong4:
+		size := 1
+		if op == opLong4 {
+			size = 4
+		}
+		b, err := u.readSized(size)
+		if err != nil {
+			return err
+		}
+		u.push(decodeLong(b))
+	case opBinFloat:
+		b, err := u.read(8)
+		if err != nil {
+			return err
+		}
+		u.push(math.Float64frombits(binary.BigEndian.Uint64(b)))
+	case opShortBinUni, opShortBinString:
+		b, err := u.readSized(1)
+		if err != nil {
+			return err
+		}
+		u.push(string(b))
+	case opBinUnicode, opBinString:
+		b, err := u.readSized(4)
+		if err != nil {
+			return err
+		}
+		u.push(string(b))
+	case opBinUnicode8:
+		b, err := u.readSized(8)
+		if err != nil {
+			return err
+		}
+		u.push(string(b))
+	case opShortBinBytes:
+		b, err := u.readSized(1)
+		if err != nil {
+			return err
+		}
+		u.push(append([]byte(nil), b...))
+	case opBinBytes:
+		b, err := u.readSized(4)
+		if err != nil {
+			return err
+		}
+		u.push(append([]byte(nil), b...))
+	case opBinBytes8, opByteArray8:
+		b, err := u.readSized(8)
+		if err != nil {
+			return err
+		}
+		u.push(append([]byte(nil), b...))
+	case opEmptyList:
+		u.push([]interface{}{})
+	case opEmptyTuple:
+		u.push([]interface{}{})
+	case opEmptyDict:
+		u.push(map[string]interface{}{})
+	case opEmptySet:
+		u.push([]interface{}{})
+	case opList, opTuple, opFrozenSet:
+		items, err := u.popMark()
+		if err != nil {
+			return err
+		}
+		u.push(items)
+	case opTuple1, opTuple2, opTuple3:
+		n := int(op-opTuple1) + 1
+		if len(u.stack) < n {
+			return fmt.Errorf("栈元素不足")
+		}
+		items := append([]interface{}(nil), u.stack[len(u.stack)-n:]...)
+		u.stack = u.stack[:len(u.stack)-n]
+		u.push(items)
+	case opAppend:
+		item, err := u.pop()
+		if err != nil {
+			return err
+		}
+		return u.appendItems([]interface{}{item})
+	case opAppends, opAddItems:
+		items, err := u.popMark()
+		if err != nil {
+			return err
+		}
+		return u.appendItems(items)
+	case opDict:
+		items, err := u.popMark()
+		if err != nil {
+			return err
+		}
+		u.push(map[string]interface{}{})
+		return u.setItems(items)
+	case opSetItem:
+		if len(u.stack) < 2 {
+			return fmt.Errorf("栈元素不足")
+		}
+		items := append([]interface{}(nil), u.stack[len(u.stack)-2:]...)
+		u.stack = u.stack[:len(u.stack)-2]
+		return u.setItems(items)
+	case opSetItems:
+		items, err := u.popMark()
+		if err != nil {
+			return err
+		}
+		return u.setItems(items)
+	case opMemoize:
+		top, err := u.top()
+		if err != nil {
+			return err
+		}
+		u.memo[len(u.memo)] = top
+	case opBinPut, opLongBinPut:
+		idx, err := u.readIndex(op == opLongBinPut)
+		if err != nil {
+			return err
+		}
+		top, err := u.top()
+		if err != nil {
+			return err
+		}
+		u.memo[idx] = top
+	case opBinGet, opLongBinGet:
+		idx, err := u.readIndex(op == opLongBinGet)
+		if err != nil {
+			return err
+		}
+		v, ok := u.memo[idx]
+		if !ok {
+			return fmt.Errorf("memo 中不存在 %d", idx)
+		}
+		u.push(v)
+	case opGlobal:
+		module, err := u.readLine()
+		if err != nil {
+			return err
+		}
+		name, err := u.readLine()
+		if err != nil {
+			return err
+		}
+		u.push(global(module + "." + name))
+	case opStackGlobal:
+		name, err := u.pop()
+		if err != nil {
+			return err
+		}
+		module, err := u.pop()
+		if err != nil {
+			return err
+		}
+		u.push(global(fmt.Sprintf("%v.%v", module, name)))
+	case opReduce, opNewObj:
+		args, err := u.pop()
+		if err != nil {
+			return err
+		}
+		class, err := u.pop()
+		if err != nil {
+			return err
+		}
+		return u.pushObject(class, args)
+	case opNewObjEx:
+		if _, err := u.pop(); err != nil {
+			return err
+		}
+		args, err := u.pop()
+		if err != nil {
+			return err
+		}
+		class, err := u.pop()
+		if err != nil {
+			return err
+		}
+		return u.pushObject(class, args)
+	case opBuild:
+		// 对象状态（如异常的 __dict__）对调用方没有意义，直接丢弃
+		_, err := u.pop()
+		return err
+	default:
+		return fmt.Errorf("不支持的操作码 0x%02x", op)
+	}
+	return nil
+}
+
+func (u *unpickler) push(v interface{}) {
+	u.stack = append(u.stack, v)
+}
+
+func (u *unpickler) pop() (interface{}, error) {
+	v, err := u.top()
+	if err != nil {
+		return nil, err
+	}
+	u.stack = u.stack[:len(u.stack)-1]
+	return v, nil
+}
+
+func (u *unpickler) top() (interface{}, error) {
+	if len(u.stack) == 0 {
+		return nil, fmt.Errorf("栈为空")
+	}
+	return u.stack[len(u.stack)-1], nil
+}
+
+// popMark 弹出最近的 MARK 之后的所有元素
+func (u *unpickler) popMark() ([]interface{}, error) {
+	for i := len(u.stack) - 1; i >= 0; i-- {
+		if _, ok := u.stack[i].(mark); ok {
+			items := append([]interface{}{}, u.stack[i+1:]...)
+			u.stack = u.stack[:i]
+			return items, nil
+		}
+	}
+	return nil, fmt.Errorf("缺少 MARK")
+}
+
+// appendItems 向栈顶的列表或集合追加元素
+func (u *unpickler) appendItems(items []interface{}) error {
+	top, err := u.top()
+	if err != nil {
+		return err
+	}
+	list, ok := top.([]interface{})
+	if !ok {
+		return fmt.Errorf("栈顶不是列表: %T", top)
+	}
+	u.stack[len(u.stack)-1] = append(list, items...)
+	return nil
+}
+
+// setItems 向栈顶的字典写入键值对，键统一转换为字符串
+func (u *unpickler) setItems(items []interface{}) error {
+	if len(items)%2 != 0 {
+		return fmt.Errorf("字典键值数量不匹配")
+	}
+	top, err := u.top()
+	if err != nil {
+		return err
+	}
+	dict, ok := top.(map[string]interface{})
+	if !ok {
+		return fmt.Errorf("栈顶不是字典: %T", top)
+	}
+	for i := 0; i < len(items); i += 2 {
+		dict[fmt.Sprint(items[i])] = items[i+1]
+	}
+	return nil
+}
+
+// pushObject 将 REDUCE、NEWOBJ 构造的对象表示为 *Object
+func (u *unpickler) pushObject(class, args interface{}) error {
+	name, ok := class.(global)
+	if !ok {
+		return fmt.Errorf("无法调用 %T", class)
+	}
+	list, _ := args.([]interface{})
+	u.push(&Object{Class: string(name), Args: list})
+	return nil
+}
+
+// read 读取定长数据
+func (u *unpickler) read(n int) ([]byte, error) {
+	if n < 0 || u.pos+n > len(u.data) {
+		return nil, fmt.Errorf("pickle 数据不完整")
+	}
+	b := u.data[u.pos : u.pos+n]
+	u.pos += n
+	return b, nil
+}
+
+// readSized 读取以 size 字节小端长度为前缀的数据
+func (u *unpickler) readSized(size int) ([]byte, error) {
+	b, err := u.read(size)
+	if err != nil {
+		return nil, err
+	}
+	var n uint64
+	for i := size - 1; i >= 0; i-- {
+		n = n<<8 | uint64(b[i])
+	}
+	if n > uint64(len(u.data)) {
+		return nil, fmt.Errorf("pickle 数据不完整")
+	}
+	return u.read(int(n))
+}
+
+// readIndex 读取 memo 下标，long 为 true 时为 4 字节
+func (u *unpickler) readIndex(long bool) (int, error) {
+	if long {
+		b, err := u.read(4)
+		if err != nil {
+			return 0, err
+		}
+		return int(binary.LittleEndian.Uint32(b)), nil
+	}
+	b, err := u.read(1)
+	if err != nil {
+		return 0, err
+	}
+	return int(b[0]), nil
+}
+
+// readLine 读取以换行结束的文本参数
+func (u *unpickler) readLine() (string, error) {
+	i := bytes.IndexByte(u.data[u.pos:], '\n')
+	if i < 0 {
+		return "", fmt.Errorf("pickle 数据不完整")
+	}
+	line := string(u.data[u.pos : u.pos+i])
+	u.pos += i + 1
+	return line, nil
+}
+
+// decodeLong 解码小端补码整数，超出 int64 时返回十进制字符串
+func decodeLong(b []byte) interface{} {
+	if len(b) == 0 {
+		return int64(0)
+	}
+	be := make([]byte, len(b))
+	for i := range b {
+		be[len(b)-1-i] = b[i]
+	}
+	n := new(big.Int).SetBytes(be)
+	if b[len(b)-1]&0x80 != 0 {
+		n.Sub(n, new(big.Int).Lsh(big.NewInt(1), uint(len(b)*8)))
+	}
+	if n.IsInt64() {
+		return n.Int64()
+	}
+	return n.String()
+}
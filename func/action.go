@@ -0,0 +1,98 @@
+package _func
+
+import (
+	"fmt"
+	"loginfopush/action"
+	"loginfopush/config"
+	"loginfopush/func/monitors"
+	"loginfopush/logger"
+	"strconv"
+	"time"
+)
+
+// actionJail 主动封禁产生的 ban、unban 事件中的 jail 名称
+const actionJail = "loginfopush"
+
+// actionManager 主动封禁管理器，未启用时为 nil
+var actionManager *action.Manager
+
+// initAction 按配置创建主动封禁管理器
+func initAction(cfg *config.Config) error {
+	if !cfg.ActionEnabled() {
+		return nil
+	}
+	actionConfig := *cfg.Action
+	if actionConfig.StateFile == "" {
+		actionConfig.StateFile = cfg.StatePath("bans.json")
+	}
+	var m *action.Manager
+	var err error
+	if actionConfig.Backend == config.ActionBackendFail2ban {
+		client := newFail2banClient(cfg)
+		m, err = action.NewManagerWithBackend(&actionConfig, action.NewFail2banBackend(client, actionConfig.Jail))
+	} else {
+		m, err = action.NewManager(&actionConfig, nil)
+	}
+	if err != nil {
+		return err
+	}
+	m.OnExpire(func(b action.Ban) {
+		handleEvent(monitors.Event{
+			Type: config.EventTypeUnban,
+			Time: time.Now(),
+			IP:   b.IP,
+			Fields: map[string]string{
+				"jail":     actionJail,
+				"action":   "unban",
+				"duration": b.Until.Sub(b.Start).Round(time.Second).String(),
+			},
+			Details: fmt.Sprintf("IP %s 封禁到期，已自动解封", b.IP),
+		})
+	})
+	actionManager = m
+	return nil
+}
+
+// blockOnFailure 记录登录失败，达到阈值并封禁成功时返回 ban 事件
+func blockOnFailure(event monitors.Event) *monitors.Event {
+	if actionManager == nil || event.Type != config.EventTypeFailure || event.IP == "" {
+		return nil
+	}
+	ban, err := actionManager.RecordFailure(event.IP, event.Details, event.Time)
+	if err != nil {
+		logger.Warnf("主动封禁失败: %v", err)
+	}
+	if ban == nil {
+		return nil
+	}
+
+	bantime := ban.Until.Sub(ban.Start)
+	return &monitors.Event{
+		Type:     config.EventTypeBan,
+		Time:     ban.Start,
+		IP:       ban.IP,
+		Location: event.Location,
+		Country:  event.Country,
+		ASN:      event.ASN,
+		Fields: map[string]string{
+			"jail":     actionJail,
+			"action":   "ban",
+			"attempts": strconv.Itoa(ban.Attempts),
+			"bantime":  bantime.String(),
+		},
+		Details: fmt.Sprintf("IP %s[%s] 登录失败 %d 次，已封禁 %s", ban.IP, event.Location, ban.Attempts, bantime),
+	}
+}
+
+// isActionEcho 判断 fail2ban 日志中的封禁、解封是否由主动封禁通过 fail2ban 后端发起，
+// 主动封禁已为这些操作产生过 ban、unban 事件，不再重复通知
+func isActionEcho(event monitors.Event) bool {
+	if actionManager == nil || event.IP == "" {
+		return false
+	}
+	jail, act := event.Fields["jail"], event.Fields["action"]
+	if jail == "" || jail == actionJail || (act != "ban" && act != "unban") {
+		return false
+	}
+	return actionManager.Echo(jail, act, event.IP, time.Now())
+}
@@ -0,0 +1,65 @@
+package _func
+
+import (
+	"loginfopush/config"
+	"loginfopush/fail2ban"
+	"loginfopush/func/monitors"
+	"loginfopush/logger"
+	"time"
+)
+
+// fail2banClient fail2ban 控制套接字客户端，未启用时为 nil
+var fail2banClient *fail2ban.Client
+
+// initFail2ban 按配置创建 fail2ban 客户端，服务不可用时只打印警告，后续命令会重新连接
+func initFail2ban(cfg *config.Config) {
+	if cfg.Fail2ban == nil || !cfg.Fail2ban.Enabled {
+		return
+	}
+	fail2banClient = newFail2banClient(cfg)
+	if err := fail2banClient.Ping(); err != nil {
+		logger.Warnf("fail2ban 控制套接字不可用: %v", err)
+	}
+}
+
+// newFail2banClient 按 fail2ban 配置创建客户端，未配置时使用默认套接字与超时
+func newFail2banClient(cfg *config.Config) *fail2ban.Client {
+	if cfg.Fail2ban == nil {
+		return fail2ban.NewClient("", 0)
+	}
+	return fail2ban.NewClient(cfg.Fail2ban.Socket, time.Duration(cfg.Fail2ban.Timeout))
+}
+
+// jailStatus 获取各 jail 的实时状态，用于安全报告
+func jailStatus() []fail2ban.JailStatus {
+	if fail2banClient == nil {
+		return nil
+	}
+	jails, err := fail2banClient.Status()
+	if err != nil {
+		logger.Warnf("获取 fail2ban 状态失败: %v", err)
+		return nil
+	}
+	return jails
+}
+
+// fillBanTime 日志中没有封禁时长时，从 fail2ban 查询 jail 的 bantime
+func fillBanTime(event *monitors.Event) {
+	if fail2banClient == nil || event.Type != config.EventTypeBan {
+		return
+	}
+	jail := event.Fields["jail"]
+	if jail == "" || event.Fields["bantime"] != "" {
+		return
+	}
+	bantime, err := fail2banClient.BanTime(jail)
+	if err != nil {
+		logger.Warnf("获取 jail %s 的封禁时长失败: %v", jail, err)
+		return
+	}
+	if bantime < 0 {
+		event.Fields["bantime"] = "permanent"
+		return
+	}
+	event.Fields["bantime"] = bantime.String()
+}
@@ -0,0 +1,82 @@
+package _func
+
+import (
+	"loginfopush/config"
+	"loginfopush/func/monitors"
+	"loginfopush/logger"
+	"loginfopush/notifier"
+	"loginfopush/store"
+	"time"
+)
+
+// statusNotEnabled 事件类型未启用推送，只用于统计与存储
+const statusNotEnabled = "not_enabled"
+
+// history 事件历史存储，未启用时为 nil
+var history *store.Store
+
+// initHistory 按配置打开事件历史存储并清理过期数据
+func initHistory(cfg *config.Config) error {
+	if !cfg.StoreEnabled() {
+		return nil
+	}
+	s, err := store.Open(cfg.StoreDir(), cfg.StoreRetention())
+	if err != nil {
+		return err
+	}
+	// 只在守护进程中清理过期数据，events 等只读查询不会删除分段
+	if err := s.Prune(time.Now()); err != nil {
+		s.Close()
+		return err
+	}
+	history = s
+	return nil
+}
+
+// closeHistory 关闭事件存储
+func closeHistory() {
+	if history == nil {
+		return
+	}
+	if err := history.Close(); err != nil {
+		logger.Warnf("关闭事件存储失败: %v", err)
+	}
+	history = nil
+}
+
+// recordHistory 保存事件与处理结果，data 为经过路由规则处理后的事件数据
+func recordHistory(event monitors.Event, data map[string]interface{}, result notifier.Result) {
+	if history == nil {
+		return
+	}
+
+	record := store.Record{
+		Time:     event.Time,
+		Type:     string(event.Type),
+		Severity: string(event.Severity),
+		IP:       event.IP,
+		User:     event.User,
+		Location: event.Location,
+		Country:  event.Country,
+		ASN:      event.ASN,
+		Details:  event.Details,
+		Raw:      event.Raw,
+		Fields:   event.Fields,
+		Status:   result.Status,
+	}
+	if severity, ok := data["Severity"].(string); ok && severity != "" {
+		record.Severity = severity
+	}
+	record.Tags, _ = data["Tags"].([]string)
+	for _, d := range result.Deliveries {
+		record.Deliveries = append(record.Deliveries, store.Delivery{
+			Notifier: d.Notifier,
+			Status:   d.Status,
+			Error:    d.Error,
+		})
+	}
+
+	if err := history.Append(record); err != nil {
+		logger.Warnf("保存事件失败: %v", err)
+	}
+}
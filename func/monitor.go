@@ -4,21 +4,46 @@ import (
 	"fmt"
 	"loginfopush/config"
 	"loginfopush/func/monitors"
+	"loginfopush/logger"
 	"loginfopush/notifier"
 	_ "loginfopush/notifier/bark"     // 注册 Bark 通知器
+	_ "loginfopush/notifier/exec"     // 注册 Exec 通知器
 	_ "loginfopush/notifier/fcm"      // 注册 FCM 通知器
+	_ "loginfopush/notifier/file"     // 注册 File 通知器
+	_ "loginfopush/notifier/syslog"   // 注册 Syslog 通知器
 	_ "loginfopush/notifier/telegram" // 注册 Telegram 通知器
 	_ "loginfopush/notifier/wecom"    // 注册 WeCom 通知器
 	_ "loginfopush/notifier/wxpusher" // 注册 WxPusher 通知器
+	"loginfopush/schedule"
 	"strings"
 	"sync"
 	"time"
 )
 
 var notifierManager *notifier.NotifierManager
+var monitorConfig *config.Config
 var monitorWg sync.WaitGroup
 var monitorStopChan chan struct{}
 
+// eventWg 与 eventStopChan 控制事件处理协程，监控器全部停止后再停止事件处理
+var eventWg sync.WaitGroup
+var eventStopChan chan struct{}
+
+// monitorMu 防止定时重启与退出同时进行
+var monitorMu sync.Mutex
+
+// shutdownChan 退出时关闭，停止定时重启、安全报告与封禁到期检查
+var shutdownChan chan struct{}
+
+// stats 安全统计，跨定时重启保留
+var stats = newSecurityStats()
+
+// severities 事件严重程度判定
+var severities = newSeverityAssigner()
+
+// defaultReportSchedule 安全报告的默认发送计划：每天 09:00
+const defaultReportSchedule = "0 9 * * *"
+
 // InitMonitor 初始化监控系统
 func InitMonitor(cfg *config.Config) error {
 	var err error
@@ -26,22 +51,43 @@ func InitMonitor(cfg *config.Config) error {
 	if err != nil {
 		return fmt.Errorf("初始化通知管理器失败: %v", err)
 	}
+	monitorConfig = cfg
+	initFail2ban(cfg)
+	if err := initAction(cfg); err != nil {
+		return fmt.Errorf("初始化主动封禁失败: %v", err)
+	}
+	if err := initHistory(cfg); err != nil {
+		return fmt.Errorf("初始化事件存储失败: %v", err)
+	}
+	// 已知用户列表跨进程重启保留，读取失败时只影响报告中的新用户
+	if err := stats.loadKnownUsers(cfg.StatePath(knownUsersFile)); err != nil {
+		logger.Warnf("%v", err)
+	}
 	return nil
 }
 
-// sendNotification 发送通知
-func sendNotification(event monitors.Event) error {
-	// 准备事件数据
+// eventData 准备发送与存储使用的事件数据
+func eventData(event monitors.Event) map[string]interface{} {
 	data := map[string]interface{}{
 		"IP":       event.IP,
+		"User":     event.User,
 		"Location": event.Location,
+		"Country":  event.Country,
+		"ASN":      event.ASN,
+		"Severity": string(event.Severity),
 		"Details":  event.Details,
-		"Time":     time.Now().Format("2006-01-02 15:04:05"),
+		"Time":     event.Time.Format("2006-01-02 15:04:05"),
 		"Raw":      event.Raw,
+		"Fields":   event.Fields,
 	}
 
-	// 发送事件通知
-	return notifierManager.SendEvent(config.EventType(event.Type), data)
+	// 自定义字段同时作为顶层字段，便于路由规则与去重直接引用
+	for name, value := range event.Fields {
+		if !hasKeyFold(data, name) {
+			data[name] = value
+		}
+	}
+	return data
 }
 
 // scheduleRestart 调度定时重启
@@ -54,81 +100,246 @@ func scheduleRestart() {
 		}
 
 		duration := next.Sub(now)
-		fmt.Printf("下次重启时间: %v (%.2f 小时后)\n", next.Format("2006-01-02 15:04:05"), duration.Hours())
+		logger.Infof("下次重启时间: %v (%.2f 小时后)", next.Format("2006-01-02 15:04:05"), duration.Hours())
 
 		select {
+		case <-shutdownChan:
+			return
 		case <-time.After(duration):
-			fmt.Println("执行定时重启...")
-			// 通知所有监控器停止
-			close(monitorStopChan)
-			// 等待所有监控器停止
-			monitorWg.Wait()
+			logger.Infof("执行定时重启...")
+			monitorMu.Lock()
+			select {
+			case <-shutdownChan:
+				// 已经开始退出
+				monitorMu.Unlock()
+				return
+			default:
+			}
+			// 停止所有监控器与事件处理
+			stopMonitors()
 			// 重新创建停止通道
 			monitorStopChan = make(chan struct{})
 			// 重新启动监控
-			go startMonitors()
+			startMonitors()
+			monitorMu.Unlock()
 		}
 	}
 }
 
+// stopMonitors 停止所有监控器，等处理完已读取的事件后再停止事件处理
+func stopMonitors() {
+	close(monitorStopChan)
+	monitorWg.Wait()
+	close(eventStopChan)
+	eventWg.Wait()
+}
+
 // startMonitors 启动所有监控器
 func startMonitors() {
 	// 创建事件通道
 	eventChan := make(chan monitors.Event)
+	eventStopChan = make(chan struct{})
+	stop := eventStopChan
 
 	// 记录是否有任何监控器成功启动
 	monitorsStarted := false
 
+	customConfigs, err := monitors.CustomLogConfigs(monitorConfig)
+	if err != nil {
+		logger.Warnf("无法加载自定义事件: %v", err)
+	}
+	logConfigs := append(append([]monitors.LogConfig(nil), monitors.LogConfigs...), customConfigs...)
+
+	// run 启动监控器，监控器停止后关闭
+	run := func(m monitors.Monitor) {
+		monitorsStarted = true
+		monitorWg.Add(1)
+		go func() {
+			defer monitorWg.Done()
+			defer m.Close()
+			m.Start(eventChan, monitorStopChan)
+		}()
+	}
+
 	// 启动所有配置的日志监控
-	for _, config := range monitors.LogConfigs {
+	authStarted := false
+	for _, config := range logConfigs {
 		m, err := monitors.NewLogMonitor(config)
 		if err != nil {
 			if strings.Contains(err.Error(), "均未启用") {
-				fmt.Printf("跳过监控 %s: %v\n", config.Path, err)
+				logger.Infof("跳过监控 %s: %v", config.Path, err)
 				continue
 			}
-			fmt.Printf("警告: 无法启动 %s 监控: %v\n", config.Path, err)
+			logger.Warnf("无法启动 %s 监控: %v", config.Path, err)
 			continue
 		}
-		monitorsStarted = true
-		defer m.Close()
+		if config.Type == monitors.LogTypeAuth {
+			authStarted = true
+		}
+		run(m)
+	}
 
-		monitorWg.Add(1)
-		go func(m *monitors.LogMonitor) {
-			defer monitorWg.Done()
-			m.Start(eventChan, monitorStopChan)
-		}(m)
+	// 没有 auth.log/secure 文件时从 systemd journal 读取认证日志
+	if !authStarted {
+		if m, err := monitors.NewJournalMonitor(); err != nil {
+			logger.Infof("跳过 journal 监控: %v", err)
+		} else {
+			run(m)
+		}
 	}
 
 	// 如果没有任何监控器启动，返回错误
 	if !monitorsStarted {
-		fmt.Printf("未能启动任何日志监控，请检查配置和事件启用状态\n")
+		logger.Errorf("未能启动任何日志监控，请检查配置和事件启用状态")
 		return
 	}
 
 	// 启动事件处理
+	eventWg.Add(1)
 	go func() {
-		for event := range eventChan {
-			if err := sendNotification(event); err != nil {
-				fmt.Printf("发送通知失败: %v\n", err)
-			} else {
-				fmt.Printf("已发送通知: %s\n", event.Details)
+		defer eventWg.Done()
+		for {
+			select {
+			case <-stop:
+				return
+			case event := <-eventChan:
+				processEvent(event)
 			}
 		}
 	}()
 }
 
-// StartMonitor 启动监控
+// processEvent 处理监控器产生的一个事件
+func processEvent(event monitors.Event) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	if isActionEcho(event) {
+		logger.Debugf("忽略主动封禁在 fail2ban 日志中的记录: %s", event.Details)
+		return
+	}
+	handleEvent(event)
+
+	// 登录失败达到阈值时由主动封禁产生 ban 事件
+	if ban := blockOnFailure(event); ban != nil {
+		handleEvent(*ban)
+	}
+}
+
+// handleEvent 判定严重程度、记录统计、写入归档渠道并推送事件
+func handleEvent(event monitors.Event) {
+	fillBanTime(&event)
+	severities.Assign(monitorConfig, &event)
+	stats.Record(event)
+	archiveEvent(event)
+
+	// 路由规则可能修改 data 中的严重程度与标签，存储处理后的结果
+	data, result, err := sendEvent(event)
+	recordHistory(event, data, result)
+	if result.Status == statusNotEnabled {
+		return
+	}
+	if err != nil {
+		logger.Errorf("发送通知失败: %v", err)
+	} else {
+		logger.Infof("已发送通知: %s", event.Details)
+	}
+}
+
+// archiveEvent 将每个事件写入 File、Syslog 等归档渠道，不经过推送流程的过滤
+func archiveEvent(event monitors.Event) {
+	if _, err := notifierManager.Archive(event.Type, eventData(event)); err != nil {
+		logger.Errorf("归档事件失败: %v", err)
+	}
+}
+
+// sendEvent 推送事件，返回推送使用的事件数据与处理结果
+func sendEvent(event monitors.Event) (map[string]interface{}, notifier.Result, error) {
+	data := eventData(event)
+
+	// 仅为安全报告统计的事件不单独推送
+	if !monitorConfig.EventEnabled(event.Type) {
+		return data, notifier.Result{Status: statusNotEnabled}, nil
+	}
+
+	result, err := notifierManager.SendEventAt(event.Type, data, event.Time)
+	return data, result, err
+}
+
+// scheduleReport 按计划发送安全报告
+func scheduleReport() error {
+	reportConfig, ok := monitorConfig.FindEvent(config.EventTypeReport)
+	if !ok {
+		return nil
+	}
+
+	spec := reportConfig.Schedule
+	if spec == "" {
+		spec = defaultReportSchedule
+	}
+	sched, err := schedule.Parse(spec)
+	if err != nil {
+		return fmt.Errorf("安全报告计划无效: %v", err)
+	}
+
+	go schedule.Run(sched, shutdownChan, func(now time.Time) {
+		data := stats.Snapshot(now)
+		data.Jails = jailStatus()
+		if err := notifierManager.SendReport(data); err != nil {
+			logger.Errorf("发送安全报告失败: %v", err)
+		} else {
+			logger.Infof("已发送安全报告")
+		}
+	})
+	return nil
+}
+
+// StartMonitor 启动监控，直到 StopMonitor 被调用
 func StartMonitor() error {
 	// 初始化停止通道
 	monitorStopChan = make(chan struct{})
+	shutdownChan = make(chan struct{})
 
 	// 启动定时重启协程
 	go scheduleRestart()
 
+	// 启动安全报告
+	if err := scheduleReport(); err != nil {
+		return err
+	}
+
+	// 启动主动封禁
+	if actionManager != nil {
+		if err := actionManager.Start(shutdownChan); err != nil {
+			return err
+		}
+	}
+
 	// 启动监控
+	monitorMu.Lock()
 	startMonitors()
+	monitorMu.Unlock()
+	return nil
+}
+
+// StopMonitor 依次停止监控器与事件处理、发送汇总缓存中的事件、重复抑制的汇总与免打扰延迟中的消息，
+// 最后关闭事件存储，用于收到退出信号时
+func StopMonitor() {
+	monitorMu.Lock()
+	defer monitorMu.Unlock()
 
-	// 保持主程序运行
-	select {}
+	close(shutdownChan)
+	stopMonitors()
+	notifierManager.Close()
+	closeHistory()
+}
+
+// hasKeyFold 忽略大小写判断事件数据中是否已有该字段
+func hasKeyFold(data map[string]interface{}, key string) bool {
+	for k := range data {
+		if strings.EqualFold(k, key) {
+			return true
+		}
+	}
+	return false
 }
@@ -0,0 +1,125 @@
+package monitors
+
+import (
+	"bufio"
+	"fmt"
+	"loginfopush/logger"
+	"os/exec"
+	"sync/atomic"
+	"time"
+)
+
+// journalIdentifiers 从 systemd journal 读取的日志标识
+var journalIdentifiers = []string{"sshd", "sshd-session", "sudo", "su"}
+
+// JournalMonitor 通过 journalctl 读取 systemd journal，用于没有 auth.log/secure 文件的系统
+type JournalMonitor struct {
+	parser *LogMonitor // 复用认证日志的解析与会话状态
+	cmd    *exec.Cmd
+}
+
+// NewJournalMonitor 创建 journal 监控器
+func NewJournalMonitor() (*JournalMonitor, error) {
+	if !shouldMonitorLogType(LogTypeAuth) {
+		return nil, fmt.Errorf("日志类型 %v 的所有事件均未启用，跳过监控", LogTypeAuth)
+	}
+	if _, err := exec.LookPath("journalctl"); err != nil {
+		return nil, fmt.Errorf("未找到 journalctl: %v", err)
+	}
+
+	atomic.StoreInt32(&sshdFailuresActive, 1)
+	return &JournalMonitor{
+		parser: &LogMonitor{
+			config:   LogConfig{Type: LogTypeAuth, Path: "journal", Patterns: authPatterns},
+			path:     "journal",
+			sessions: trackerFor("journal"),
+			allow:    compileAllowRules(),
+			failures: newFailureTracker(),
+		},
+	}, nil
+}
+
+// Close 关闭监控器
+func (m *JournalMonitor) Close() error {
+	if m.cmd != nil && m.cmd.Process != nil {
+		return m.cmd.Process.Kill()
+	}
+	return nil
+}
+
+// Start 开始监控，journalctl 意外退出时自动重启
+func (m *JournalMonitor) Start(eventChan chan<- Event, stopChan <-chan struct{}) {
+	logger.Infof("开始监控 systemd journal")
+
+	// 定期检查长会话与等待超时的失败日志
+	go func() {
+		ticker := time.NewTicker(pendingFailureTimeout)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stopChan:
+				return
+			case <-ticker.C:
+				m.parser.checkLongSessions(eventChan)
+				m.parser.checkPendingFailures(eventChan)
+			}
+		}
+	}()
+
+	for {
+		err := m.follow(eventChan, stopChan)
+
+		select {
+		case <-stopChan:
+			// 停止时 journalctl 被结束，不是错误
+			logger.Infof("停止监控 systemd journal")
+			return
+		default:
+		}
+		if err != nil {
+			logger.Errorf("读取 journal 失败: %v", err)
+		}
+		select {
+		case <-stopChan:
+			logger.Infof("停止监控 systemd journal")
+			return
+		case <-time.After(5 * time.Second):
+		}
+	}
+}
+
+// follow 启动 journalctl 并逐行处理输出，直到进程退出
+func (m *JournalMonitor) follow(eventChan chan<- Event, stopChan <-chan struct{}) error {
+	args := []string{"--follow", "--lines=0", "--output=short", "--no-pager"}
+	for _, id := range journalIdentifiers {
+		args = append(args, "-t", id)
+	}
+
+	cmd := exec.Command("journalctl", args...)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return err
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	m.cmd = cmd
+
+	done := make(chan struct{})
+	defer close(done)
+	go func() {
+		select {
+		case <-stopChan:
+			cmd.Process.Kill()
+		case <-done:
+		}
+	}()
+
+	scanner := bufio.NewScanner(stdout)
+	for scanner.Scan() {
+		if event := m.parser.processLine(scanner.Text() + "\n"); event != nil {
+			eventChan <- *event
+		}
+	}
+	return cmd.Wait()
+}
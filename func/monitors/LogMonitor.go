@@ -7,11 +7,13 @@ import (
 	"io"
 	"io/ioutil"
 	"loginfopush/config"
+	"loginfopush/logger"
 	"net/http"
 	"os"
 	"regexp"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 )
 
@@ -22,6 +24,14 @@ type LogMonitor struct {
 	reader *bufio.Reader
 	path   string // 保存文件路径
 	offset int64  // 保存读取位置
+
+	sessions         *sessionTracker                         // SSH 会话状态（仅 auth 日志）
+	lastSessionCheck time.Time                               // 上次检查长会话的时间
+	allow            []allowRule                             // sudo 命令白名单
+	failures         *failureTracker                         // sshd 登录失败日志合并（仅 auth 日志）
+	fail2ban         *fail2banTracker                        // fail2ban 失败次数与封禁状态（仅 fail2ban 日志）
+	clock            func() time.Time                        // 当前时间，回放时为日志中的时间
+	locate           func(ip string) (ipLocationInfo, error) // 查询 IP 位置，为 nil 时不查询
 }
 
 // NewLogMonitor 创建新的日志监控器
@@ -45,12 +55,21 @@ func NewLogMonitor(config LogConfig) (*LogMonitor, error) {
 
 	reader := bufio.NewReader(file)
 
+	if config.Type == LogTypeAuth {
+		atomic.StoreInt32(&sshdFailuresActive, 1)
+	}
+
 	return &LogMonitor{
-		config: config,
-		file:   file,
-		reader: reader,
-		path:   config.Path,
-		offset: offset,
+		config:   config,
+		file:     file,
+		reader:   reader,
+		path:     config.Path,
+		offset:   offset,
+		sessions: trackerFor(config.Path),
+		allow:    compileAllowRules(),
+		failures: newFailureTracker(),
+		fail2ban: fail2banTrackerFor(config.Path),
+		locate:   getIPLocation,
 	}, nil
 }
 
@@ -59,6 +78,22 @@ func (m *LogMonitor) Close() error {
 	return m.file.Close()
 }
 
+// now 当前时间
+func (m *LogMonitor) now() time.Time {
+	if m.clock != nil {
+		return m.clock()
+	}
+	return time.Now()
+}
+
+// emit 记录事件时间并发送事件
+func (m *LogMonitor) emit(eventChan chan<- Event, event Event) {
+	if event.Time.IsZero() {
+		event.Time = m.now()
+	}
+	eventChan <- event
+}
+
 // reopenFile 重新打开文件
 func (m *LogMonitor) reopenFile() error {
 	// 关闭现有文件
@@ -98,17 +133,22 @@ func (m *LogMonitor) reopenFile() error {
 
 // Start 开始监控
 func (m *LogMonitor) Start(eventChan chan<- Event, stopChan <-chan struct{}) {
-	fmt.Printf("开始监控日志文件: %s\n", m.config.Path)
+	logger.Infof("开始监控日志文件: %s", m.config.Path)
 
 	for {
 		select {
 		case <-stopChan:
-			fmt.Printf("停止监控日志文件: %s\n", m.config.Path)
+			logger.Infof("停止监控日志文件: %s", m.config.Path)
 			return
 		default:
 			line, err := m.reader.ReadString('\n')
 			if err != nil {
 				if err == io.EOF {
+					if m.config.Type == LogTypeAuth {
+						m.checkLongSessions(eventChan)
+						m.checkPendingFailures(eventChan)
+					}
+
 					// 保存当前位置
 					if m.file != nil {
 						m.offset, _ = m.file.Seek(0, 1)
@@ -116,14 +156,14 @@ func (m *LogMonitor) Start(eventChan chan<- Event, stopChan <-chan struct{}) {
 
 					// 检查文件是否被轮转
 					if err := m.reopenFile(); err != nil {
-						fmt.Printf("重新打开文件失败: %v\n", err)
+						logger.Errorf("重新打开文件失败: %v", err)
 						time.Sleep(5 * time.Second)
 					}
 
 					time.Sleep(100 * time.Millisecond)
 					continue
 				}
-				fmt.Printf("读取日志错误: %v\n", err)
+				logger.Errorf("读取日志错误: %v", err)
 				time.Sleep(1 * time.Second)
 				continue
 			}
@@ -133,7 +173,7 @@ func (m *LogMonitor) Start(eventChan chan<- Event, stopChan <-chan struct{}) {
 
 			// 处理日志行
 			if event := m.processLine(line); event != nil {
-				eventChan <- *event
+				m.emit(eventChan, *event)
 			}
 		}
 	}
@@ -141,52 +181,54 @@ func (m *LogMonitor) Start(eventChan chan<- Event, stopChan <-chan struct{}) {
 
 // processLine 处理单行日志
 func (m *LogMonitor) processLine(line string) *Event {
+	switch m.config.Type {
+	case LogTypeCustom:
+		return m.processCustomLine(line)
+	case LogTypeFail2ban:
+		return m.processFail2ban(line)
+	}
+	if m.config.Type == LogTypeAuth {
+		if event, handled := m.processPrivilege(line); handled {
+			return event
+		}
+		if event, handled := m.processSSHDFailure(line); handled {
+			return event
+		}
+	}
+
 	for _, pattern := range m.config.Patterns {
 		if strings.Contains(line, pattern) {
 			event := &Event{
-				Raw: line,
-				IP:  extractIP(line),
+				Raw:  line,
+				IP:   extractIP(line),
+				User: extractUser(line),
 			}
-			// 根据ip 地址查询归属 https://api.ip.sb/geoip/
-			location, err := getIPLocation(event.IP)
-			if err != nil {
-				fmt.Printf("获取IP位置失败: %v\n", err)
-			}
-
-			event.Location = location
-
 			// 根据日志类型和模式确定事件类型
 			switch m.config.Type {
-			case LogTypeFail2ban:
-				if strings.Contains(line, "Ban") {
-					// 检查 ban 事件是否启用
-					if !isEventEnabled("ban") {
-						return nil
-					}
-					event.Type = EventTypeBan
-					event.Details = fmt.Sprintf("IP %s[%s] 已被 fail2ban 封禁", event.IP, location)
-				} else if strings.Contains(line, "Found") {
-					// 检查 fail 事件是否启用
-					if !isEventEnabled("fail") {
-						return nil
-					}
-					event.Type = EventTypeFailure
-					event.Details = fmt.Sprintf("检测到来自 IP %s[%s] 的失败登录尝试", event.IP, location)
-				}
 			case LogTypeAuth:
+				if strings.Contains(line, "session closed for user") || strings.Contains(line, "Disconnected from user") {
+					return m.processSessionEnd(line, event)
+				}
 				if strings.Contains(line, "Accepted") {
-					// 检查 success 事件是否启用
-					if !isEventEnabled("success") {
+					// 检查 success 事件是否启用，未启用时不查询 IP 位置，登出时再查询
+					enabled := isEventEnabled(config.EventTypeSuccess)
+					if enabled {
+						// 根据ip 地址查询归属 https://api.ip.sb/geoip/
+						m.fillLocation(event)
+					}
+					// 无论是否推送登录通知都记录会话，用于登出与长会话事件
+					m.sessions.open(sshdPID(line), sshdPort(line), event, m.now())
+					if !enabled {
 						return nil
 					}
-					event.Type = EventTypeSuccess
+					event.Type = config.EventTypeSuccess
 					// 根据日志内容判断是密码登录还是密钥登录
 					if strings.Contains(line, "password") {
 						event.Details = fmt.Sprintf("IP %s 密码登录成功", event.IP)
 					} else if strings.Contains(line, "publickey") {
-						event.Details = fmt.Sprintf("IP %s[%s] 密钥登录成功", event.IP, location)
+						event.Details = fmt.Sprintf("IP %s[%s] 密钥登录成功", event.IP, event.Location)
 					} else {
-						event.Details = fmt.Sprintf("IP %s[%s] 登录成功", event.IP, location)
+						event.Details = fmt.Sprintf("IP %s[%s] 登录成功", event.IP, event.Location)
 					}
 				}
 			}
@@ -199,20 +241,76 @@ func (m *LogMonitor) processLine(line string) *Event {
 	return nil
 }
 
+// processCustomLine 按自定义事件的正则匹配日志行，命名分组作为事件字段
+func (m *LogMonitor) processCustomLine(line string) *Event {
+	text := strings.TrimRight(line, "\r\n")
+	for _, p := range m.config.Custom {
+		match := p.Regexp.FindStringSubmatch(text)
+		if match == nil {
+			continue
+		}
+
+		event := &Event{
+			Type:    p.Type,
+			Raw:     line,
+			Details: strings.TrimSpace(text),
+			Fields:  make(map[string]string),
+		}
+		for i, name := range p.Regexp.SubexpNames() {
+			if name != "" {
+				event.Fields[name] = match[i]
+			}
+		}
+
+		event.IP = event.Fields["ip"]
+		if event.IP == "" {
+			event.IP = extractIP(line)
+		}
+		event.User = event.Fields["user"]
+		if event.User == "" {
+			event.User = extractUser(line)
+		}
+
+		if event.IP != "" {
+			m.fillLocation(event)
+		}
+		return event
+	}
+	return nil
+}
+
+// fillLocation 查询并填充事件 IP 的位置信息，在确定发送事件后调用
+func (m *LogMonitor) fillLocation(event *Event) {
+	if m.locate == nil {
+		event.Location = skippedLocation.location
+		return
+	}
+	geo, err := m.locate(event.IP)
+	if err != nil {
+		logger.Warnf("获取IP位置失败: %v", err)
+	}
+	event.Location = geo.location
+	event.Country = geo.country
+	event.ASN = geo.asn
+}
+
 // isEventEnabled 检查事件是否启用
-func isEventEnabled(eventType string) bool {
+func isEventEnabled(eventType config.EventType) bool {
 	if config.GlobalConfig == nil {
 		return true // 如果配置未加载，默认启用所有事件
 	}
 
-	// 在事件配置中查找对应事件
-	for _, evt := range config.GlobalConfig.Events {
-		if string(evt.Type) == eventType {
-			return evt.Enabled
-		}
+	// 启用安全报告时需要采集所有事件用于统计
+	if config.GlobalConfig.EventEnabled(config.EventTypeReport) {
+		return true
+	}
+
+	// 主动封禁需要统计登录失败
+	if eventType == config.EventTypeFailure && config.GlobalConfig.ActionEnabled() {
+		return true
 	}
 
-	return false // 如果未找到事件配置，默认不启用
+	return config.GlobalConfig.EventEnabled(eventType)
 }
 
 // extractIP 从日志行中提取 IP 地址（支持 IPv4 和 IPv6）
@@ -233,6 +331,17 @@ func extractIP(line string) string {
 	return match
 }
 
+// userPattern 匹配 sshd 日志中的用户名，如 "for root from"、"for invalid user admin from"
+var userPattern = regexp.MustCompile(`for (?:invalid user )?(\S+) from`)
+
+// extractUser 从日志行中提取用户名
+func extractUser(line string) string {
+	if match := userPattern.FindStringSubmatch(line); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
 // 使用缓存的 IP 位置信息
 var (
 	ipLocationCache = make(map[string]ipLocationInfo)
@@ -241,13 +350,21 @@ var (
 
 type ipLocationInfo struct {
 	location  string
+	country   string // 国家
+	asn       string // 自治系统，如 "AS4134 CHINANET"
 	timestamp time.Time
 }
 
+// unknownLocation 查询失败时使用的位置信息
+var unknownLocation = ipLocationInfo{location: "未知位置"}
+
+// skippedLocation 不查询 IP 位置时使用的位置信息
+var skippedLocation = ipLocationInfo{location: "未查询"}
+
 // getIPLocation 修改后的函数，添加缓存机制
-func getIPLocation(ip string) (string, error) {
+func getIPLocation(ip string) (ipLocationInfo, error) {
 	if ip == "" {
-		return "未知位置", nil
+		return unknownLocation, nil
 	}
 
 	// 检查缓存
@@ -256,7 +373,7 @@ func getIPLocation(ip string) (string, error) {
 		// 如果缓存未过期（24小时内）
 		if time.Since(info.timestamp) < 24*time.Hour {
 			ipCacheMutex.RUnlock()
-			return info.location, nil
+			return info, nil
 		}
 	}
 	ipCacheMutex.RUnlock()
@@ -270,7 +387,7 @@ func getIPLocation(ip string) (string, error) {
 
 		request, err := http.NewRequest("GET", fmt.Sprintf("https://api.ip.sb/geoip/%s", ip), nil)
 		if err != nil {
-			return "未知位置", err
+			return unknownLocation, err
 		}
 
 		request.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/58.0.3029.110 Safari/537.36")
@@ -281,38 +398,45 @@ func getIPLocation(ip string) (string, error) {
 				time.Sleep(time.Second * time.Duration(i+1))
 				continue
 			}
-			return "未知位置", err
+			return unknownLocation, err
 		}
 		defer response.Body.Close()
 
 		body, err := ioutil.ReadAll(response.Body)
 		if err != nil {
-			return "未知位置", err
+			return unknownLocation, err
 		}
 
-		var ipInfo map[string]interface{}
+		var ipInfo struct {
+			Country         string `json:"country"`
+			City            string `json:"city"`
+			ASN             int    `json:"asn"`
+			ASNOrganization string `json:"asn_organization"`
+		}
 		err = json.Unmarshal(body, &ipInfo)
 		if err != nil {
-			return "未知位置", err
+			return unknownLocation, err
 		}
 
-		var location string
-		if city, ok := ipInfo["city"]; ok {
-			location = fmt.Sprintf("%s-%s", ipInfo["country"].(string), city.(string))
-		} else {
-			location = fmt.Sprintf("%s", ipInfo["country"])
+		info := ipLocationInfo{
+			location:  ipInfo.Country,
+			country:   ipInfo.Country,
+			timestamp: time.Now(),
+		}
+		if ipInfo.City != "" {
+			info.location = fmt.Sprintf("%s-%s", ipInfo.Country, ipInfo.City)
+		}
+		if ipInfo.ASN != 0 {
+			info.asn = strings.TrimSpace(fmt.Sprintf("AS%d %s", ipInfo.ASN, ipInfo.ASNOrganization))
 		}
 
 		// 更新缓存
 		ipCacheMutex.Lock()
-		ipLocationCache[ip] = ipLocationInfo{
-			location:  location,
-			timestamp: time.Now(),
-		}
+		ipLocationCache[ip] = info
 		ipCacheMutex.Unlock()
 
-		return location, nil
+		return info, nil
 	}
 
-	return "未知位置", fmt.Errorf("获取位置信息失败，已达到最大重试次数")
+	return unknownLocation, fmt.Errorf("获取位置信息失败，已达到最大重试次数")
 }
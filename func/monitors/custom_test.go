@@ -0,0 +1,126 @@
+package monitors
+
+import (
+	"loginfopush/config"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestCustomLogConfigs(t *testing.T) {
+	cfg := &config.Config{Events: map[string]config.EventConfig{
+		"web_login": {Type: "web_login", Enabled: true, Source: &config.EventSourceConfig{
+			Path:     "/var/log/web.log",
+			Patterns: []string{`login (?P<user>\w+) from (?P<ip>\S+)`},
+		}},
+		"web_ban": {Type: config.EventTypeBan, Enabled: true, Source: &config.EventSourceConfig{
+			Path:     "/var/log/web.log",
+			Patterns: []string{`banned (?P<ip>\S+)`},
+		}},
+		"vpn": {Type: "vpn", Enabled: true, Source: &config.EventSourceConfig{
+			Path:     "/var/log/openvpn.log",
+			Patterns: []string{`(?P<user>\w+)/(?P<ip>[0-9.]+):\d+ MULTI`},
+		}},
+		"disabled": {Type: "disabled", Source: &config.EventSourceConfig{
+			Path:     "/var/log/other.log",
+			Patterns: []string{`x`},
+		}},
+		"ban": {Type: config.EventTypeBan, Enabled: true},
+	}}
+
+	configs, err := CustomLogConfigs(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 同一路径的事件合并为一个监控，未启用或没有日志来源的事件不创建
+	if len(configs) != 2 || configs[0].Path != "/var/log/openvpn.log" || configs[1].Path != "/var/log/web.log" {
+		t.Fatalf("configs = %+v", configs)
+	}
+	types := make(map[config.EventType]bool)
+	for _, p := range configs[1].Custom {
+		types[p.Type] = true
+	}
+	if len(configs[1].Custom) != 2 || !types["web_login"] || !types[config.EventTypeBan] {
+		t.Errorf("web.log 的匹配规则 = %+v", configs[1].Custom)
+	}
+
+	cfg.Events["vpn"].Source.Patterns = append(cfg.Events["vpn"].Source.Patterns, `(?P<ip`)
+	if _, err := CustomLogConfigs(cfg); err == nil || !strings.Contains(err.Error(), "事件 vpn 的匹配规则无效") {
+		t.Errorf("CustomLogConfigs() = %v, want 匹配规则无效", err)
+	}
+}
+
+func TestCustomEvents(t *testing.T) {
+	cfg := &config.Config{Events: map[string]config.EventConfig{
+		"web_login": {Type: "web_login", Enabled: true, Source: &config.EventSourceConfig{
+			Path:     "/var/log/web.log",
+			Patterns: []string{`login (?P<user>\w+) from (?P<ip>\S+) status=(?P<status>\d+)`},
+		}},
+		"web_error": {Type: "web_error", Enabled: true, Source: &config.EventSourceConfig{
+			Path:     "/var/log/web.log",
+			Patterns: []string{`ERROR (?P<message>.+)$`},
+		}},
+	}}
+	configs, err := CustomLogConfigs(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		line   string
+		typ    config.EventType // 为空表示不产生事件
+		ip     string
+		user   string
+		fields map[string]string
+	}{
+		{
+			line:   "2024-01-01T10:00:00Z web: login alice from 203.0.113.7 status=200",
+			typ:    "web_login",
+			ip:     "203.0.113.7",
+			user:   "alice",
+			fields: map[string]string{"user": "alice", "ip": "203.0.113.7", "status": "200"},
+		},
+		{
+			// 没有 ip、user 分组时从日志行中提取
+			line:   "2024-01-01T10:00:00Z web: ERROR upstream 198.51.100.2 timed out for root from 198.51.100.2",
+			typ:    "web_error",
+			ip:     "198.51.100.2",
+			user:   "root",
+			fields: map[string]string{"message": "upstream 198.51.100.2 timed out for root from 198.51.100.2"},
+		},
+		{
+			line:   "2024-01-01T10:00:00Z web: ERROR disk full",
+			typ:    "web_error",
+			fields: map[string]string{"message": "disk full"},
+		},
+		{
+			line: "2024-01-01T10:00:00Z web: GET / 200",
+		},
+	}
+	for _, tt := range tests {
+		tm := newTestMonitor(t, LogTypeCustom)
+		tm.config = configs[0]
+		events := tm.feed(tt.line)
+		if tt.typ == "" {
+			if len(events) != 0 {
+				t.Errorf("processLine(%s) = %+v, want 无事件", tt.line, events)
+			}
+			continue
+		}
+		if len(events) != 1 {
+			t.Errorf("processLine(%s) 产生了 %d 个事件, want 1", tt.line, len(events))
+			continue
+		}
+		e := events[0]
+		if e.Type != tt.typ || e.IP != tt.ip || e.User != tt.user || !reflect.DeepEqual(e.Fields, tt.fields) {
+			t.Errorf("processLine(%s) = %s %s %s %v, want %s %s %s %v", tt.line, e.Type, e.IP, e.User, e.Fields, tt.typ, tt.ip, tt.user, tt.fields)
+		}
+		if e.Details != strings.TrimSpace(tt.line) {
+			t.Errorf("Details = %q, want 原始日志行", e.Details)
+		}
+		// 只为有 IP 的事件查询位置
+		if (e.IP != "") != (len(tm.lookups) == 1) || (e.IP != "" && e.Location != "测试位置") {
+			t.Errorf("processLine(%s) 查询位置 %v, location = %q", tt.line, tm.lookups, e.Location)
+		}
+	}
+}
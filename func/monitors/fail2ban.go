@@ -0,0 +1,233 @@
+package monitors
+
+import (
+	"fmt"
+	"loginfopush/config"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// foundCountTTL 失败次数的统计窗口，超过该时间未再出现的 IP 重新计数
+const foundCountTTL = 24 * time.Hour
+
+// fail2ban 动作
+const (
+	fail2banFound   = "found"   // 检测到失败尝试
+	fail2banBan     = "ban"     // 封禁
+	fail2banRestore = "restore" // fail2ban 重启后恢复封禁
+	fail2banUnban   = "unban"   // 解封
+)
+
+var (
+	// fail2banPattern 匹配 "2024-01-01 10:00:00,456 fail2ban.actions [1234]: NOTICE  [sshd] Ban 1.2.3.4"
+	fail2banPattern = regexp.MustCompile(`fail2ban\.\w+\s*\[\d+\]:\s*\w+\s+\[([^\]]+)\]\s+(.*)$`)
+	// fail2banActionPattern 匹配 jail 之后的动作
+	fail2banActionPattern = regexp.MustCompile(`^(Found|Ban|Unban|Restore Ban|Increase Ban) (\S+)(.*)$`)
+	// increasePattern 匹配 "Increase Ban" 之后的 "(3 # 2:00:00 -> 2024-01-01 12:00:00)"
+	increasePattern = regexp.MustCompile(`\((\d+) # (.+?) -> (.+?)\)`)
+)
+
+// banRecord 一次封禁的状态
+type banRecord struct {
+	start    time.Time
+	bantime  string // 封禁时长，来自 Increase Ban 日志
+	banCount string // 第几次封禁，来自 Increase Ban 日志
+}
+
+// foundRecord 封禁前的失败尝试计数
+type foundRecord struct {
+	count int
+	last  time.Time
+}
+
+// fail2banTracker 按 jail 与 IP 统计失败次数和封禁状态
+type fail2banTracker struct {
+	mu     sync.Mutex
+	found  map[string]*foundRecord
+	banned map[string]*banRecord
+}
+
+// 按日志路径保存的 fail2ban 状态，定时重启监控器后仍然保留
+var (
+	fail2banTrackers = make(map[string]*fail2banTracker)
+	fail2banMutex    sync.Mutex
+)
+
+// fail2banTrackerFor 获取日志文件对应的 fail2ban 状态
+func fail2banTrackerFor(path string) *fail2banTracker {
+	fail2banMutex.Lock()
+	defer fail2banMutex.Unlock()
+
+	t, ok := fail2banTrackers[path]
+	if !ok {
+		t = &fail2banTracker{
+			found:  make(map[string]*foundRecord),
+			banned: make(map[string]*banRecord),
+		}
+		fail2banTrackers[path] = t
+	}
+	return t
+}
+
+// addFound 记录一次失败尝试，返回累计次数
+func (t *fail2banTracker) addFound(key string, now time.Time) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	for k, r := range t.found {
+		if now.Sub(r.last) >= foundCountTTL {
+			delete(t.found, k)
+		}
+	}
+	r, ok := t.found[key]
+	if !ok {
+		r = &foundRecord{}
+		t.found[key] = r
+	}
+	r.count++
+	r.last = now
+	return r.count
+}
+
+// ban 记录封禁，返回封禁前的失败次数与封禁记录
+func (t *fail2banTracker) ban(key string, now time.Time) (int, banRecord) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	attempts := 0
+	if r, ok := t.found[key]; ok {
+		attempts = r.count
+		delete(t.found, key)
+	}
+	b, ok := t.banned[key]
+	if ok && b.start.IsZero() {
+		// Increase Ban 日志先于 Ban 日志出现
+		b.start = now
+	} else {
+		b = &banRecord{start: now}
+		t.banned[key] = b
+	}
+	return attempts, *b
+}
+
+// increase 记录 Increase Ban 日志中的封禁时长与次数
+func (t *fail2banTracker) increase(key, banCount, bantime string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b, ok := t.banned[key]
+	if !ok {
+		b = &banRecord{}
+		t.banned[key] = b
+	}
+	b.banCount = banCount
+	b.bantime = bantime
+}
+
+// unban 结束封禁，返回封禁记录
+func (t *fail2banTracker) unban(key string) (*banRecord, bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	b, ok := t.banned[key]
+	if ok {
+		delete(t.banned, key)
+	}
+	return b, ok
+}
+
+// processFail2ban 解析 fail2ban 日志为 ban、unban 与 fail 事件
+func (m *LogMonitor) processFail2ban(line string) *Event {
+	text := strings.TrimRight(line, "\r\n")
+	match := fail2banPattern.FindStringSubmatch(text)
+	if match == nil {
+		return nil
+	}
+	jail := match[1]
+	action := fail2banActionPattern.FindStringSubmatch(match[2])
+	if action == nil {
+		return nil
+	}
+	ip := action[2]
+	key := jail + "|" + ip
+	now := m.now()
+
+	event := &Event{
+		IP:  ip,
+		Raw: line,
+		Fields: map[string]string{
+			"jail": jail,
+		},
+	}
+
+	switch action[1] {
+	case "Found":
+		attempts := m.fail2ban.addFound(key, now)
+		// 已直接解析 sshd 日志时，sshd jail 的 Found 与其重复
+		if parsingSSHDFailures() && jail == "sshd" {
+			return nil
+		}
+		if !isEventEnabled(config.EventTypeFailure) {
+			return nil
+		}
+		m.fillLocation(event)
+		event.Type = config.EventTypeFailure
+		event.Fields["action"] = fail2banFound
+		event.Fields["attempts"] = strconv.Itoa(attempts)
+		event.Details = fmt.Sprintf("jail %s 检测到来自 IP %s[%s] 的失败登录尝试（第 %d 次）", jail, ip, event.Location, attempts)
+
+	case "Ban", "Restore Ban":
+		attempts, b := m.fail2ban.ban(key, now)
+		if !isEventEnabled(config.EventTypeBan) {
+			return nil
+		}
+		m.fillLocation(event)
+		event.Type = config.EventTypeBan
+		if b.bantime != "" {
+			event.Fields["bantime"] = b.bantime
+			event.Fields["ban_count"] = b.banCount
+		}
+		if action[1] == "Restore Ban" {
+			event.Fields["action"] = fail2banRestore
+			event.Details = fmt.Sprintf("fail2ban 重启后恢复对 IP %s[%s] 的封禁（jail: %s）", ip, event.Location, jail)
+		} else {
+			event.Fields["action"] = fail2banBan
+			event.Fields["attempts"] = strconv.Itoa(attempts)
+			event.Details = fmt.Sprintf("IP %s[%s] 已被 fail2ban 封禁（jail: %s）", ip, event.Location, jail)
+			if attempts > 0 {
+				event.Details = fmt.Sprintf("IP %s[%s] 失败 %d 次后已被 fail2ban 封禁（jail: %s）", ip, event.Location, attempts, jail)
+			}
+		}
+
+	case "Increase Ban":
+		// 封禁时长递增只更新封禁状态，对应的 Ban 日志会产生事件
+		if inc := increasePattern.FindStringSubmatch(action[3]); inc != nil {
+			m.fail2ban.increase(key, inc[1], inc[2])
+		}
+		return nil
+
+	case "Unban":
+		b, ok := m.fail2ban.unban(key)
+		if !isEventEnabled(config.EventTypeUnban) {
+			return nil
+		}
+		m.fillLocation(event)
+		event.Type = config.EventTypeUnban
+		event.Fields["action"] = fail2banUnban
+		event.Details = fmt.Sprintf("IP %s[%s] 已被 fail2ban 解封（jail: %s）", ip, event.Location, jail)
+		if ok && !b.start.IsZero() {
+			duration := now.Sub(b.start).Round(time.Second)
+			event.Fields["duration"] = duration.String()
+			event.Details = fmt.Sprintf("IP %s[%s] 已被 fail2ban 解封（jail: %s，封禁 %s）", ip, event.Location, jail, duration)
+		}
+		if ok {
+			event.Fields["bantime"] = b.bantime
+			event.Fields["ban_count"] = b.banCount
+		}
+	}
+
+	return event
+}
@@ -0,0 +1,166 @@
+package monitors
+
+import (
+	"reflect"
+	"sync/atomic"
+	"testing"
+)
+
+// withSSHDParsing 测试期间设置是否在直接解析 sshd 登录失败
+func withSSHDParsing(t *testing.T, active bool) {
+	old := atomic.LoadInt32(&sshdFailuresActive)
+	var v int32
+	if active {
+		v = 1
+	}
+	atomic.StoreInt32(&sshdFailuresActive, v)
+	t.Cleanup(func() { atomic.StoreInt32(&sshdFailuresActive, old) })
+}
+
+func TestFail2banEvents(t *testing.T) {
+	tests := []struct {
+		name  string
+		sshd  bool // 是否在直接解析 sshd 日志
+		lines []string
+		want  []string // "事件类型 详细信息"
+	}{
+		{
+			name: "失败后封禁",
+			lines: []string{
+				"2024-01-01 10:00:00,123 fail2ban.filter         [1234]: INFO    [nginx-http-auth] Found 203.0.113.7 - 2024-01-01 10:00:00",
+				"2024-01-01 10:00:03,123 fail2ban.filter         [1234]: INFO    [nginx-http-auth] Found 203.0.113.7 - 2024-01-01 10:00:03",
+				"2024-01-01 10:00:03,456 fail2ban.actions        [1234]: NOTICE  [nginx-http-auth] Ban 203.0.113.7",
+			},
+			want: []string{
+				"fail jail nginx-http-auth 检测到来自 IP 203.0.113.7[测试位置] 的失败登录尝试（第 1 次）",
+				"fail jail nginx-http-auth 检测到来自 IP 203.0.113.7[测试位置] 的失败登录尝试（第 2 次）",
+				"ban IP 203.0.113.7[测试位置] 失败 2 次后已被 fail2ban 封禁（jail: nginx-http-auth）",
+			},
+		},
+		{
+			name: "直接解析 sshd 日志时 sshd jail 的 Found 只计数",
+			sshd: true,
+			lines: []string{
+				"2024-01-01 10:00:00,123 fail2ban.filter         [1234]: INFO    [sshd] Found 203.0.113.7 - 2024-01-01 10:00:00",
+				"2024-01-01 10:00:01,123 fail2ban.filter         [1234]: INFO    [sshd] Found 203.0.113.7 - 2024-01-01 10:00:01",
+				"2024-01-01 10:00:02,123 fail2ban.filter         [1234]: INFO    [sshd] Found 203.0.113.7 - 2024-01-01 10:00:02",
+				"2024-01-01 10:00:02,456 fail2ban.actions        [1234]: NOTICE  [sshd] Ban 203.0.113.7",
+			},
+			want: []string{
+				"ban IP 203.0.113.7[测试位置] 失败 3 次后已被 fail2ban 封禁（jail: sshd）",
+			},
+		},
+		{
+			name: "封禁与解封",
+			lines: []string{
+				"2024-01-01 10:00:00,456 fail2ban.actions        [1234]: NOTICE  [sshd] Ban 2001:db8::1",
+				"2024-01-01 10:10:00,456 fail2ban.actions        [1234]: NOTICE  [sshd] Unban 2001:db8::1",
+			},
+			want: []string{
+				"ban IP 2001:db8::1[测试位置] 已被 fail2ban 封禁（jail: sshd）",
+				"unban IP 2001:db8::1[测试位置] 已被 fail2ban 解封（jail: sshd，封禁 10m0s）",
+			},
+		},
+		{
+			name: "未跟踪到封禁的解封",
+			lines: []string{
+				"2024-01-01 10:10:00,456 fail2ban.actions        [1234]: NOTICE  [recidive] Unban 203.0.113.7",
+			},
+			want: []string{
+				"unban IP 203.0.113.7[测试位置] 已被 fail2ban 解封（jail: recidive）",
+			},
+		},
+		{
+			name: "恢复封禁",
+			lines: []string{
+				"2024-01-01 09:00:00,000 fail2ban.actions        [1234]: NOTICE  [sshd] Restore Ban 203.0.113.7",
+			},
+			want: []string{
+				"ban fail2ban 重启后恢复对 IP 203.0.113.7[测试位置] 的封禁（jail: sshd）",
+			},
+		},
+		{
+			name: "递增封禁只更新状态",
+			lines: []string{
+				"2024-01-01 10:00:00,400 fail2ban.observer       [1234]: NOTICE  [sshd] Increase Ban 203.0.113.7 (3 # 2:00:00 -> 2024-01-01 12:00:00)",
+			},
+		},
+		{
+			name: "无关日志",
+			lines: []string{
+				"2024-01-01 10:00:00,000 fail2ban.server         [1234]: INFO    Starting Fail2ban v0.11.2",
+				"2024-01-01 10:00:00,100 fail2ban.jail           [1234]: INFO    Jail 'sshd' started",
+				"2024-01-01 10:00:00,200 fail2ban.filter         [1234]: INFO    [sshd] Added logfile: '/var/log/auth.log' (pos = 0, hash = abc)",
+				"2024-01-01 10:00:00,300 fail2ban.actions        [1234]: NOTICE  [sshd] 203.0.113.7 already banned",
+				"2024-01-01 10:00:00,400 fail2ban.actions        [1234]: NOTICE  [sshd] Flush ticket(s) with iptables-multiport",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withSSHDParsing(t, tt.sshd)
+			tm := newTestMonitor(t, LogTypeFail2ban)
+
+			var got []string
+			for _, e := range tm.feed(tt.lines...) {
+				got = append(got, string(e.Type)+" "+e.Details)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFail2banFields(t *testing.T) {
+	withSSHDParsing(t, false)
+	tm := newTestMonitor(t, LogTypeFail2ban)
+
+	events := tm.feed(
+		"2024-01-01 10:00:00,123 fail2ban.filter         [1234]: INFO    [nginx-limit-req] Found 203.0.113.7 - 2024-01-01 10:00:00",
+		// fail2ban 先记录递增封禁，再记录封禁
+		"2024-01-01 10:00:00,400 fail2ban.observer       [1234]: NOTICE  [nginx-limit-req] Increase Ban 203.0.113.7 (3 # 2:00:00 -> 2024-01-01 12:00:00)",
+		"2024-01-01 10:00:00,456 fail2ban.actions        [1234]: NOTICE  [nginx-limit-req] Ban 203.0.113.7",
+		"2024-01-01 12:00:00,456 fail2ban.actions        [1234]: NOTICE  [nginx-limit-req] Unban 203.0.113.7",
+	)
+	want := []map[string]string{
+		{"jail": "nginx-limit-req", "action": "found", "attempts": "1"},
+		{"jail": "nginx-limit-req", "action": "ban", "attempts": "1", "bantime": "2:00:00", "ban_count": "3"},
+		{"jail": "nginx-limit-req", "action": "unban", "duration": "2h0m0s", "bantime": "2:00:00", "ban_count": "3"},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("产生了 %d 个事件, want %d", len(events), len(want))
+	}
+	for i, e := range events {
+		if e.IP != "203.0.113.7" || !reflect.DeepEqual(e.Fields, want[i]) {
+			t.Errorf("events[%d] = %s %v, want 203.0.113.7 %v", i, e.IP, e.Fields, want[i])
+		}
+	}
+}
+
+func TestFail2banJailsCountedSeparately(t *testing.T) {
+	withSSHDParsing(t, false)
+	tm := newTestMonitor(t, LogTypeFail2ban)
+
+	events := tm.feed(
+		"2024-01-01 10:00:00,123 fail2ban.filter         [1234]: INFO    [sshd] Found 203.0.113.7 - 2024-01-01 10:00:00",
+		"2024-01-01 10:00:01,123 fail2ban.filter         [1234]: INFO    [sshd] Found 203.0.113.7 - 2024-01-01 10:00:01",
+		"2024-01-01 10:00:02,123 fail2ban.filter         [1234]: INFO    [nginx-http-auth] Found 203.0.113.7 - 2024-01-01 10:00:02",
+		"2024-01-01 10:00:03,123 fail2ban.filter         [1234]: INFO    [sshd] Found 203.0.113.8 - 2024-01-01 10:00:03",
+		"2024-01-01 10:00:04,456 fail2ban.actions        [1234]: NOTICE  [sshd] Ban 203.0.113.7",
+	)
+	var attempts []string
+	for _, e := range events {
+		attempts = append(attempts, e.Fields["jail"]+" "+e.IP+" "+e.Fields["attempts"])
+	}
+	want := []string{
+		"sshd 203.0.113.7 1",
+		"sshd 203.0.113.7 2",
+		"nginx-http-auth 203.0.113.7 1",
+		"sshd 203.0.113.8 1",
+		"sshd 203.0.113.7 2",
+	}
+	if !reflect.DeepEqual(attempts, want) {
+		t.Errorf("attempts = %q, want %q", attempts, want)
+	}
+}
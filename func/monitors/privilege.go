@@ -0,0 +1,187 @@
+package monitors
+
+import (
+	"fmt"
+	"loginfopush/config"
+	"loginfopush/logger"
+	"regexp"
+	"strings"
+)
+
+var (
+	// sudoPattern 匹配 "sudo:    alice : TTY=pts/0 ; PWD=/home/alice ; USER=root ; COMMAND=/usr/bin/apt update"
+	sudoPattern = regexp.MustCompile(`\bsudo(?:\[\d+\])?:\s+(\S+) : (.*)$`)
+	// suSessionPattern 匹配 "su: pam_unix(su-l:session): session opened for user root(uid=0) by alice(uid=1000)"
+	suSessionPattern = regexp.MustCompile(`\bsu(?:\[\d+\])?: pam_unix\(su(?:-l)?:session\): session opened for user ([^\s(]+)(?:\(uid=\d+\))? by ([^\s(]*)(?:\(uid=(\d+)\))?`)
+	// suFailurePattern 匹配 "su: pam_unix(su:auth): authentication failure; logname=alice uid=1000 euid=0 tty=/dev/pts/0 ruser=alice rhost=  user=root"
+	suFailurePattern = regexp.MustCompile(`\bsu(?:\[\d+\])?: pam_unix\(su(?:-l)?:auth\): authentication failure;(.*)$`)
+)
+
+// 提权结果
+const (
+	privilegeSuccess = "success"
+	privilegeFailure = "failure"
+)
+
+// allowRule 编译后的 sudo 命令白名单
+type allowRule struct {
+	user    string
+	command *regexp.Regexp
+}
+
+// compileAllowRules 编译 sudo 事件配置中的命令白名单
+func compileAllowRules() []allowRule {
+	if config.GlobalConfig == nil {
+		return nil
+	}
+	evt, ok := config.GlobalConfig.FindEvent(config.EventTypeSudo)
+	if !ok {
+		return nil
+	}
+
+	var allow []allowRule
+	for _, a := range evt.AllowCommands {
+		re, err := regexp.Compile(a.Command)
+		if err != nil {
+			logger.Warnf("sudo 命令白名单 %q 无效: %v", a.Command, err)
+			continue
+		}
+		allow = append(allow, allowRule{user: a.User, command: re})
+	}
+	return allow
+}
+
+// allowed 判断 sudo 命令是否命中白名单
+func (m *LogMonitor) allowed(user, command string) bool {
+	for _, a := range m.allow {
+		if (a.user == "" || a.user == user) && a.command.MatchString(command) {
+			return true
+		}
+	}
+	return false
+}
+
+// processPrivilege 处理 sudo 与 su 日志，返回的 handled 表示该行已被识别
+func (m *LogMonitor) processPrivilege(line string) (event *Event, handled bool) {
+	if !strings.Contains(line, "COMMAND=") && !strings.Contains(line, "pam_unix(su") {
+		return nil, false
+	}
+	text := strings.TrimRight(line, "\r\n")
+
+	if match := sudoPattern.FindStringSubmatch(text); match != nil && strings.Contains(match[2], "COMMAND=") {
+		return m.sudoEvent(text, match[1], match[2]), true
+	}
+	if match := suSessionPattern.FindStringSubmatch(text); match != nil {
+		user := match[2]
+		if user == "" && match[3] != "" {
+			// 由系统服务等无登录名的进程执行时只有 uid
+			user = "uid=" + match[3]
+			if match[3] == "0" {
+				user = "root"
+			}
+		}
+		return suEvent(text, user, match[1], "", privilegeSuccess), true
+	}
+	if match := suFailurePattern.FindStringSubmatch(text); match != nil {
+		attrs := parseAttrs(match[1])
+		user := attrs["ruser"]
+		if user == "" {
+			user = attrs["logname"]
+		}
+		return suEvent(text, user, attrs["user"], attrs["tty"], privilegeFailure), true
+	}
+	return nil, false
+}
+
+// sudoEvent 解析 sudo 日志的各段，COMMAND 之后的内容全部视为命令
+func (m *LogMonitor) sudoEvent(line, user, rest string) *Event {
+	idx := strings.Index(rest, "COMMAND=")
+	command := strings.TrimSpace(rest[idx+len("COMMAND="):])
+
+	fields := map[string]string{
+		"user":    user,
+		"command": command,
+		"result":  privilegeSuccess,
+	}
+	var reasons []string
+	for _, part := range strings.Split(rest[:idx], " ; ") {
+		part = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(part), ";"))
+		if part == "" {
+			continue
+		}
+		if kv := strings.SplitN(part, "=", 2); len(kv) == 2 && kv[0] == strings.ToUpper(kv[0]) {
+			switch kv[0] {
+			case "TTY":
+				fields["tty"] = kv[1]
+			case "PWD":
+				fields["pwd"] = kv[1]
+			case "USER":
+				fields["target_user"] = kv[1]
+			}
+			continue
+		}
+		// 不是 KEY=VALUE 形式的段为失败原因，如 "3 incorrect password attempts"、"user NOT in sudoers"
+		reasons = append(reasons, part)
+	}
+	if fields["target_user"] == "" {
+		fields["target_user"] = "root"
+	}
+
+	event := &Event{
+		Type:   config.EventTypeSudo,
+		User:   user,
+		Raw:    line,
+		Fields: fields,
+	}
+	if len(reasons) > 0 {
+		fields["result"] = privilegeFailure
+		fields["reason"] = strings.Join(reasons, "; ")
+		event.Details = fmt.Sprintf("用户 %s 以 %s 身份执行 %s 失败: %s", user, fields["target_user"], command, fields["reason"])
+	} else {
+		if m.allowed(user, command) {
+			return nil
+		}
+		event.Details = fmt.Sprintf("用户 %s 以 %s 身份执行: %s", user, fields["target_user"], command)
+	}
+
+	if !isEventEnabled(config.EventTypeSudo) {
+		return nil
+	}
+	return event
+}
+
+// suEvent 创建 su 事件
+func suEvent(line, user, target, tty, result string) *Event {
+	if !isEventEnabled(config.EventTypeSu) {
+		return nil
+	}
+
+	event := &Event{
+		Type: config.EventTypeSu,
+		User: user,
+		Raw:  line,
+		Fields: map[string]string{
+			"user":        user,
+			"target_user": target,
+			"tty":         strings.TrimPrefix(tty, "/dev/"),
+			"result":      result,
+		},
+	}
+	if result == privilegeFailure {
+		event.Details = fmt.Sprintf("用户 %s 切换到 %s 失败", user, target)
+	} else {
+		event.Details = fmt.Sprintf("用户 %s 切换到 %s", user, target)
+	}
+	return event
+}
+
+// parseAttrs 解析 pam 日志中的 "key=value" 属性，值可以为空
+func parseAttrs(s string) map[string]string {
+	attrs := make(map[string]string)
+	for _, field := range strings.Fields(s) {
+		if kv := strings.SplitN(field, "=", 2); len(kv) == 2 {
+			attrs[kv[0]] = kv[1]
+		}
+	}
+	return attrs
+}
@@ -0,0 +1,144 @@
+package monitors
+
+import (
+	"loginfopush/config"
+	"reflect"
+	"regexp"
+	"testing"
+)
+
+func TestPrivilegeEvents(t *testing.T) {
+	tests := []struct {
+		line    string
+		typ     config.EventType // 为空表示不产生事件
+		fields  map[string]string
+		details string
+	}{
+		{
+			line: "Jan  1 10:00:00 web sudo:    alice : TTY=pts/0 ; PWD=/home/alice ; USER=root ; COMMAND=/usr/bin/apt update",
+			typ:  config.EventTypeSudo,
+			fields: map[string]string{
+				"user": "alice", "command": "/usr/bin/apt update", "result": "success",
+				"tty": "pts/0", "pwd": "/home/alice", "target_user": "root",
+			},
+			details: "用户 alice 以 root 身份执行: /usr/bin/apt update",
+		},
+		{
+			// 较新的 sudo 记录 PID，命令中的 " ; " 属于命令本身
+			line: "Jan  1 10:00:00 web sudo[4321]:    alice : TTY=pts/0 ; PWD=/srv ; USER=postgres ; COMMAND=/bin/sh -c echo a ; echo b",
+			typ:  config.EventTypeSudo,
+			fields: map[string]string{
+				"user": "alice", "command": "/bin/sh -c echo a ; echo b", "result": "success",
+				"tty": "pts/0", "pwd": "/srv", "target_user": "postgres",
+			},
+			details: "用户 alice 以 postgres 身份执行: /bin/sh -c echo a ; echo b",
+		},
+		{
+			line: "Jan  1 10:00:00 web sudo:      bob : 3 incorrect password attempts ; TTY=pts/1 ; PWD=/home/bob ; USER=root ; COMMAND=/bin/bash",
+			typ:  config.EventTypeSudo,
+			fields: map[string]string{
+				"user": "bob", "command": "/bin/bash", "result": "failure", "reason": "3 incorrect password attempts",
+				"tty": "pts/1", "pwd": "/home/bob", "target_user": "root",
+			},
+			details: "用户 bob 以 root 身份执行 /bin/bash 失败: 3 incorrect password attempts",
+		},
+		{
+			line: "Jan  1 10:00:00 web sudo:      eve : user NOT in sudoers ; TTY=pts/2 ; PWD=/home/eve ; USER=root ; COMMAND=/bin/cat /etc/shadow",
+			typ:  config.EventTypeSudo,
+			fields: map[string]string{
+				"user": "eve", "command": "/bin/cat /etc/shadow", "result": "failure", "reason": "user NOT in sudoers",
+				"tty": "pts/2", "pwd": "/home/eve", "target_user": "root",
+			},
+			details: "用户 eve 以 root 身份执行 /bin/cat /etc/shadow 失败: user NOT in sudoers",
+		},
+		{
+			line: "Jan  1 10:00:00 web su: pam_unix(su-l:session): session opened for user root(uid=0) by alice(uid=1000)",
+			typ:  config.EventTypeSu,
+			fields: map[string]string{
+				"user": "alice", "target_user": "root", "tty": "", "result": "success",
+			},
+			details: "用户 alice 切换到 root",
+		},
+		{
+			// 由系统服务执行时没有登录名
+			line: "Jan  1 10:00:00 web su[4321]: pam_unix(su:session): session opened for user postgres(uid=113) by (uid=0)",
+			typ:  config.EventTypeSu,
+			fields: map[string]string{
+				"user": "root", "target_user": "postgres", "tty": "", "result": "success",
+			},
+			details: "用户 root 切换到 postgres",
+		},
+		{
+			line: "Jan  1 10:00:00 web su: pam_unix(su:auth): authentication failure; logname=alice uid=1000 euid=0 tty=/dev/pts/0 ruser=alice rhost=  user=root",
+			typ:  config.EventTypeSu,
+			fields: map[string]string{
+				"user": "alice", "target_user": "root", "tty": "pts/0", "result": "failure",
+			},
+			details: "用户 alice 切换到 root 失败",
+		},
+		{
+			line: "Jan  1 10:00:00 web sudo: pam_unix(sudo:session): session opened for user root(uid=0) by alice(uid=1000)",
+		},
+		{
+			line: "Jan  1 10:00:00 web su: pam_unix(su:session): session closed for user root",
+		},
+	}
+	for _, tt := range tests {
+		tm := newTestMonitor(t, LogTypeAuth)
+		events := tm.feed(tt.line)
+		if tt.typ == "" {
+			if len(events) != 0 {
+				t.Errorf("processLine(%s) = %+v, want 无事件", tt.line, events)
+			}
+			continue
+		}
+		if len(events) != 1 {
+			t.Errorf("processLine(%s) 产生了 %d 个事件, want 1", tt.line, len(events))
+			continue
+		}
+		e := events[0]
+		if e.Type != tt.typ || e.User != tt.fields["user"] || e.Details != tt.details {
+			t.Errorf("processLine(%s) = %s %s %q, want %s %s %q", tt.line, e.Type, e.User, e.Details, tt.typ, tt.fields["user"], tt.details)
+		}
+		if !reflect.DeepEqual(e.Fields, tt.fields) {
+			t.Errorf("processLine(%s) fields = %v, want %v", tt.line, e.Fields, tt.fields)
+		}
+	}
+}
+
+func TestSudoAllowCommands(t *testing.T) {
+	tm := newTestMonitor(t, LogTypeAuth)
+	tm.allow = []allowRule{
+		{user: "deploy", command: regexp.MustCompile(`^/usr/bin/systemctl restart app$`)},
+		{command: regexp.MustCompile(`^/usr/bin/apt `)},
+	}
+
+	tests := []struct {
+		line string
+		want bool
+	}{
+		{"Jan  1 10:00:00 web sudo:   deploy : TTY=pts/0 ; PWD=/srv ; USER=root ; COMMAND=/usr/bin/systemctl restart app", false},
+		{"Jan  1 10:00:00 web sudo:    alice : TTY=pts/0 ; PWD=/srv ; USER=root ; COMMAND=/usr/bin/systemctl restart app", true},
+		{"Jan  1 10:00:00 web sudo:    alice : TTY=pts/0 ; PWD=/srv ; USER=root ; COMMAND=/usr/bin/apt update", false},
+		// 白名单只忽略成功执行的命令
+		{"Jan  1 10:00:00 web sudo:    alice : 1 incorrect password attempt ; TTY=pts/0 ; PWD=/srv ; USER=root ; COMMAND=/usr/bin/apt update", true},
+	}
+	for _, tt := range tests {
+		if got := len(tm.feed(tt.line)) == 1; got != tt.want {
+			t.Errorf("processLine(%s) 产生事件 = %v, want %v", tt.line, got, tt.want)
+		}
+	}
+}
+
+func TestPrivilegeDisabled(t *testing.T) {
+	withConfig(t, config.EventTypeSu)
+	tm := newTestMonitor(t, LogTypeAuth)
+
+	events := tm.feed(
+		"Jan  1 10:00:00 web sudo:    alice : TTY=pts/0 ; PWD=/home/alice ; USER=root ; COMMAND=/usr/bin/id",
+		"Jan  1 10:00:01 web su: pam_unix(su-l:session): session opened for user root(uid=0) by alice(uid=1000)",
+	)
+	if len(events) != 1 || events[0].Type != config.EventTypeSu {
+		t.Errorf("events = %+v, want 只有 su 事件", events)
+	}
+}
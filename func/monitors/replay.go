@@ -0,0 +1,150 @@
+package monitors
+
+import (
+	"bufio"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"loginfopush/config"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// rotatedSuffix 轮转产生的文件名后缀，如 auth.log.1、auth.log.2.gz、secure-20240101
+var rotatedSuffix = regexp.MustCompile(`(?:\.\d+|-\d{8})?(?:\.gz)?$`)
+
+// ReplayConfig 确定回放文件使用的日志配置，logType 为空时按文件名判断:
+// 与自定义事件的日志来源同名时按自定义事件解析，文件名包含 fail2ban 时按 fail2ban 日志解析，其余按认证日志解析
+func ReplayConfig(cfg *config.Config, path string, logType LogType) (LogConfig, error) {
+	base := rotatedSuffix.ReplaceAllString(filepath.Base(path), "")
+
+	if logType == "" || logType == LogTypeCustom {
+		customConfigs, err := CustomLogConfigs(cfg)
+		if err != nil {
+			return LogConfig{}, err
+		}
+		var custom LogConfig
+		for _, c := range customConfigs {
+			if logType == LogTypeCustom || filepath.Base(c.Path) == base {
+				custom.Custom = append(custom.Custom, c.Custom...)
+			}
+		}
+		if len(custom.Custom) > 0 {
+			custom.Type = LogTypeCustom
+			custom.Path = path
+			return custom, nil
+		}
+		if logType == LogTypeCustom {
+			return LogConfig{}, fmt.Errorf("没有已启用的自定义事件")
+		}
+	}
+
+	if logType == "" {
+		logType = LogTypeAuth
+		if strings.Contains(base, "fail2ban") {
+			logType = LogTypeFail2ban
+		}
+	}
+	for _, c := range LogConfigs {
+		if c.Type == logType {
+			c.Path = path
+			return c, nil
+		}
+	}
+	return LogConfig{}, fmt.Errorf("不支持的日志类型: %s", logType)
+}
+
+// Replay 从头读取日志文件并逐行解析，以日志中的时间作为当前时间，handle 按顺序收到产生的事件
+//
+// 与实时监控不同，回放不跟踪文件增长，读到文件末尾后结束；.gz 文件会自动解压。
+// geolocate 为 false 时不查询 IP 位置，事件的位置为 "未查询"。
+func Replay(logConfig LogConfig, geolocate bool, handle func(Event)) error {
+	file, err := os.Open(logConfig.Path)
+	if err != nil {
+		return fmt.Errorf("打开日志文件失败: %v", err)
+	}
+	defer file.Close()
+
+	var r io.Reader = file
+	if strings.HasSuffix(logConfig.Path, ".gz") {
+		gz, err := gzip.NewReader(file)
+		if err != nil {
+			return fmt.Errorf("解压日志文件失败: %v", err)
+		}
+		defer gz.Close()
+		r = gz
+	}
+
+	if logConfig.Type == LogTypeAuth {
+		atomic.StoreInt32(&sshdFailuresActive, 1)
+	}
+
+	// 没有时间的行沿用上一行的时间
+	current := time.Now()
+	m := &LogMonitor{
+		config:   logConfig,
+		path:     logConfig.Path,
+		sessions: trackerFor(logConfig.Path),
+		allow:    compileAllowRules(),
+		failures: newFailureTracker(),
+		fail2ban: fail2banTrackerFor(logConfig.Path),
+		clock:    func() time.Time { return current },
+	}
+	if geolocate {
+		m.locate = getIPLocation
+	}
+
+	// 事件在同一个协程中按顺序处理
+	eventChan := make(chan Event)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for event := range eventChan {
+			handle(event)
+		}
+	}()
+
+	reader := bufio.NewReader(r)
+	first := true
+	for {
+		line, err := reader.ReadString('\n')
+		if line != "" {
+			if t, ok := ParseTimestamp(line, time.Now()); ok {
+				if first {
+					// 以第一行的时间开始检查长会话
+					m.lastSessionCheck = t
+				}
+				current = t
+				first = false
+			}
+			if event := m.processLine(line); event != nil {
+				m.emit(eventChan, *event)
+			}
+			if logConfig.Type == LogTypeAuth {
+				m.checkLongSessions(eventChan)
+				m.checkPendingFailures(eventChan)
+			}
+		}
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			close(eventChan)
+			<-done
+			return fmt.Errorf("读取日志失败: %v", err)
+		}
+	}
+
+	// 日志结束后仍在等待主要日志的次要失败事件
+	if logConfig.Type == LogTypeAuth {
+		current = current.Add(pendingFailureTimeout)
+		m.checkPendingFailures(eventChan)
+	}
+	close(eventChan)
+	<-done
+	return nil
+}
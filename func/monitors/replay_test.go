@@ -0,0 +1,29 @@
+package monitors
+
+import (
+	"io/ioutil"
+	"loginfopush/config"
+	"path/filepath"
+	"testing"
+)
+
+func TestReplaySkipsGeolocation(t *testing.T) {
+	withConfig(t, config.EventTypeFailure)
+	path := filepath.Join(t.TempDir(), "auth.log")
+	log := "2024-01-01T10:00:00+00:00 host sshd[100]: Failed password for root from 203.0.113.7 port 22 ssh2\n"
+	if err := ioutil.WriteFile(path, []byte(log), 0644); err != nil {
+		t.Fatal(err)
+	}
+	logConfig, err := ReplayConfig(config.GlobalConfig, path, LogTypeAuth)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var events []Event
+	if err := Replay(logConfig, false, func(e Event) { events = append(events, e) }); err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 1 || events[0].IP != "203.0.113.7" || events[0].Location != skippedLocation.location {
+		t.Errorf("Replay() = %+v, want 一个未查询位置的失败事件", events)
+	}
+}
@@ -0,0 +1,308 @@
+package monitors
+
+import (
+	"fmt"
+	"loginfopush/config"
+	"regexp"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// defaultLongSessionThreshold 长会话告警的默认阈值
+const defaultLongSessionThreshold = 4 * time.Hour
+
+// sessionCheckInterval 检查长会话的间隔
+const sessionCheckInterval = 30 * time.Second
+
+// endedSessionTTL 已结束会话的记录保留时间，用于忽略同一会话的后续断开日志
+const endedSessionTTL = time.Minute
+
+// staleSessionTTL 会话的最长跟踪时间，超过后视为错过了登出日志（如日志丢失、系统重启）而丢弃
+const staleSessionTTL = 7 * 24 * time.Hour
+
+var (
+	// sshdPIDPattern 匹配 "sshd[1234]:"，OpenSSH 9.8 之后为 "sshd-session[1234]:"
+	sshdPIDPattern = regexp.MustCompile(`sshd(?:-session)?\[(\d+)\]`)
+	// disconnectPattern 匹配 "Disconnected from user root 1.2.3.4 port 22"
+	disconnectPattern = regexp.MustCompile(`Disconnected from user (\S+) (\S+) port (\d+)`)
+	// acceptedPortPattern 匹配 "Accepted publickey for root from 1.2.3.4 port 22" 中的端口
+	acceptedPortPattern = regexp.MustCompile(` from \S+ port (\d+)`)
+)
+
+// sshSession 一个已登录的 SSH 会话
+type sshSession struct {
+	pid      string
+	user     string
+	ip       string
+	port     string
+	location string
+	country  string
+	asn      string
+	start    time.Time
+	alerted  bool // 是否已发送长会话告警
+}
+
+// sessionTracker 按 sshd PID 跟踪会话
+//
+// 特权分离时登录与 "session closed" 日志来自监控进程，"Disconnected from user" 日志来自子进程，
+// 两者 PID 不同，断开日志按用户、IP 与端口关联到会话。
+type sessionTracker struct {
+	mu       sync.Mutex
+	sessions map[string]*sshSession
+	ended    map[string]time.Time // 最近结束的会话 PID 与连接（见 connKey）及结束时间
+}
+
+// 按日志路径保存的会话状态，定时重启监控器后仍然保留
+var (
+	sessionTrackers = make(map[string]*sessionTracker)
+	sessionMutex    sync.Mutex
+)
+
+// trackerFor 获取日志文件对应的会话状态
+func trackerFor(path string) *sessionTracker {
+	sessionMutex.Lock()
+	defer sessionMutex.Unlock()
+
+	t, ok := sessionTrackers[path]
+	if !ok {
+		t = &sessionTracker{
+			sessions: make(map[string]*sshSession),
+			ended:    make(map[string]time.Time),
+		}
+		sessionTrackers[path] = t
+	}
+	return t
+}
+
+// sshdPID 从日志行中提取 sshd 进程号
+func sshdPID(line string) string {
+	if match := sshdPIDPattern.FindStringSubmatch(line); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// sshdPort 从登录成功日志中提取客户端端口
+func sshdPort(line string) string {
+	if match := acceptedPortPattern.FindStringSubmatch(line); match != nil {
+		return match[1]
+	}
+	return ""
+}
+
+// connKey 标识一个 SSH 连接
+func connKey(user, ip, port string) string {
+	return user + " " + ip + " " + port
+}
+
+// open 记录登录成功的会话
+func (t *sessionTracker) open(pid, port string, event *Event, now time.Time) {
+	if pid == "" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.prune(now)
+	t.sessions[pid] = &sshSession{
+		pid:      pid,
+		user:     event.User,
+		ip:       event.IP,
+		port:     port,
+		location: event.Location,
+		country:  event.Country,
+		asn:      event.ASN,
+		start:    now,
+	}
+}
+
+// prune 清理过期的结束记录与长期未结束的会话，调用时需持有锁
+func (t *sessionTracker) prune(now time.Time) {
+	for k, at := range t.ended {
+		if now.Sub(at) >= endedSessionTTL {
+			delete(t.ended, k)
+		}
+	}
+	for pid, s := range t.sessions {
+		if now.Sub(s.start) >= staleSessionTTL {
+			delete(t.sessions, pid)
+		}
+	}
+}
+
+// end 结束会话并记录其 PID 与连接，调用时需持有锁
+func (t *sessionTracker) end(s *sshSession, now time.Time) {
+	delete(t.sessions, s.pid)
+	t.ended[s.pid] = now
+	t.ended[connKey(s.user, s.ip, s.port)] = now
+}
+
+// close 按 PID 结束会话，返回登录时记录的会话；ended 表示该会话刚刚已经结束过
+func (t *sessionTracker) close(pid string, now time.Time) (s *sshSession, ok bool, ended bool) {
+	if pid == "" {
+		return nil, false, false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.prune(now)
+	if _, ended := t.ended[pid]; ended {
+		return nil, false, true
+	}
+
+	s, ok = t.sessions[pid]
+	if ok {
+		t.end(s, now)
+	}
+	return s, ok, false
+}
+
+// disconnect 处理断开日志：先按 PID 查找会话，找不到时按用户、IP 与端口查找；
+// 未跟踪到登录时记录该连接已经结束，忽略之后同一会话的 "session closed" 日志
+func (t *sessionTracker) disconnect(pid, user, ip, port string, now time.Time) (s *sshSession, ok bool, ended bool) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.prune(now)
+	key := connKey(user, ip, port)
+	if _, ended := t.ended[key]; ended {
+		return nil, false, true
+	}
+	if pid != "" {
+		if _, ended := t.ended[pid]; ended {
+			return nil, false, true
+		}
+	}
+
+	s, ok = t.sessions[pid]
+	if !ok {
+		for _, c := range t.sessions {
+			if c.user == user && c.ip == ip && (c.port == port || c.port == "") {
+				s, ok = c, true
+				break
+			}
+		}
+	}
+	if ok {
+		t.end(s, now)
+	} else {
+		t.ended[key] = now
+	}
+	if pid != "" {
+		t.ended[pid] = now
+	}
+	return s, ok, false
+}
+
+// longSessions 返回超过阈值且尚未告警的会话，并标记为已告警
+func (t *sessionTracker) longSessions(threshold time.Duration, now time.Time) []sshSession {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var long []sshSession
+	for _, s := range t.sessions {
+		if !s.alerted && now.Sub(s.start) >= threshold {
+			s.alerted = true
+			long = append(long, *s)
+		}
+	}
+	return long
+}
+
+// fill 用会话信息填充事件
+func (s *sshSession) fill(event *Event, now time.Time) {
+	duration := now.Sub(s.start).Round(time.Second)
+	event.User = s.user
+	event.IP = s.ip
+	event.Location = s.location
+	event.Country = s.country
+	event.ASN = s.asn
+	event.Fields = map[string]string{
+		"pid":              s.pid,
+		"login_time":       s.start.Format("2006-01-02 15:04:05"),
+		"duration":         duration.String(),
+		"duration_seconds": strconv.FormatInt(int64(duration/time.Second), 10),
+	}
+}
+
+// processSessionEnd 处理 "session closed" 与 "Disconnected from user" 日志，
+// 两者对应同一会话，只在第一次出现时产生 logout 事件
+func (m *LogMonitor) processSessionEnd(line string, event *Event) *Event {
+	now := m.now()
+	pid := sshdPID(line)
+	match := disconnectPattern.FindStringSubmatch(line)
+
+	var (
+		s         *sshSession
+		ok, ended bool
+	)
+	if match != nil {
+		s, ok, ended = m.sessions.disconnect(pid, match[1], match[2], match[3], now)
+	} else {
+		s, ok, ended = m.sessions.close(pid, now)
+	}
+	if ended {
+		return nil
+	}
+	if ok {
+		s.fill(event, now)
+	} else {
+		// 未跟踪到对应的登录（如监控启动前已登录），只能从 Disconnected 日志获取用户与 IP
+		if match == nil {
+			return nil
+		}
+		event.User = match[1]
+		event.IP = match[2]
+		event.Fields = map[string]string{"pid": pid}
+	}
+
+	if !isEventEnabled(config.EventTypeLogout) {
+		return nil
+	}
+	event.Type = config.EventTypeLogout
+	m.fillSessionLocation(event)
+	if ok {
+		event.Details = fmt.Sprintf("用户 %s 从 IP %s[%s] 登出，会话时长 %s",
+			event.User, event.IP, event.Location, event.Fields["duration"])
+	} else {
+		event.Details = fmt.Sprintf("用户 %s 从 IP %s[%s] 登出，会话时长未知", event.User, event.IP, event.Location)
+	}
+	return event
+}
+
+// fillSessionLocation 登录时未查询 IP 位置（如未启用 success 事件）的会话在发送事件前查询
+func (m *LogMonitor) fillSessionLocation(event *Event) {
+	if event.Location == "" {
+		m.fillLocation(event)
+	}
+}
+
+// checkLongSessions 对持续时间超过阈值的会话产生 long_session 事件，每个会话只告警一次
+func (m *LogMonitor) checkLongSessions(eventChan chan<- Event) {
+	now := m.now()
+	if now.Sub(m.lastSessionCheck) < sessionCheckInterval {
+		return
+	}
+	m.lastSessionCheck = now
+
+	if config.GlobalConfig == nil {
+		return
+	}
+	evt, ok := config.GlobalConfig.FindEvent(config.EventTypeLongSession)
+	if !ok {
+		return
+	}
+	threshold := evt.Threshold.Std()
+	if threshold <= 0 {
+		threshold = defaultLongSessionThreshold
+	}
+
+	for _, s := range m.sessions.longSessions(threshold, now) {
+		event := Event{Type: config.EventTypeLongSession}
+		s.fill(&event, now)
+		m.fillSessionLocation(&event)
+		event.Details = fmt.Sprintf("用户 %s 来自 IP %s[%s] 的会话已持续 %s，超过阈值 %s",
+			event.User, event.IP, event.Location, event.Fields["duration"], threshold)
+		m.emit(eventChan, event)
+	}
+}
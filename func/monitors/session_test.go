@@ -0,0 +1,123 @@
+package monitors
+
+import (
+	"loginfopush/config"
+	"testing"
+	"time"
+)
+
+func TestSessionLogout(t *testing.T) {
+	accepted := []string{
+		"Jan  1 10:00:00 web sshd[2001]: Accepted publickey for alice from 198.51.100.1 port 50000 ssh2: ED25519 SHA256:abc",
+		"Jan  1 10:00:00 web sshd[2001]: pam_unix(sshd:session): session opened for user alice(uid=1000) by (uid=0)",
+	}
+	tests := []struct {
+		name    string
+		login   bool // 是否跟踪到登录
+		lines   []string
+		details string
+	}{
+		{
+			name:  "特权分离的子进程先记录断开",
+			login: true,
+			lines: []string{
+				"Jan  1 10:30:00 web sshd[2050]: Received disconnect from 198.51.100.1 port 50000:11: disconnected by user",
+				"Jan  1 10:30:00 web sshd[2050]: Disconnected from user alice 198.51.100.1 port 50000",
+				"Jan  1 10:30:00 web sshd[2001]: pam_unix(sshd:session): session closed for user alice",
+			},
+			details: "用户 alice 从 IP 198.51.100.1[测试位置] 登出，会话时长 30m0s",
+		},
+		{
+			name:  "session closed 先于子进程的断开日志",
+			login: true,
+			lines: []string{
+				"Jan  1 10:30:00 web sshd[2001]: pam_unix(sshd:session): session closed for user alice",
+				"Jan  1 10:30:01 web sshd[2050]: Disconnected from user alice 198.51.100.1 port 50000",
+			},
+			details: "用户 alice 从 IP 198.51.100.1[测试位置] 登出，会话时长 30m0s",
+		},
+		{
+			name:  "同一进程记录断开",
+			login: true,
+			lines: []string{
+				"Jan  1 10:30:00 web sshd-session[2001]: Disconnected from user alice 198.51.100.1 port 50000",
+				"Jan  1 10:30:00 web sshd-session[2001]: pam_unix(sshd:session): session closed for user alice",
+			},
+			details: "用户 alice 从 IP 198.51.100.1[测试位置] 登出，会话时长 30m0s",
+		},
+		{
+			name: "监控启动前已登录",
+			lines: []string{
+				"Jan  1 10:30:00 web sshd[2050]: Disconnected from user alice 198.51.100.1 port 50000",
+				"Jan  1 10:30:00 web sshd[2001]: pam_unix(sshd:session): session closed for user alice",
+			},
+			details: "用户 alice 从 IP 198.51.100.1[测试位置] 登出，会话时长未知",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, config.EventTypeLogout)
+			tm := newTestMonitor(t, LogTypeAuth)
+			if tt.login {
+				tm.feed(accepted...)
+			}
+			events := tm.feed(tt.lines...)
+			if len(events) != 1 {
+				t.Fatalf("产生了 %d 个事件, want 1: %+v", len(events), events)
+			}
+			if e := events[0]; e.Type != config.EventTypeLogout || e.Details != tt.details {
+				t.Errorf("event = %s %q, want logout %q", e.Type, e.Details, tt.details)
+			}
+		})
+	}
+}
+
+func TestSessionConcurrentConnections(t *testing.T) {
+	withConfig(t, config.EventTypeLogout)
+	tm := newTestMonitor(t, LogTypeAuth)
+
+	// 同一用户从同一 IP 建立两个连接，按端口区分
+	events := tm.feed(
+		"Jan  1 10:00:00 web sshd[2001]: Accepted publickey for alice from 198.51.100.1 port 50000 ssh2",
+		"Jan  1 10:10:00 web sshd[2101]: Accepted publickey for alice from 198.51.100.1 port 50001 ssh2",
+		"Jan  1 10:30:00 web sshd[2150]: Disconnected from user alice 198.51.100.1 port 50001",
+		"Jan  1 10:30:00 web sshd[2101]: pam_unix(sshd:session): session closed for user alice",
+		"Jan  1 11:00:00 web sshd[2050]: Disconnected from user alice 198.51.100.1 port 50000",
+		"Jan  1 11:00:00 web sshd[2001]: pam_unix(sshd:session): session closed for user alice",
+	)
+	var durations []string
+	for _, e := range events {
+		durations = append(durations, e.Fields["pid"]+" "+e.Fields["duration"])
+	}
+	if len(durations) != 2 || durations[0] != "2101 20m0s" || durations[1] != "2001 1h0m0s" {
+		t.Errorf("logout = %v, want [2101 20m0s 2001 1h0m0s]", durations)
+	}
+}
+
+func TestSessionExpiry(t *testing.T) {
+	tracker := &sessionTracker{
+		sessions: make(map[string]*sshSession),
+		ended:    make(map[string]time.Time),
+	}
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local)
+	tracker.open("2001", "50000", &Event{User: "alice", IP: "198.51.100.1"}, start)
+	if _, ok, _ := tracker.close("2001", start.Add(time.Hour)); !ok {
+		t.Fatal("close() 没有找到会话")
+	}
+	if len(tracker.ended) != 2 {
+		t.Errorf("ended = %v, want PID 与连接两条记录", tracker.ended)
+	}
+
+	// 结束记录与长期未结束的会话过期后被清理
+	tracker.open("2101", "50001", &Event{User: "bob", IP: "198.51.100.2"}, start.Add(time.Hour))
+	tracker.open("2201", "50002", &Event{User: "carol", IP: "198.51.100.3"}, start.Add(time.Hour+staleSessionTTL))
+	if len(tracker.ended) != 0 {
+		t.Errorf("ended = %v, want 已清理", tracker.ended)
+	}
+	if _, ok := tracker.sessions["2101"]; ok {
+		t.Error("超过 staleSessionTTL 的会话没有被清理")
+	}
+	if _, ok := tracker.sessions["2201"]; !ok {
+		t.Error("新会话被清理")
+	}
+}
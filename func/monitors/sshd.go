@@ -0,0 +1,263 @@
+package monitors
+
+import (
+	"fmt"
+	"loginfopush/config"
+	"regexp"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// pendingFailureTimeout 次要失败日志等待对应主要日志的最长时间
+const pendingFailureTimeout = 10 * time.Second
+
+// reportedFailureTTL 已报告失败的连接记录保留时间
+const reportedFailureTTL = 10 * time.Minute
+
+// sshd 登录失败原因
+const (
+	failureFailed      = "failed"       // Failed password/publickey/... 认证失败
+	failureInvalidUser = "invalid_user" // 用户不存在
+	failurePAM         = "pam"          // pam_unix 认证失败
+	failureMaxAttempts = "max_attempts" // 超过最大认证次数
+	failurePreauth     = "preauth"      // 认证前断开连接
+)
+
+var (
+	// failedPattern 匹配 "Failed password for invalid user admin from 1.2.3.4 port 22 ssh2"
+	failedPattern = regexp.MustCompile(`Failed (\S+) for (invalid user )?(.*?) from (\S+) port (\d+)`)
+	// invalidUserPattern 匹配 "Invalid user admin from 1.2.3.4 port 22"
+	invalidUserPattern = regexp.MustCompile(`Invalid user (.*?) from (\S+) port (\d+)`)
+	// pamFailurePattern 匹配 "pam_unix(sshd:auth): authentication failure; logname= uid=0 euid=0 tty=ssh ruser= rhost=1.2.3.4  user=root"
+	pamFailurePattern = regexp.MustCompile(`pam_unix\(sshd:auth\): authentication failure;(.*)$`)
+	// maxAttemptsPattern 匹配 "maximum authentication attempts exceeded for root from 1.2.3.4 port 22 ssh2 [preauth]"
+	maxAttemptsPattern = regexp.MustCompile(`maximum authentication attempts exceeded for (invalid user )?(.*?) from (\S+) port (\d+)`)
+	// preauthPattern 匹配 "Connection closed by authenticating user root 1.2.3.4 port 22 [preauth]" 等认证前断开的日志
+	preauthPattern = regexp.MustCompile(`(?:Disconnected from|Connection closed by|Connection reset by|Received disconnect from)(?: (?:invalid|authenticating) user (\S+))? (\S+) port (\d+).*\[preauth\]`)
+)
+
+// sshdFailuresActive 是否有认证日志监控在解析 sshd 登录失败，此时 fail2ban sshd jail 的 Found 日志不再重复产生事件
+var sshdFailuresActive int32
+
+// parsingSSHDFailures 判断是否在直接解析 sshd 登录失败
+func parsingSSHDFailures() bool {
+	return atomic.LoadInt32(&sshdFailuresActive) == 1
+}
+
+// pendingFailure 等待对应主要日志的次要失败事件
+type pendingFailure struct {
+	event Event
+	at    time.Time
+}
+
+// failureTracker 按 sshd PID 合并同一次认证产生的多行失败日志
+//
+// "Invalid user"、pam 认证失败等次要日志总是先于 "Failed ..." 主要日志出现，
+// 先暂存，若随后出现主要日志则丢弃；连接断开或超时仍未出现时再作为失败事件发送。
+type failureTracker struct {
+	mu       sync.Mutex
+	pending  map[string]*pendingFailure
+	reported map[string]time.Time // 已报告过失败的连接
+}
+
+// newFailureTracker 创建失败日志合并器
+func newFailureTracker() *failureTracker {
+	return &failureTracker{
+		pending:  make(map[string]*pendingFailure),
+		reported: make(map[string]time.Time),
+	}
+}
+
+// primary 记录主要失败日志，丢弃同一连接暂存的次要日志
+func (t *failureTracker) primary(pid string, now time.Time) {
+	if pid == "" {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	delete(t.pending, pid)
+	t.reported[pid] = now
+}
+
+// secondary 暂存次要失败日志，返回 false 表示无法关联连接，需要立即发送
+func (t *failureTracker) secondary(pid string, event Event, now time.Time) bool {
+	if pid == "" {
+		return false
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if _, ok := t.reported[pid]; ok {
+		// 同一连接已经报告过失败，后续的次要日志不再重复
+		return true
+	}
+	if _, ok := t.pending[pid]; !ok {
+		event.Time = now
+		t.pending[pid] = &pendingFailure{event: event, at: now}
+	}
+	return true
+}
+
+// closed 连接在认证前断开，返回暂存的次要事件；该连接从未报告过失败时 unreported 为 true
+//
+// 一次断开通常会记录多行日志，处理后将连接标记为已报告，后续行不再重复产生事件。
+func (t *failureTracker) closed(pid string, now time.Time) (pending *Event, unreported bool) {
+	if pid == "" {
+		return nil, true
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if p, ok := t.pending[pid]; ok {
+		delete(t.pending, pid)
+		t.reported[pid] = now
+		return &p.event, false
+	}
+	if _, reported := t.reported[pid]; reported {
+		return nil, false
+	}
+	t.reported[pid] = now
+	return nil, true
+}
+
+// expired 返回超时的暂存事件，并清理过期的连接记录
+func (t *failureTracker) expired(now time.Time) []Event {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var events []Event
+	for pid, p := range t.pending {
+		if now.Sub(p.at) >= pendingFailureTimeout {
+			delete(t.pending, pid)
+			t.reported[pid] = p.at
+			events = append(events, p.event)
+		}
+	}
+	for pid, at := range t.reported {
+		if now.Sub(at) >= reportedFailureTTL {
+			delete(t.reported, pid)
+		}
+	}
+	return events
+}
+
+// newFailureEvent 创建 sshd 登录失败事件，invalid 表示用户不存在
+//
+// 此时不查询 IP 位置，位置与详细信息在确定发送时由 failureIfEnabled 填充，
+// 避免为被合并、丢弃或未启用的失败日志发起查询。
+func newFailureEvent(line, user, ip, port, method, reason string, invalid bool) Event {
+	event := Event{
+		Type: config.EventTypeFailure,
+		IP:   ip,
+		User: user,
+		Raw:  line,
+		Fields: map[string]string{
+			"port":   port,
+			"method": method,
+			"reason": reason,
+		},
+	}
+	if invalid {
+		event.Fields["invalid_user"] = "true"
+	}
+	return event
+}
+
+// failureDetails 根据失败原因生成事件详细信息
+func failureDetails(event *Event) string {
+	ip, user, method := event.IP, event.User, event.Fields["method"]
+	invalid := event.Fields["invalid_user"] == "true"
+
+	switch event.Fields["reason"] {
+	case failureFailed:
+		if invalid {
+			return fmt.Sprintf("IP %s[%s] 使用 %s 方式登录不存在的用户 %s 失败", ip, event.Location, method, user)
+		}
+		return fmt.Sprintf("IP %s[%s] 使用 %s 方式登录用户 %s 失败", ip, event.Location, method, user)
+	case failureInvalidUser:
+		return fmt.Sprintf("IP %s[%s] 尝试登录不存在的用户 %s", ip, event.Location, user)
+	case failureMaxAttempts:
+		return fmt.Sprintf("IP %s[%s] 登录用户 %s 超过最大认证次数", ip, event.Location, user)
+	case failurePreauth:
+		if user != "" {
+			return fmt.Sprintf("IP %s[%s] 登录用户 %s 时在认证前断开连接", ip, event.Location, user)
+		}
+		return fmt.Sprintf("IP %s[%s] 在认证前断开连接", ip, event.Location)
+	default:
+		return fmt.Sprintf("IP %s[%s] 登录用户 %s 认证失败", ip, event.Location, user)
+	}
+}
+
+// processSSHDFailure 解析 sshd 登录失败日志，返回的 handled 表示该行已被识别
+func (m *LogMonitor) processSSHDFailure(line string) (event *Event, handled bool) {
+	if !strings.Contains(line, "sshd") {
+		return nil, false
+	}
+	text := strings.TrimRight(line, "\r\n")
+	pid := sshdPID(text)
+	now := m.now()
+
+	if match := failedPattern.FindStringSubmatch(text); match != nil {
+		m.failures.primary(pid, now)
+		e := newFailureEvent(text, match[3], match[4], match[5], match[1], failureFailed, match[2] != "")
+		return m.failureIfEnabled(&e), true
+	}
+
+	if match := maxAttemptsPattern.FindStringSubmatch(text); match != nil {
+		m.failures.primary(pid, now)
+		e := newFailureEvent(text, match[2], match[3], match[4], "", failureMaxAttempts, match[1] != "")
+		return m.failureIfEnabled(&e), true
+	}
+
+	var secondary *Event
+	if match := invalidUserPattern.FindStringSubmatch(text); match != nil {
+		e := newFailureEvent(text, match[1], match[2], match[3], "", failureInvalidUser, true)
+		secondary = &e
+	} else if match := pamFailurePattern.FindStringSubmatch(text); match != nil {
+		attrs := parseAttrs(match[1])
+		if attrs["rhost"] == "" {
+			return nil, true
+		}
+		e := newFailureEvent(text, attrs["user"], attrs["rhost"], "", "password", failurePAM, false)
+		secondary = &e
+	}
+	if secondary != nil {
+		if m.failures.secondary(pid, *secondary, now) {
+			return nil, true
+		}
+		return m.failureIfEnabled(secondary), true
+	}
+
+	if match := preauthPattern.FindStringSubmatch(text); match != nil {
+		pending, unreported := m.failures.closed(pid, now)
+		if pending != nil {
+			return m.failureIfEnabled(pending), true
+		}
+		if !unreported {
+			return nil, true
+		}
+		e := newFailureEvent(text, match[1], match[2], match[3], "", failurePreauth, strings.Contains(text, "invalid user"))
+		return m.failureIfEnabled(&e), true
+	}
+
+	return nil, false
+}
+
+// checkPendingFailures 发送等待超时的次要失败事件
+func (m *LogMonitor) checkPendingFailures(eventChan chan<- Event) {
+	for _, event := range m.failures.expired(m.now()) {
+		if e := m.failureIfEnabled(&event); e != nil {
+			m.emit(eventChan, *e)
+		}
+	}
+}
+
+// failureIfEnabled fail 事件未启用时返回 nil，启用时查询 IP 位置并填充详细信息
+func (m *LogMonitor) failureIfEnabled(event *Event) *Event {
+	if !isEventEnabled(config.EventTypeFailure) {
+		return nil
+	}
+	m.fillLocation(event)
+	event.Details = failureDetails(event)
+	return event
+}
@@ -0,0 +1,320 @@
+package monitors
+
+import (
+	"fmt"
+	"loginfopush/config"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testMonitor 测试使用的监控器，时间取自日志行，IP 位置查询被替换为固定结果
+type testMonitor struct {
+	*LogMonitor
+	at      time.Time
+	lookups []string // 查询过位置的 IP
+}
+
+func newTestMonitor(t *testing.T, logType LogType) *testMonitor {
+	tm := &testMonitor{at: time.Date(2024, 1, 1, 0, 0, 0, 0, time.Local)}
+	// 会话与 fail2ban 状态按路径共享，每个测试使用独立的路径
+	path := t.Name()
+	tm.LogMonitor = &LogMonitor{
+		config:   LogConfig{Type: logType, Path: path, Patterns: authPatterns},
+		path:     path,
+		sessions: trackerFor(path),
+		failures: newFailureTracker(),
+		fail2ban: fail2banTrackerFor(path),
+		clock:    func() time.Time { return tm.at },
+		locate: func(ip string) (ipLocationInfo, error) {
+			tm.lookups = append(tm.lookups, ip)
+			return ipLocationInfo{location: "测试位置", country: "CN"}, nil
+		},
+	}
+	return tm
+}
+
+// feed 按顺序处理日志行，返回产生的事件
+func (tm *testMonitor) feed(lines ...string) []Event {
+	var events []Event
+	for _, line := range lines {
+		if at, ok := ParseTimestamp(line, tm.at); ok {
+			tm.at = at
+		}
+		if event := tm.processLine(line + "\n"); event != nil {
+			events = append(events, *event)
+		}
+	}
+	return events
+}
+
+// pending 将时间推进 d 后返回等待超时的次要失败事件
+func (tm *testMonitor) pending(d time.Duration) []Event {
+	tm.at = tm.at.Add(d)
+	eventChan := make(chan Event, 16)
+	tm.checkPendingFailures(eventChan)
+	close(eventChan)
+
+	var events []Event
+	for event := range eventChan {
+		events = append(events, event)
+	}
+	return events
+}
+
+// withConfig 测试期间使用只启用了指定事件的配置
+func withConfig(t *testing.T, types ...config.EventType) {
+	old := config.GlobalConfig
+	cfg := &config.Config{Events: make(map[string]config.EventConfig)}
+	for _, typ := range types {
+		cfg.Events[string(typ)] = config.EventConfig{Type: typ, Enabled: true}
+	}
+	config.GlobalConfig = cfg
+	t.Cleanup(func() { config.GlobalConfig = old })
+}
+
+// failureSummary 以 "原因 用户 IP 端口" 概括失败事件，用户不存在时追加 invalid
+func failureSummary(events []Event) []string {
+	var got []string
+	for _, e := range events {
+		s := fmt.Sprintf("%s %s %s %s", e.Fields["reason"], e.User, e.IP, e.Fields["port"])
+		if e.Fields["invalid_user"] == "true" {
+			s += " invalid"
+		}
+		got = append(got, s)
+	}
+	return got
+}
+
+func TestSSHDFailures(t *testing.T) {
+	tests := []struct {
+		name  string
+		lines []string
+		want  []string
+	}{
+		{
+			name: "密码错误",
+			lines: []string{
+				"Jan  1 10:00:00 web sshd[1001]: Failed password for root from 203.0.113.7 port 51234 ssh2",
+			},
+			want: []string{"failed root 203.0.113.7 51234"},
+		},
+		{
+			name: "密钥认证失败",
+			lines: []string{
+				"Jan  1 10:00:00 web sshd[1001]: Failed publickey for deploy from 2001:db8::7 port 51234 ssh2: ED25519 SHA256:abc",
+			},
+			want: []string{"failed deploy 2001:db8::7 51234"},
+		},
+		{
+			name: "不存在的用户与主要日志合并",
+			lines: []string{
+				"Jan  1 10:00:00 web sshd[1002]: Invalid user admin from 203.0.113.8 port 40000",
+				"Jan  1 10:00:01 web sshd[1002]: pam_unix(sshd:auth): check pass; user unknown",
+				"Jan  1 10:00:01 web sshd[1002]: pam_unix(sshd:auth): authentication failure; logname= uid=0 euid=0 tty=ssh ruser= rhost=203.0.113.8",
+				"Jan  1 10:00:03 web sshd[1002]: Failed password for invalid user admin from 203.0.113.8 port 40000 ssh2",
+				"Jan  1 10:00:03 web sshd[1002]: Connection closed by invalid user admin 203.0.113.8 port 40000 [preauth]",
+			},
+			want: []string{"failed admin 203.0.113.8 40000 invalid"},
+		},
+		{
+			name: "不存在的用户在认证前断开",
+			lines: []string{
+				"Jan  1 10:00:00 web sshd[1003]: Invalid user test from 203.0.113.9 port 5022",
+				"Jan  1 10:00:00 web sshd[1003]: Connection closed by invalid user test 203.0.113.9 port 5022 [preauth]",
+			},
+			want: []string{"invalid_user test 203.0.113.9 5022 invalid"},
+		},
+		{
+			name: "pam 认证失败与主要日志合并",
+			lines: []string{
+				"Jan  1 10:00:00 web sshd[1004]: pam_unix(sshd:auth): authentication failure; logname= uid=0 euid=0 tty=ssh ruser= rhost=203.0.113.10  user=root",
+				"Jan  1 10:00:02 web sshd[1004]: Failed password for root from 203.0.113.10 port 6000 ssh2",
+			},
+			want: []string{"failed root 203.0.113.10 6000"},
+		},
+		{
+			name: "本地 pam 认证失败",
+			lines: []string{
+				"Jan  1 10:00:00 web sshd[1004]: pam_unix(sshd:auth): authentication failure; logname= uid=0 euid=0 tty=ssh ruser= rhost=  user=root",
+			},
+		},
+		{
+			name: "只有认证前断开",
+			lines: []string{
+				"Jan  1 10:00:00 web sshd[1005]: Connection closed by authenticating user root 203.0.113.11 port 7000 [preauth]",
+			},
+			want: []string{"preauth root 203.0.113.11 7000"},
+		},
+		{
+			name: "失败后断开的多行日志",
+			lines: []string{
+				"Jan  1 10:00:00 web sshd[1006]: Failed password for root from 203.0.113.12 port 7100 ssh2",
+				"Jan  1 10:00:01 web sshd[1006]: Received disconnect from 203.0.113.12 port 7100:11: Bye Bye [preauth]",
+				"Jan  1 10:00:01 web sshd[1006]: Disconnected from authenticating user root 203.0.113.12 port 7100 [preauth]",
+			},
+			want: []string{"failed root 203.0.113.12 7100"},
+		},
+		{
+			name: "同一连接的每次尝试都计数",
+			lines: []string{
+				"Jan  1 10:00:00 web sshd[1007]: Failed password for root from 203.0.113.13 port 7200 ssh2",
+				"Jan  1 10:00:02 web sshd[1007]: Failed password for root from 203.0.113.13 port 7200 ssh2",
+				"Jan  1 10:00:04 web sshd[1007]: Failed password for root from 203.0.113.13 port 7200 ssh2",
+				"Jan  1 10:00:04 web sshd[1007]: error: maximum authentication attempts exceeded for root from 203.0.113.13 port 7200 ssh2 [preauth]",
+				"Jan  1 10:00:04 web sshd[1007]: Disconnecting authenticating user root 203.0.113.13 port 7200: Too many authentication failures [preauth]",
+			},
+			want: []string{
+				"failed root 203.0.113.13 7200",
+				"failed root 203.0.113.13 7200",
+				"failed root 203.0.113.13 7200",
+				"max_attempts root 203.0.113.13 7200",
+			},
+		},
+		{
+			name: "不同连接分别合并",
+			lines: []string{
+				"Jan  1 10:00:00 web sshd[1008]: Invalid user oracle from 203.0.113.14 port 8000",
+				"Jan  1 10:00:00 web sshd[1009]: Invalid user oracle from 203.0.113.14 port 8001",
+				"Jan  1 10:00:01 web sshd[1008]: Failed password for invalid user oracle from 203.0.113.14 port 8000 ssh2",
+				"Jan  1 10:00:01 web sshd-session[1009]: Connection closed by invalid user oracle 203.0.113.14 port 8001 [preauth]",
+			},
+			want: []string{
+				"failed oracle 203.0.113.14 8000 invalid",
+				"invalid_user oracle 203.0.113.14 8001 invalid",
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tm := newTestMonitor(t, LogTypeAuth)
+			events := tm.feed(tt.lines...)
+			events = append(events, tm.pending(pendingFailureTimeout)...)
+
+			if got := failureSummary(events); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("events = %q, want %q", got, tt.want)
+			}
+			for _, e := range events {
+				if e.Type != config.EventTypeFailure || e.Location != "测试位置" || !strings.Contains(e.Details, "[测试位置]") {
+					t.Errorf("event = %+v", e)
+				}
+			}
+		})
+	}
+}
+
+func TestSSHDFailureDetails(t *testing.T) {
+	tests := []struct {
+		line string
+		want string
+	}{
+		{
+			"Jan  1 10:00:00 web sshd[1001]: Failed password for root from 203.0.113.7 port 22 ssh2",
+			"IP 203.0.113.7[测试位置] 使用 password 方式登录用户 root 失败",
+		},
+		{
+			"Jan  1 10:00:00 web sshd[1001]: Failed password for invalid user admin from 203.0.113.7 port 22 ssh2",
+			"IP 203.0.113.7[测试位置] 使用 password 方式登录不存在的用户 admin 失败",
+		},
+		{
+			"Jan  1 10:00:00 web sshd[1001]: error: maximum authentication attempts exceeded for root from 203.0.113.7 port 22 ssh2 [preauth]",
+			"IP 203.0.113.7[测试位置] 登录用户 root 超过最大认证次数",
+		},
+		{
+			"Jan  1 10:00:00 web sshd[1001]: Connection closed by 203.0.113.7 port 22 [preauth]",
+			"IP 203.0.113.7[测试位置] 在认证前断开连接",
+		},
+	}
+	for _, tt := range tests {
+		tm := newTestMonitor(t, LogTypeAuth)
+		events := tm.feed(tt.line)
+		if len(events) != 1 || events[0].Details != tt.want {
+			t.Errorf("processLine(%s) = %+v, want %q", tt.line, events, tt.want)
+		}
+	}
+}
+
+func TestSSHDPendingFailureTimeout(t *testing.T) {
+	tm := newTestMonitor(t, LogTypeAuth)
+	if events := tm.feed("Jan  1 10:00:00 web sshd[1002]: Invalid user admin from 203.0.113.8 port 40000"); len(events) != 0 {
+		t.Fatalf("次要日志立即产生了事件: %+v", events)
+	}
+	if events := tm.pending(pendingFailureTimeout - time.Second); len(events) != 0 {
+		t.Errorf("未超时就发送了事件: %+v", events)
+	}
+	events := tm.pending(time.Second)
+	if got, want := failureSummary(events), []string{"invalid_user admin 203.0.113.8 40000 invalid"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+
+	// 超时发送后，同一连接迟到的主要日志仍会产生事件，断开日志不再重复
+	events = tm.feed(
+		"Jan  1 10:00:12 web sshd[1002]: Failed password for invalid user admin from 203.0.113.8 port 40000 ssh2",
+		"Jan  1 10:00:12 web sshd[1002]: Connection closed by invalid user admin 203.0.113.8 port 40000 [preauth]",
+	)
+	if got, want := failureSummary(events), []string{"failed admin 203.0.113.8 40000 invalid"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("events = %q, want %q", got, want)
+	}
+}
+
+func TestGeolocateAfterChecks(t *testing.T) {
+	lines := []string{
+		"Jan  1 10:00:00 web sshd[2001]: Accepted publickey for alice from 198.51.100.1 port 50000 ssh2: ED25519 SHA256:abc",
+		"Jan  1 10:00:00 web sshd[2001]: pam_unix(sshd:session): session opened for user alice(uid=1000) by (uid=0)",
+		"Jan  1 10:00:05 web sshd[3001]: Invalid user admin from 203.0.113.8 port 40000",
+		"Jan  1 10:00:06 web sshd[3001]: Failed password for invalid user admin from 203.0.113.8 port 40000 ssh2",
+		"Jan  1 10:00:06 web sshd[3001]: Connection closed by invalid user admin 203.0.113.8 port 40000 [preauth]",
+		"Jan  1 10:30:00 web sshd[2001]: Disconnected from user alice 198.51.100.1 port 50000",
+		"Jan  1 10:30:00 web sshd[2001]: pam_unix(sshd:session): session closed for user alice",
+	}
+	tests := []struct {
+		name    string
+		enabled []config.EventType
+		types   []config.EventType
+		lookups []string
+	}{
+		{
+			name:    "只启用 fail",
+			enabled: []config.EventType{config.EventTypeFailure},
+			types:   []config.EventType{config.EventTypeFailure},
+			lookups: []string{"203.0.113.8"},
+		},
+		{
+			name:    "启用 logout 未启用 success",
+			enabled: []config.EventType{config.EventTypeLogout},
+			types:   []config.EventType{config.EventTypeLogout},
+			lookups: []string{"198.51.100.1"},
+		},
+		{
+			name:    "启用 success 与 logout",
+			enabled: []config.EventType{config.EventTypeSuccess, config.EventTypeLogout},
+			types:   []config.EventType{config.EventTypeSuccess, config.EventTypeLogout},
+			lookups: []string{"198.51.100.1"},
+		},
+		{
+			name:    "均未启用",
+			enabled: []config.EventType{config.EventTypeSudo},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			withConfig(t, tt.enabled...)
+			tm := newTestMonitor(t, LogTypeAuth)
+
+			var types []config.EventType
+			for _, e := range tm.feed(lines...) {
+				types = append(types, e.Type)
+				if e.Location != "测试位置" {
+					t.Errorf("%s 事件的位置 = %q", e.Type, e.Location)
+				}
+			}
+			if !reflect.DeepEqual(types, tt.types) {
+				t.Errorf("events = %v, want %v", types, tt.types)
+			}
+			if !reflect.DeepEqual(tm.lookups, tt.lookups) {
+				t.Errorf("查询位置的 IP = %v, want %v", tm.lookups, tt.lookups)
+			}
+		})
+	}
+}
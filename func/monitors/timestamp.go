@@ -0,0 +1,55 @@
+package monitors
+
+import (
+	"regexp"
+	"strings"
+	"time"
+)
+
+var (
+	// isoTimestamp 匹配行首的 "2024-01-02T15:04:05.123456+08:00"（rsyslog 高精度格式）
+	// 与 "2024-01-02 15:04:05,123"（fail2ban 日志）
+	isoTimestamp = regexp.MustCompile(`^\d{4}-\d{2}-\d{2}[T ]\d{2}:\d{2}:\d{2}(?:[.,]\d+)?(?:Z|[+-]\d{2}:?\d{2})?`)
+	// syslogTimestamp 匹配行首的 "Jan  2 15:04:05"（传统 syslog 格式，不含年份）
+	syslogTimestamp = regexp.MustCompile(`^[A-Z][a-z]{2} [ \d]\d \d{2}:\d{2}:\d{2}`)
+	// clfTimestamp 匹配 "[02/Jan/2006:15:04:05 -0700]"（nginx、apache 访问日志）
+	clfTimestamp = regexp.MustCompile(`\[(\d{2}/[A-Z][a-z]{2}/\d{4}:\d{2}:\d{2}:\d{2} [+-]\d{4})\]`)
+)
+
+// isoLayouts 行首时间可能的格式，没有时区时按本地时间解析
+var isoLayouts = []string{
+	"2006-01-02T15:04:05.999999999Z07:00",
+	"2006-01-02T15:04:05.999999999Z0700",
+	"2006-01-02T15:04:05.999999999",
+}
+
+// ParseTimestamp 解析日志行中的时间，ref 用于补全 syslog 格式缺少的年份
+func ParseTimestamp(line string, ref time.Time) (time.Time, bool) {
+	if s := isoTimestamp.FindString(line); s != "" {
+		s = strings.Replace(strings.Replace(s, " ", "T", 1), ",", ".", 1)
+		for _, layout := range isoLayouts {
+			if t, err := time.ParseInLocation(layout, s, time.Local); err == nil {
+				return t, true
+			}
+		}
+	}
+
+	if s := syslogTimestamp.FindString(line); s != "" {
+		t, err := time.ParseInLocation("Jan _2 15:04:05", s, time.Local)
+		if err == nil {
+			t = t.AddDate(ref.Year(), 0, 0)
+			// 晚于参考时间的日期属于上一年，如一月份回放去年十二月的日志
+			if t.Sub(ref) > 24*time.Hour {
+				t = t.AddDate(-1, 0, 0)
+			}
+			return t, true
+		}
+	}
+
+	if match := clfTimestamp.FindStringSubmatch(line); match != nil {
+		if t, err := time.Parse("02/Jan/2006:15:04:05 -0700", match[1]); err == nil {
+			return t, true
+		}
+	}
+	return time.Time{}, false
+}
@@ -1,6 +1,12 @@
 package monitors
 
-import "loginfopush/config"
+import (
+	"fmt"
+	"loginfopush/config"
+	"regexp"
+	"sort"
+	"time"
+)
 
 // LogType 定义日志类型
 type LogType string
@@ -9,31 +15,51 @@ const (
 	// 日志类型常量
 	LogTypeFail2ban LogType = "fail2ban" // fail2ban 日志
 	LogTypeAuth     LogType = "auth"     // 认证日志
+	LogTypeCustom   LogType = "custom"   // 自定义事件的日志
 )
 
 // LogConfig 日志配置结构
 type LogConfig struct {
-	Type     LogType  // 日志类型
-	Path     string   // 日志文件路径
-	Patterns []string // 匹配模式
+	Type     LogType         // 日志类型
+	Path     string          // 日志文件路径
+	Patterns []string        // 匹配模式
+	Custom   []CustomPattern // 自定义事件的匹配规则（仅 custom 类型）
 }
 
-// EventType 事件类型
-type EventType string
-
-const (
-	EventTypeBan     EventType = "ban"     // IP 被封禁
-	EventTypeFailure EventType = "fail"    // 登录失败
-	EventTypeSuccess EventType = "success" // 登录成功
-)
+// CustomPattern 自定义事件的匹配规则
+type CustomPattern struct {
+	Type   config.EventType // 匹配后产生的事件类型
+	Regexp *regexp.Regexp   // 匹配日志行的正则表达式
+}
 
 // Event 事件结构
 type Event struct {
-	Type     EventType // 事件类型
-	IP       string    // 相关 IP
-	Location string    // 相关IP 位置
-	Details  string    // 详细信息
-	Raw      string    // 原始日志行
+	Type     config.EventType  // 事件类型
+	Time     time.Time         // 事件发生时间
+	IP       string            // 相关 IP
+	User     string            // 相关用户
+	Location string            // 相关IP 位置
+	Country  string            // IP 所属国家
+	ASN      string            // IP 所属自治系统
+	Severity config.Severity   // 严重程度
+	Details  string            // 详细信息
+	Raw      string            // 原始日志行
+	Fields   map[string]string // 自定义事件正则命名分组提取的字段
+}
+
+// Monitor 日志监控器
+type Monitor interface {
+	Start(eventChan chan<- Event, stopChan <-chan struct{})
+	Close() error
+}
+
+// authPatterns 认证日志的匹配模式
+var authPatterns = []string{
+	"Accepted password for",   // 密码登录成功
+	"Accepted publickey for",  // 密钥登录成功
+	"session opened for user", // 会话开启
+	"session closed for user", // 会话关闭
+	"Disconnected from user",  // 连接断开
 }
 
 // LogConfigs 预定义的日志配置
@@ -43,27 +69,19 @@ var LogConfigs = []LogConfig{
 		Path: "/var/log/fail2ban.log",
 		// Path: "./fail2ban.log",
 		Patterns: []string{
-			"Ban",   // 封禁事件
+			"Ban",   // 封禁、解封与恢复封禁事件
 			"Found", // 发现攻击
 		},
 	},
 	{
-		Type: LogTypeAuth,
-		Path: "/var/log/auth.log", // Debian/Ubuntu 系统
-		Patterns: []string{
-			"Accepted password for",   // 密码登录成功
-			"Accepted publickey for",  // 密钥登录成功
-			"session opened for user", // 会话开启
-		},
+		Type:     LogTypeAuth,
+		Path:     "/var/log/auth.log", // Debian/Ubuntu 系统
+		Patterns: authPatterns,
 	},
 	{
-		Type: LogTypeAuth,
-		Path: "/var/log/secure", // CentOS/RHEL 系统
-		Patterns: []string{
-			"Accepted password for",   // 密码登录成功
-			"Accepted publickey for",  // 密钥登录成功
-			"session opened for user", // 会话开启
-		},
+		Type:     LogTypeAuth,
+		Path:     "/var/log/secure", // CentOS/RHEL 系统
+		Patterns: authPatterns,
 	},
 }
 
@@ -73,24 +91,80 @@ func shouldMonitorLogType(logType LogType) bool {
 		return true // 如果配置未加载，默认监控所有日志
 	}
 
+	// 启用安全报告时需要采集所有日志用于统计
+	if config.GlobalConfig.EventEnabled(config.EventTypeReport) {
+		return true
+	}
+
 	switch logType {
 	case LogTypeFail2ban:
 		// 检查是否启用了 ban 或 fail 事件
-		for _, evt := range config.GlobalConfig.Events {
-			if (evt.Type == "ban" || evt.Type == "fail") && evt.Enabled {
-				return true
-			}
-		}
-		return false
+		return config.GlobalConfig.EventEnabled(config.EventTypeBan) ||
+			config.GlobalConfig.EventEnabled(config.EventTypeUnban) ||
+			config.GlobalConfig.EventEnabled(config.EventTypeFailure) ||
+			config.GlobalConfig.ActionEnabled()
 	case LogTypeAuth:
-		// 检查是否启用了 success 事件
-		for _, evt := range config.GlobalConfig.Events {
-			if evt.Type == "success" && evt.Enabled {
-				return true
-			}
-		}
-		return false
+		// 检查是否启用了登录、登出或长会话事件
+		return config.GlobalConfig.EventEnabled(config.EventTypeSuccess) ||
+			config.GlobalConfig.EventEnabled(config.EventTypeLogout) ||
+			config.GlobalConfig.EventEnabled(config.EventTypeLongSession) ||
+			config.GlobalConfig.EventEnabled(config.EventTypeSudo) ||
+			config.GlobalConfig.EventEnabled(config.EventTypeSu) ||
+			config.GlobalConfig.EventEnabled(config.EventTypeFailure) ||
+			config.GlobalConfig.ActionEnabled()
+	case LogTypeCustom:
+		// 自定义日志只为已启用的事件创建
+		return true
 	default:
 		return false
 	}
 }
+
+// CustomLogConfigs 按日志路径汇总已启用事件的日志来源，每个路径创建一个监控
+func CustomLogConfigs(cfg *config.Config) ([]LogConfig, error) {
+	byPath := make(map[string]*LogConfig)
+	var paths []string
+
+	for name, evt := range cfg.Events {
+		if !evt.Enabled || evt.Source == nil {
+			continue
+		}
+
+		logConfig, ok := byPath[evt.Source.Path]
+		if !ok {
+			logConfig = &LogConfig{Type: LogTypeCustom, Path: evt.Source.Path}
+			byPath[evt.Source.Path] = logConfig
+			paths = append(paths, evt.Source.Path)
+		}
+
+		for _, pattern := range evt.Source.Patterns {
+			re, err := regexp.Compile(pattern)
+			if err != nil {
+				return nil, fmt.Errorf("事件 %s 的匹配规则无效: %v", name, err)
+			}
+			logConfig.Custom = append(logConfig.Custom, CustomPattern{Type: evt.Type, Regexp: re})
+		}
+	}
+
+	sort.Strings(paths)
+	configs := make([]LogConfig, 0, len(paths))
+	for _, path := range paths {
+		configs = append(configs, *byPath[path])
+	}
+	return configs, nil
+}
+
+// PatternFields 自定义事件正则中的命名分组，正则无效时返回空
+func PatternFields(pattern string) []string {
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil
+	}
+	var names []string
+	for _, name := range re.SubexpNames() {
+		if name != "" {
+			names = append(names, name)
+		}
+	}
+	return names
+}
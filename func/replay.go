@@ -0,0 +1,96 @@
+package _func
+
+import (
+	"loginfopush/config"
+	"loginfopush/func/monitors"
+	"loginfopush/notifier"
+	"sync"
+	"time"
+)
+
+// ReplayOptions 回放参数
+type ReplayOptions struct {
+	Type      monitors.LogType // 日志类型，为空时按文件名判断
+	DryRun    bool             // 只渲染消息，不真正发送
+	Geolocate bool             // 查询 IP 位置，默认不查询
+}
+
+// ReplayMessage 演练时渲染出的一条消息
+type ReplayMessage struct {
+	Notifier string
+	Message  notifier.Message
+}
+
+// ReplayEvent 回放产生的一个事件及其处理结果
+type ReplayEvent struct {
+	Event    monitors.Event
+	Result   notifier.Result
+	Err      error
+	Messages []ReplayMessage // 演练时各通知渠道收到的消息
+	Summary  bool            // 抑制窗口结束时的汇总或事件汇总，不对应日志中的事件，只有演练时产生
+}
+
+// Replay 将日志文件从头送入解析与推送流程，每个事件处理后回调 handle
+//
+// 回放不写入事件历史、不触发主动封禁，也不查询 fail2ban 控制套接字；除非指定 Geolocate，也不查询 IP 位置。
+// 免打扰延迟的消息立即发送，重复抑制的汇总与事件汇总按日志时间到期时或在文件末尾发送。
+func Replay(cfg *config.Config, path string, opts ReplayOptions, handle func(ReplayEvent)) error {
+	logConfig, err := monitors.ReplayConfig(cfg, path, opts.Type)
+	if err != nil {
+		return err
+	}
+
+	var mu sync.Mutex
+	var messages []ReplayMessage
+	if opts.DryRun {
+		notifierManager, err = notifier.NewDryRunManager(cfg, func(name string, msg notifier.Message) {
+			mu.Lock()
+			messages = append(messages, ReplayMessage{Notifier: name, Message: msg})
+			mu.Unlock()
+		})
+	} else {
+		notifierManager, err = notifier.NewNotifierManager(cfg)
+	}
+	if err != nil {
+		return err
+	}
+	defer notifierManager.Close()
+	notifierManager.EnableReplay()
+	monitorConfig = cfg
+
+	// take 取出已渲染的消息
+	take := func() []ReplayMessage {
+		mu.Lock()
+		defer mu.Unlock()
+		taken := messages
+		messages = nil
+		return taken
+	}
+	// summarize 在事件之前输出已到期的汇总，避免混入下一个事件的消息
+	summarize := func(now time.Time) {
+		if summaries := take(); len(summaries) > 0 {
+			handle(ReplayEvent{Event: monitors.Event{Time: now}, Messages: summaries, Summary: true})
+		}
+	}
+
+	var last time.Time
+	err = monitors.Replay(logConfig, opts.Geolocate, func(event monitors.Event) {
+		notifierManager.ExpireDedup(event.Time)
+		notifierManager.ExpireDigests(event.Time)
+		summarize(event.Time)
+
+		severities.Assign(cfg, &event)
+		_, result, err := sendEvent(event)
+		last = event.Time
+		handle(ReplayEvent{Event: event, Result: result, Err: err, Messages: take()})
+	})
+	if err != nil {
+		return err
+	}
+
+	// 文件末尾仍未结束的抑制窗口与汇总缓存中的事件
+	notifierManager.FlushDedup()
+	notifierManager.FlushDigests(last)
+	summarize(last)
+	return nil
+}
@@ -0,0 +1,105 @@
+package _func
+
+import (
+	"loginfopush/config"
+	"loginfopush/func/monitors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// defaultFailureWindow 判定"失败后登录成功"的默认时间窗口
+const defaultFailureWindow = time.Hour
+
+// defaultSeverities 各事件类型的默认严重程度
+var defaultSeverities = map[config.EventType]config.Severity{
+	config.EventTypeBan:         config.SeverityInfo,
+	config.EventTypeFailure:     config.SeverityInfo,
+	config.EventTypeSuccess:     config.SeverityWarning,
+	config.EventTypeLogout:      config.SeverityInfo,
+	config.EventTypeLongSession: config.SeverityWarning,
+	config.EventTypeSudo:        config.SeverityWarning,
+	config.EventTypeSu:          config.SeverityWarning,
+	config.EventTypeUnban:       config.SeverityInfo,
+}
+
+// severityAssigner 根据配置的规则为事件判定严重程度
+type severityAssigner struct {
+	mu       sync.Mutex
+	failures map[string]time.Time // IP 最近一次失败或被封禁的时间
+}
+
+// newSeverityAssigner 创建严重程度判定器
+func newSeverityAssigner() *severityAssigner {
+	return &severityAssigner{failures: make(map[string]time.Time)}
+}
+
+// Assign 判定事件的严重程度，规则只会提升严重程度
+func (a *severityAssigner) Assign(cfg *config.Config, event *monitors.Event) {
+	severity := defaultSeverities[event.Type]
+	if evt, ok := cfg.FindEvent(event.Type); ok && evt.Severity.Valid() {
+		severity = evt.Severity
+	}
+	if severity == "" {
+		severity = config.SeverityInfo
+	}
+
+	rules := cfg.Severity
+	if rules == nil {
+		rules = &config.SeverityConfig{}
+	}
+	raise := func(s, fallback config.Severity) {
+		if !s.Valid() {
+			s = fallback
+		}
+		if s.Rank() > severity.Rank() {
+			severity = s
+		}
+	}
+
+	now := event.Time
+	window := rules.FailureWindow.Std()
+	if window <= 0 {
+		window = defaultFailureWindow
+	}
+
+	a.mu.Lock()
+	switch event.Type {
+	case config.EventTypeFailure, config.EventTypeBan:
+		if event.IP != "" {
+			a.failures[event.IP] = now
+		}
+	case config.EventTypeSuccess:
+		if event.User == "root" {
+			raise(rules.RootLogin, config.SeverityCritical)
+		}
+		if strings.Contains(event.Raw, "Accepted password") {
+			raise(rules.PasswordAuth, config.SeverityWarning)
+		}
+		if last, ok := a.failures[event.IP]; ok && now.Sub(last) < window {
+			raise(rules.SuccessAfterFailures, config.SeverityCritical)
+		}
+	}
+	for ip, last := range a.failures {
+		if now.Sub(last) >= window {
+			delete(a.failures, ip)
+		}
+	}
+	a.mu.Unlock()
+
+	if len(rules.AllowedCountries) > 0 && event.Country != "" && !containsFold(rules.AllowedCountries, event.Country) {
+		raise(rules.UnknownCountry, config.SeverityWarning)
+	}
+
+	event.Severity = severity
+}
+
+// containsFold 忽略大小写判断列表中是否包含指定值
+func containsFold(list []string, value string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, value) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,206 @@
+package _func
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"loginfopush/config"
+	"loginfopush/func/monitors"
+	"loginfopush/logger"
+	"loginfopush/notifier"
+	"loginfopush/store"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+)
+
+// reportTopN 报告中排行榜的条数
+const reportTopN = 5
+
+// knownUsersFile 已知用户列表在状态目录下的文件名
+const knownUsersFile = "known_users.json"
+
+// securityStats 从处理过的事件中累计安全统计，用于定时安全报告
+type securityStats struct {
+	mu         sync.Mutex
+	start      time.Time
+	logins     map[string]int
+	failures   int
+	bans       int
+	ips        map[string]int
+	countries  map[string]int
+	asns       map[string]int
+	newUsers   []string
+	knownUsers map[string]bool
+	usersFile  string // 已知用户列表的保存路径，为空时只保存在内存中
+}
+
+// newSecurityStats 创建统计器
+func newSecurityStats() *securityStats {
+	s := &securityStats{knownUsers: make(map[string]bool)}
+	s.reset(time.Now())
+	return s
+}
+
+// reset 开始新的统计周期，已知用户列表保留
+func (s *securityStats) reset(start time.Time) {
+	s.start = start
+	s.logins = make(map[string]int)
+	s.failures = 0
+	s.bans = 0
+	s.ips = make(map[string]int)
+	s.countries = make(map[string]int)
+	s.asns = make(map[string]int)
+	s.newUsers = nil
+}
+
+// Record 记录一条事件
+func (s *securityStats) Record(event monitors.Event) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if event.IP != "" {
+		s.ips[event.IP]++
+	}
+
+	switch event.Type {
+	case config.EventTypeSuccess:
+		user := event.User
+		if user == "" {
+			user = "unknown"
+		}
+		s.logins[user]++
+		if event.User != "" && !s.knownUsers[event.User] {
+			s.knownUsers[event.User] = true
+			s.newUsers = append(s.newUsers, event.User)
+			if err := s.saveKnownUsers(); err != nil {
+				logger.Warnf("%v", err)
+			}
+		}
+	case config.EventTypeFailure:
+		s.failures++
+		s.recordSource(event)
+	case config.EventTypeBan:
+		// fail2ban 重启后恢复的封禁不是新的封禁
+		if event.Fields["action"] == "restore" {
+			return
+		}
+		s.bans++
+		s.recordSource(event)
+	}
+}
+
+// loadKnownUsers 读取 path 中保存的已知用户列表，之后出现的新用户同样写入 path
+//
+// 文件不存在时以事件历史中的成功登录补全，避免启用后所有用户都被当作新用户。
+func (s *securityStats) loadKnownUsers(path string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.usersFile = path
+
+	data, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return s.seedKnownUsers()
+	}
+	if err != nil {
+		return fmt.Errorf("读取已知用户列表失败: %v", err)
+	}
+	var users []string
+	if err := json.Unmarshal(data, &users); err != nil {
+		return fmt.Errorf("解析已知用户列表 %s 失败: %v", path, err)
+	}
+	for _, user := range users {
+		s.knownUsers[user] = true
+	}
+	return nil
+}
+
+// seedKnownUsers 从事件历史中的成功登录补全已知用户并保存，调用方需持有锁
+func (s *securityStats) seedKnownUsers() error {
+	if history == nil {
+		return nil
+	}
+	records, err := history.Query(store.Filter{Types: []string{string(config.EventTypeSuccess)}})
+	if err != nil {
+		return fmt.Errorf("从事件历史读取已知用户失败: %v", err)
+	}
+	for _, r := range records {
+		if r.User != "" {
+			s.knownUsers[r.User] = true
+		}
+	}
+	if len(s.knownUsers) == 0 {
+		return nil
+	}
+	return s.saveKnownUsers()
+}
+
+// saveKnownUsers 保存已知用户列表，调用方需持有锁
+func (s *securityStats) saveKnownUsers() error {
+	if s.usersFile == "" {
+		return nil
+	}
+	users := make([]string, 0, len(s.knownUsers))
+	for user := range s.knownUsers {
+		users = append(users, user)
+	}
+	sort.Strings(users)
+
+	data, err := json.MarshalIndent(users, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(s.usersFile), 0755); err != nil {
+		return fmt.Errorf("保存已知用户列表失败: %v", err)
+	}
+	tmp := s.usersFile + ".tmp"
+	if err := ioutil.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("保存已知用户列表失败: %v", err)
+	}
+	if err := os.Rename(tmp, s.usersFile); err != nil {
+		return fmt.Errorf("保存已知用户列表失败: %v", err)
+	}
+	return nil
+}
+
+// recordSource 记录攻击来源的国家与 ASN
+func (s *securityStats) recordSource(event monitors.Event) {
+	if event.Country != "" {
+		s.countries[event.Country]++
+	}
+	if event.ASN != "" {
+		s.asns[event.ASN]++
+	}
+}
+
+// Snapshot 生成当前周期的报告数据并开始新周期
+func (s *securityStats) Snapshot(now time.Time) notifier.ReportData {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	total := 0
+	for _, count := range s.logins {
+		total += count
+	}
+
+	newUsers := append([]string(nil), s.newUsers...)
+	sort.Strings(newUsers)
+
+	data := notifier.ReportData{
+		Start:        s.start.Format("2006-01-02 15:04:05"),
+		End:          now.Format("2006-01-02 15:04:05"),
+		LoginTotal:   total,
+		Logins:       notifier.TopN(s.logins, 0),
+		Failures:     s.failures,
+		Bans:         s.bans,
+		DistinctIPs:  len(s.ips),
+		TopIPs:       notifier.TopN(s.ips, reportTopN),
+		TopCountries: notifier.TopN(s.countries, reportTopN),
+		TopASNs:      notifier.TopN(s.asns, reportTopN),
+		NewUsers:     newUsers,
+	}
+	s.reset(now)
+	return data
+}
@@ -0,0 +1,121 @@
+package _func
+
+import (
+	"fmt"
+	"loginfopush/config"
+	"loginfopush/func/monitors"
+	"loginfopush/notifier"
+	"time"
+)
+
+// 测试事件使用的示例数据，IP 取自文档保留地址段
+const (
+	sampleIP       = "203.0.113.10"
+	sampleLocation = "示例国家-示例城市"
+	sampleCountry  = "示例国家"
+	sampleASN      = "AS64500 EXAMPLE-NET"
+)
+
+// SampleEvent 构造一个指定类型的示例事件，字段与实际日志产生的事件一致
+func SampleEvent(cfg *config.Config, eventType config.EventType) monitors.Event {
+	now := time.Now()
+	event := monitors.Event{
+		Type:     eventType,
+		Time:     now,
+		IP:       sampleIP,
+		User:     "root",
+		Location: sampleLocation,
+		Country:  sampleCountry,
+		ASN:      sampleASN,
+	}
+
+	switch eventType {
+	case config.EventTypeSuccess:
+		event.Raw = fmt.Sprintf("sshd[1234]: Accepted publickey for root from %s port 52222 ssh2", sampleIP)
+		event.Details = fmt.Sprintf("IP %s[%s] 密钥登录成功", sampleIP, sampleLocation)
+	case config.EventTypeFailure:
+		event.User = "admin"
+		event.Raw = fmt.Sprintf("sshd[1234]: Failed password for invalid user admin from %s port 52222 ssh2", sampleIP)
+		event.Fields = map[string]string{"port": "52222", "method": "password", "reason": "failed", "invalid_user": "true"}
+		event.Details = fmt.Sprintf("IP %s[%s] 使用 password 方式登录不存在的用户 admin 失败", sampleIP, sampleLocation)
+	case config.EventTypeBan:
+		event.User = ""
+		event.Fields = map[string]string{"jail": "sshd", "action": "ban", "attempts": "5", "bantime": "10m", "ban_count": "1"}
+		event.Details = fmt.Sprintf("IP %s[%s] 失败 5 次后已被 fail2ban 封禁（jail: sshd）", sampleIP, sampleLocation)
+	case config.EventTypeUnban:
+		event.User = ""
+		event.Fields = map[string]string{"jail": "sshd", "action": "unban", "duration": "10m0s", "bantime": "10m", "ban_count": "1"}
+		event.Details = fmt.Sprintf("IP %s[%s] 已被 fail2ban 解封（jail: sshd，封禁 10m0s）", sampleIP, sampleLocation)
+	case config.EventTypeLogout, config.EventTypeLongSession:
+		duration := 3 * time.Hour
+		event.Fields = map[string]string{
+			"pid":              "1234",
+			"login_time":       now.Add(-duration).Format("2006-01-02 15:04:05"),
+			"duration":         duration.String(),
+			"duration_seconds": fmt.Sprint(int64(duration / time.Second)),
+		}
+		if eventType == config.EventTypeLogout {
+			event.Details = fmt.Sprintf("用户 root 从 IP %s[%s] 登出，会话时长 %s", sampleIP, sampleLocation, duration)
+		} else {
+			event.Details = fmt.Sprintf("用户 root 来自 IP %s[%s] 的会话已持续 %s", sampleIP, sampleLocation, duration)
+		}
+	case config.EventTypeSudo:
+		event.IP, event.Location, event.Country, event.ASN = "", "", "", ""
+		event.User = "alice"
+		event.Fields = map[string]string{"user": "alice", "target_user": "root", "command": "/usr/bin/systemctl restart nginx", "tty": "pts/0", "pwd": "/home/alice", "result": "success"}
+		event.Details = "用户 alice 以 root 身份执行: /usr/bin/systemctl restart nginx"
+	case config.EventTypeSu:
+		event.IP, event.Location, event.Country, event.ASN = "", "", "", ""
+		event.User = "alice"
+		event.Fields = map[string]string{"user": "alice", "target_user": "root", "tty": "pts/0", "result": "success"}
+		event.Details = "用户 alice 切换到 root"
+	default:
+		// 自定义事件的字段取自日志来源正则的命名分组
+		event.Fields = make(map[string]string)
+		if evt, ok := cfg.LookupEvent(eventType); ok && evt.Source != nil {
+			for _, pattern := range evt.Source.Patterns {
+				for _, name := range monitors.PatternFields(pattern) {
+					event.Fields[name] = "example"
+				}
+			}
+		}
+		event.Fields["ip"] = sampleIP
+		if _, ok := event.Fields["user"]; ok {
+			event.Fields["user"] = event.User
+		}
+		event.Details = fmt.Sprintf("这是一条 %s 测试事件", eventType)
+	}
+	return event
+}
+
+// sampleReport 由一组示例事件生成的安全报告数据
+func sampleReport(cfg *config.Config) notifier.ReportData {
+	s := newSecurityStats()
+	for _, t := range []config.EventType{config.EventTypeSuccess, config.EventTypeFailure, config.EventTypeFailure, config.EventTypeBan} {
+		s.Record(SampleEvent(cfg, t))
+	}
+	return s.Snapshot(time.Now())
+}
+
+// TestNotify 按事件配置的模板渲染示例事件，标记为测试消息后发送到指定的通知渠道，names 为空时发送到所有启用的通知渠道
+func TestNotify(cfg *config.Config, eventType config.EventType, names []string) (notifier.Message, []notifier.TestResult, error) {
+	manager, err := notifier.NewNotifierManager(cfg)
+	if err != nil {
+		return notifier.Message{}, nil, err
+	}
+	defer manager.Close()
+
+	var msg notifier.Message
+	if eventType == config.EventTypeReport {
+		msg, err = manager.RenderReport(sampleReport(cfg))
+	} else {
+		event := SampleEvent(cfg, eventType)
+		severities.Assign(cfg, &event)
+		msg, err = manager.RenderEvent(eventType, eventData(event))
+	}
+	if err != nil {
+		return notifier.Message{}, nil, err
+	}
+	msg = notifier.MarkTest(msg)
+	return msg, manager.SendTest(msg, names), nil
+}
@@ -0,0 +1,73 @@
+package logger
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+)
+
+// Level 日志级别
+type Level int32
+
+// 日志级别，从低到高
+const (
+	LevelDebug Level = iota
+	LevelInfo
+	LevelWarn
+	LevelError
+)
+
+// levelNames 级别名称
+var levelNames = map[string]Level{
+	"debug": LevelDebug,
+	"info":  LevelInfo,
+	"warn":  LevelWarn,
+	"error": LevelError,
+}
+
+// current 当前级别，默认 info
+var current = int32(LevelInfo)
+
+// SetLevel 设置日志级别: debug、info、warn、error
+func SetLevel(name string) error {
+	level, ok := levelNames[strings.ToLower(name)]
+	if !ok {
+		return fmt.Errorf("未知的日志级别 %q，可选: debug、info、warn、error", name)
+	}
+	atomic.StoreInt32(&current, int32(level))
+	return nil
+}
+
+// Enabled 判断该级别的日志是否输出
+func Enabled(level Level) bool {
+	return int32(level) >= atomic.LoadInt32(&current)
+}
+
+// Debugf 输出调试日志
+func Debugf(format string, args ...interface{}) {
+	logf(LevelDebug, "", format, args...)
+}
+
+// Infof 输出普通日志
+func Infof(format string, args ...interface{}) {
+	logf(LevelInfo, "", format, args...)
+}
+
+// Warnf 输出警告日志
+func Warnf(format string, args ...interface{}) {
+	logf(LevelWarn, "警告: ", format, args...)
+}
+
+// Errorf 输出错误日志
+func Errorf(format string, args ...interface{}) {
+	logf(LevelError, "错误: ", format, args...)
+}
+
+// logf 按级别输出一行日志到标准输出，由 systemd journal 记录
+func logf(level Level, prefix, format string, args ...interface{}) {
+	if !Enabled(level) {
+		return
+	}
+	fmt.Fprintf(os.Stdout, prefix+format+"\n", args...)
+}
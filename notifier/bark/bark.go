@@ -1,58 +1,245 @@
 package bark
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io/ioutil"
 	"loginfopush/config"
 	"loginfopush/notifier"
 	"net/http"
 	"net/url"
+	"strings"
 )
 
 func init() {
 	notifier.RegisterNotifier(config.NotifierTypeBark, NewBarkNotifer)
 }
 
-// BarkNotifer Telegram 通知器
+// 默认的事件类型到中断级别映射
+var defaultLevels = map[config.EventType]string{
+	config.EventTypeSuccess: "timeSensitive",
+	config.EventTypeFailure: "active",
+	config.EventTypeBan:     "passive",
+}
+
+// 默认的严重程度到中断级别映射
+var defaultSeverityLevels = map[config.Severity]string{
+	config.SeverityInfo:     "active",
+	config.SeverityWarning:  "timeSensitive",
+	config.SeverityCritical: "critical",
+}
+
+// 支持的中断级别
+var validLevels = map[string]bool{
+	"active":        true,
+	"timeSensitive": true,
+	"passive":       true,
+	"critical":      true,
+}
+
+// BarkNotifer Bark 通知器
 type BarkNotifer struct {
-	config config.BarkConfig
+	config  config.BarkConfig
+	keys    []string
+	baseURL string
+	cipher  *barkCipher
+	client  *http.Client
 }
 
-// NewBarkNotifer 创建 Telegram 通知器
-func NewBarkNotifer(cfg interface{}) (notifier.Notifier, error) {
+// NewBarkNotifer 创建 Bark 通知器
+func NewBarkNotifer(cfg interface{}, opts notifier.Options) (notifier.Notifier, error) {
 	barkConfig, ok := cfg.(config.BarkConfig)
 	if !ok {
 		return nil, fmt.Errorf("无效的 Bark 配置")
 	}
 
-	return &BarkNotifer{
-		config: barkConfig,
-	}, nil
+	// 合并单个与多个设备 Key，并去重
+	var keys []string
+	seen := make(map[string]bool)
+	for _, key := range append([]string{barkConfig.DeviceToken}, barkConfig.DeviceTokens...) {
+		key = strings.TrimSpace(key)
+		if key == "" || seen[key] {
+			continue
+		}
+		seen[key] = true
+		keys = append(keys, key)
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("Bark device_token 不能为空")
+	}
+
+	if barkConfig.Level != "" && !validLevels[barkConfig.Level] {
+		return nil, fmt.Errorf("无效的 Bark 中断级别: %s", barkConfig.Level)
+	}
+	for typ, level := range barkConfig.Levels {
+		if !validLevels[level] {
+			return nil, fmt.Errorf("事件 %s 的 Bark 中断级别无效: %s", typ, level)
+		}
+	}
+	for severity, level := range barkConfig.SeverityLevels {
+		if !validLevels[level] {
+			return nil, fmt.Errorf("严重程度 %s 的 Bark 中断级别无效: %s", severity, level)
+		}
+	}
+
+	baseURL := strings.TrimRight(barkConfig.WebhookURL, "/")
+	if baseURL == "" {
+		baseURL = "https://api.day.app"
+	}
+
+	n := &BarkNotifer{
+		config:  barkConfig,
+		keys:    keys,
+		baseURL: baseURL,
+		client:  opts.HTTPClient,
+	}
+
+	if barkConfig.Encryption != nil {
+		c, err := newBarkCipher(*barkConfig.Encryption)
+		if err != nil {
+			return nil, fmt.Errorf("Bark 加密配置错误: %v", err)
+		}
+		n.cipher = c
+	}
+
+	return n, nil
+}
+
+// barkPayload Bark 推送参数，字段含义见 https://bark.day.app/#/tutorial
+type barkPayload struct {
+	Title      string   `json:"title,omitempty"`
+	Body       string   `json:"body"`
+	DeviceKey  string   `json:"device_key,omitempty"`
+	DeviceKeys []string `json:"device_keys,omitempty"`
+	Level      string   `json:"level,omitempty"`
+	Sound      string   `json:"sound,omitempty"`
+	Icon       string   `json:"icon,omitempty"`
+	Group      string   `json:"group,omitempty"`
+	URL        string   `json:"url,omitempty"`
+	IsArchive  string   `json:"isArchive,omitempty"`
+}
+
+// barkResponse Bark 服务端响应
+type barkResponse struct {
+	Code    int    `json:"code"`
+	Message string `json:"message"`
 }
 
 // Send 发送通知
 func (n *BarkNotifer) Send(msg notifier.Message) error {
-	// 对消息进行 URL 编码
-	encodedMessage := url.QueryEscape(msg.Content)
+	payload := n.buildPayload(msg)
 
-	// 构建请求 URL, 格式: https://api.day.app/your_device_key/content
+	if n.cipher != nil {
+		// 加密推送不支持 device_keys，需逐个设备发送
+		var lastErr error
+		for _, key := range n.keys {
+			if err := n.sendEncrypted(key, payload); err != nil {
+				lastErr = err
+			}
+		}
+		return lastErr
+	}
 
-	requestURL := fmt.Sprintf("%s/%s/%s",
-		n.config.WebhookURL,
-		n.config.DeviceToken,
-		encodedMessage)
+	if len(n.keys) == 1 {
+		payload.DeviceKey = n.keys[0]
+	} else {
+		payload.DeviceKeys = n.keys
+	}
 
-	// 发送请求
-	resp, err := http.Get(requestURL)
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal json error: %v", err)
+	}
+
+	// 格式: POST https://api.day.app/push
+	resp, err := n.client.Post(n.baseURL+"/push", "application/json; charset=utf-8", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("send webhook error: %v", err)
 	}
 	defer resp.Body.Close()
 
+	return checkResponse(resp)
+}
+
+// buildPayload 根据配置和消息构建推送参数
+func (n *BarkNotifer) buildPayload(msg notifier.Message) barkPayload {
+	payload := barkPayload{
+		Title: msg.Title,
+		Body:  msg.Content,
+		Level: n.levelFor(msg),
+		Sound: n.config.Sound,
+		Icon:  n.config.Icon,
+		Group: n.config.Group,
+		URL:   n.config.URL,
+	}
+	if n.config.IsArchive != nil {
+		if *n.config.IsArchive {
+			payload.IsArchive = "1"
+		} else {
+			payload.IsArchive = "0"
+		}
+	}
+	return payload
+}
+
+// levelFor 获取消息对应的中断级别
+//
+// 优先级: 事件类型映射 > 严重程度映射 > 默认级别 > 内置严重程度映射 > 内置事件类型映射
+func (n *BarkNotifer) levelFor(msg notifier.Message) string {
+	if level, ok := n.config.Levels[string(msg.EventType)]; ok {
+		return level
+	}
+	if level, ok := n.config.SeverityLevels[string(msg.Severity)]; ok {
+		return level
+	}
+	if n.config.Level != "" {
+		return n.config.Level
+	}
+	if level, ok := defaultSeverityLevels[msg.Severity]; ok {
+		return level
+	}
+	return defaultLevels[msg.EventType]
+}
+
+// sendEncrypted 发送加密推送，格式: POST https://api.day.app/{key} ciphertext=...&iv=...
+func (n *BarkNotifer) sendEncrypted(key string, payload barkPayload) error {
+	plain, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal json error: %v", err)
+	}
+
+	ciphertext, iv, err := n.cipher.encrypt(plain)
+	if err != nil {
+		return fmt.Errorf("encrypt payload error: %v", err)
+	}
+
+	form := url.Values{}
+	form.Set("ciphertext", ciphertext)
+	if iv != "" {
+		form.Set("iv", iv)
+	}
+
+	resp, err := n.client.PostForm(fmt.Sprintf("%s/%s", n.baseURL, url.PathEscape(key)), form)
+	if err != nil {
+		return fmt.Errorf("send webhook error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	return checkResponse(resp)
+}
+
+// checkResponse 检查 Bark 响应，HTTP 200 时仍需校验 code 字段
+func checkResponse(resp *http.Response) error {
+	body, _ := ioutil.ReadAll(resp.Body)
 	if resp.StatusCode != http.StatusOK {
-		body, _ := ioutil.ReadAll(resp.Body)
 		return fmt.Errorf("webhook response error: status=%d, body=%s", resp.StatusCode, string(body))
 	}
 
+	var result barkResponse
+	if err := json.Unmarshal(body, &result); err == nil && result.Code != 0 && result.Code != http.StatusOK {
+		return fmt.Errorf("webhook response error: code=%d, message=%s", result.Code, result.Message)
+	}
+
 	return nil
 }
@@ -0,0 +1,187 @@
+package bark
+
+import (
+	"encoding/json"
+	"loginfopush/config"
+	"loginfopush/notifier"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeBark 模拟 Bark 服务端的 /push 接口与加密推送的 /{key} 接口
+type fakeBark struct {
+	t          *testing.T
+	encryption config.BarkEncryptionConfig
+
+	mu       sync.Mutex
+	requests map[string][]barkPayload // 请求路径到推送参数
+	status   int                      // 非零时返回的 HTTP 状态码
+	code     int                      // 响应中的 code
+}
+
+func (f *fakeBark) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Method != http.MethodPost {
+		f.t.Errorf("%s %s, want POST", r.Method, r.URL.Path)
+	}
+	var payload barkPayload
+	if r.URL.Path == "/push" {
+		if got := r.Header.Get("Content-Type"); !strings.HasPrefix(got, "application/json") {
+			f.t.Errorf("Content-Type = %q", got)
+		}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			f.t.Errorf("解析推送请求失败: %v", err)
+		}
+	} else {
+		if err := r.ParseForm(); err != nil {
+			f.t.Errorf("解析加密推送请求失败: %v", err)
+		}
+		plain, err := decrypt(f.encryption, r.PostForm.Get("ciphertext"), r.PostForm.Get("iv"))
+		if err != nil {
+			f.t.Errorf("解密失败: %v", err)
+		} else if err := json.Unmarshal(plain, &payload); err != nil {
+			f.t.Errorf("解密后的推送参数不是 JSON: %q", plain)
+		}
+	}
+	if f.requests == nil {
+		f.requests = map[string][]barkPayload{}
+	}
+	f.requests[r.URL.Path] = append(f.requests[r.URL.Path], payload)
+
+	if f.status != 0 {
+		http.Error(w, `{"code":500,"message":"server error"}`, f.status)
+		return
+	}
+	code := f.code
+	if code == 0 {
+		code = http.StatusOK
+	}
+	json.NewEncoder(w).Encode(barkResponse{Code: code, Message: "success"})
+}
+
+// newTestNotifier 创建发送到 fake 的 Bark 通知器
+func newTestNotifier(t *testing.T, f *fakeBark, cfg config.BarkConfig) notifier.Notifier {
+	t.Helper()
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+	cfg.WebhookURL = server.URL + "/"
+	n, err := NewBarkNotifer(cfg, notifier.Options{HTTPClient: server.Client()})
+	if err != nil {
+		t.Fatalf("NewBarkNotifer() = %v", err)
+	}
+	return n
+}
+
+func TestSendPayload(t *testing.T) {
+	archive := true
+	tests := []struct {
+		name string
+		cfg  config.BarkConfig
+		msg  notifier.Message
+		want barkPayload
+	}{
+		{
+			name: "单个设备",
+			cfg:  config.BarkConfig{DeviceToken: "key1", Group: "ssh", Sound: "alarm", IsArchive: &archive},
+			msg:  notifier.Message{EventType: config.EventTypeFailure, Title: "登录失败", Content: "root 登录失败"},
+			want: barkPayload{Title: "登录失败", Body: "root 登录失败", DeviceKey: "key1", Level: "active", Sound: "alarm", Group: "ssh", IsArchive: "1"},
+		},
+		{
+			// 多个设备 Key 合并去重后通过 device_keys 一次发送
+			name: "多个设备",
+			cfg:  config.BarkConfig{DeviceToken: "key1", DeviceTokens: []string{"key2", " key1 ", "", "key3"}},
+			msg:  notifier.Message{EventType: config.EventTypeSuccess, Severity: config.SeverityCritical, Content: "root 登录成功"},
+			want: barkPayload{Body: "root 登录成功", DeviceKeys: []string{"key1", "key2", "key3"}, Level: "critical"},
+		},
+		{
+			name: "事件类型映射优先",
+			cfg: config.BarkConfig{
+				DeviceTokens:   []string{"key1"},
+				Level:          "passive",
+				Levels:         map[string]string{"ban": "timeSensitive"},
+				SeverityLevels: map[string]string{"critical": "active"},
+			},
+			msg:  notifier.Message{EventType: config.EventTypeBan, Severity: config.SeverityCritical, Content: "封禁"},
+			want: barkPayload{Body: "封禁", DeviceKey: "key1", Level: "timeSensitive"},
+		},
+	}
+	for _, tt := range tests {
+		f := &fakeBark{t: t}
+		n := newTestNotifier(t, f, tt.cfg)
+		if err := n.Send(tt.msg); err != nil {
+			t.Errorf("%s: Send() = %v", tt.name, err)
+			continue
+		}
+		want := map[string][]barkPayload{"/push": {tt.want}}
+		if !reflect.DeepEqual(f.requests, want) {
+			t.Errorf("%s: 请求 = %+v, want %+v", tt.name, f.requests, want)
+		}
+	}
+}
+
+func TestSendEncrypted(t *testing.T) {
+	for _, encryption := range []config.BarkEncryptionConfig{
+		{Mode: "CBC", Key: "1234567890123456", IV: "abcdefghijklmnop"},
+		{Key: "12345678901234567890123456789012"},
+		{Mode: "ECB", Key: "123456789012345678901234"},
+	} {
+		f := &fakeBark{t: t, encryption: encryption}
+		n := newTestNotifier(t, f, config.BarkConfig{
+			DeviceTokens: []string{"key1", "key/2"},
+			Group:        "ssh",
+			Encryption:   &encryption,
+		})
+		if err := n.Send(notifier.Message{EventType: config.EventTypeFailure, Title: "登录失败", Content: "root 登录失败"}); err != nil {
+			t.Errorf("%s: Send() = %v", encryption.Mode, err)
+			continue
+		}
+
+		// 加密推送逐个设备发送，设备 Key 在路径中转义
+		payload := barkPayload{Title: "登录失败", Body: "root 登录失败", Level: "active", Group: "ssh"}
+		want := map[string][]barkPayload{"/key1": {payload}, "/key/2": {payload}}
+		if !reflect.DeepEqual(f.requests, want) {
+			t.Errorf("%s: 请求 = %+v, want %+v", encryption.Mode, f.requests, want)
+		}
+	}
+}
+
+func TestSendErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		fake    *fakeBark
+		wantErr string
+	}{
+		{"HTTP 错误", &fakeBark{status: http.StatusInternalServerError}, "status=500"},
+		// HTTP 200 时仍需校验 code 字段
+		{"响应码错误", &fakeBark{code: 400}, "code=400"},
+	}
+	for _, tt := range tests {
+		tt.fake.t = t
+		n := newTestNotifier(t, tt.fake, config.BarkConfig{DeviceToken: "key1"})
+		if err := n.Send(notifier.Message{Content: "test"}); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: Send() = %v, want 包含 %s", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNewBarkNotiferErrors(t *testing.T) {
+	tests := []config.BarkConfig{
+		{},
+		{DeviceTokens: []string{" ", ""}},
+		{DeviceToken: "key1", Level: "loud"},
+		{DeviceToken: "key1", Levels: map[string]string{"fail": "loud"}},
+		{DeviceToken: "key1", SeverityLevels: map[string]string{"critical": "loud"}},
+		{DeviceToken: "key1", Encryption: &config.BarkEncryptionConfig{Key: "short"}},
+	}
+	for _, cfg := range tests {
+		if _, err := NewBarkNotifer(cfg, notifier.Options{}); err == nil {
+			t.Errorf("NewBarkNotifer(%+v) 没有返回错误", cfg)
+		}
+	}
+}
@@ -0,0 +1,99 @@
+package bark
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"loginfopush/config"
+	"math/big"
+	"strings"
+)
+
+// barkCipher Bark 端到端加密，与客户端「推送加密」设置保持一致
+type barkCipher struct {
+	mode  string
+	block cipher.Block
+	iv    string
+}
+
+// newBarkCipher 根据配置创建加密器
+func newBarkCipher(cfg config.BarkEncryptionConfig) (*barkCipher, error) {
+	switch len(cfg.Key) {
+	case 16, 24, 32:
+	default:
+		return nil, fmt.Errorf("密钥长度必须为 16、24 或 32，当前为 %d", len(cfg.Key))
+	}
+
+	block, err := aes.NewCipher([]byte(cfg.Key))
+	if err != nil {
+		return nil, err
+	}
+
+	mode := strings.ToUpper(cfg.Mode)
+	if mode == "" {
+		mode = "CBC"
+	}
+	switch mode {
+	case "CBC":
+		if cfg.IV != "" && len(cfg.IV) != aes.BlockSize {
+			return nil, fmt.Errorf("IV 长度必须为 %d，当前为 %d", aes.BlockSize, len(cfg.IV))
+		}
+	case "ECB":
+	default:
+		return nil, fmt.Errorf("不支持的加密模式: %s", cfg.Mode)
+	}
+
+	return &barkCipher{
+		mode:  mode,
+		block: block,
+		iv:    cfg.IV,
+	}, nil
+}
+
+// encrypt 加密明文，返回 base64 密文和本次使用的 IV（ECB 模式下为空）
+func (c *barkCipher) encrypt(plain []byte) (string, string, error) {
+	data := pkcs7Pad(plain, aes.BlockSize)
+	out := make([]byte, len(data))
+
+	switch c.mode {
+	case "CBC":
+		iv := c.iv
+		if iv == "" {
+			var err error
+			if iv, err = randomIV(); err != nil {
+				return "", "", err
+			}
+		}
+		cipher.NewCBCEncrypter(c.block, []byte(iv)).CryptBlocks(out, data)
+		return base64.StdEncoding.EncodeToString(out), iv, nil
+	default:
+		// 标准库不提供 ECB，逐块加密
+		for i := 0; i < len(data); i += aes.BlockSize {
+			c.block.Encrypt(out[i:i+aes.BlockSize], data[i:i+aes.BlockSize])
+		}
+		return base64.StdEncoding.EncodeToString(out), "", nil
+	}
+}
+
+// pkcs7Pad PKCS7 填充
+func pkcs7Pad(data []byte, blockSize int) []byte {
+	padding := blockSize - len(data)%blockSize
+	return append(data, bytes.Repeat([]byte{byte(padding)}, padding)...)
+}
+
+// randomIV 生成 16 位字母数字 IV，客户端要求 IV 为可见字符
+func randomIV() (string, error) {
+	const chars = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+	buf := make([]byte, aes.BlockSize)
+	for i := range buf {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(chars))))
+		if err != nil {
+			return "", err
+		}
+		buf[i] = chars[n.Int64()]
+	}
+	return string(buf), nil
+}
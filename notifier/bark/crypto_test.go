@@ -0,0 +1,180 @@
+package bark
+
+import (
+	"bytes"
+	"crypto/aes"
+	"crypto/cipher"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"loginfopush/config"
+	"testing"
+)
+
+// unhex 解码十六进制字符串
+func unhex(t *testing.T, s string) string {
+	t.Helper()
+	b, err := hex.DecodeString(s)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(b)
+}
+
+// decrypt 解密 encrypt 的输出并去掉 PKCS7 填充，与 Bark 客户端的处理一致
+func decrypt(cfg config.BarkEncryptionConfig, ciphertext, iv string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	if err != nil {
+		return nil, fmt.Errorf("密文不是 base64: %v", err)
+	}
+	if len(data) == 0 || len(data)%aes.BlockSize != 0 {
+		return nil, fmt.Errorf("密文长度 %d 不是块大小的整数倍", len(data))
+	}
+	block, err := aes.NewCipher([]byte(cfg.Key))
+	if err != nil {
+		return nil, err
+	}
+	plain := make([]byte, len(data))
+	if iv != "" {
+		cipher.NewCBCDecrypter(block, []byte(iv)).CryptBlocks(plain, data)
+	} else {
+		for i := 0; i < len(data); i += aes.BlockSize {
+			block.Decrypt(plain[i:i+aes.BlockSize], data[i:i+aes.BlockSize])
+		}
+	}
+	padding := int(plain[len(plain)-1])
+	if padding < 1 || padding > aes.BlockSize || !bytes.Equal(plain[len(plain)-padding:], bytes.Repeat([]byte{byte(padding)}, padding)) {
+		return nil, fmt.Errorf("PKCS7 填充错误: % x", plain[len(plain)-aes.BlockSize:])
+	}
+	return plain[:len(plain)-padding], nil
+}
+
+func TestEncryptKnownAnswer(t *testing.T) {
+	// NIST SP 800-38A F.1 与 F.2 的第一个分组，完整分组的明文之后多一个填充分组
+	plain := unhex(t, "6bc1bee22e409f96e93d7e117393172a")
+	iv := unhex(t, "000102030405060708090a0b0c0d0e0f")
+	key128 := unhex(t, "2b7e151628aed2a6abf7158809cf4f3c")
+	key256 := unhex(t, "603deb1015ca71be2b73aef0857d77811f352c073b6108d72d9810a30914dff4")
+
+	tests := []struct {
+		name string
+		cfg  config.BarkEncryptionConfig
+		want string // 第一个分组的密文
+	}{
+		{"AES128-CBC", config.BarkEncryptionConfig{Mode: "CBC", Key: key128, IV: iv}, "7649abac8119b246cee98e9b12e9197d"},
+		{"AES256-CBC", config.BarkEncryptionConfig{Mode: "cbc", Key: key256, IV: iv}, "f58c4c04d6e5f1ba779eabfb5f7bfbd6"},
+		{"AES128-ECB", config.BarkEncryptionConfig{Mode: "ECB", Key: key128}, "3ad77bb40d7a3660a89ecaf32466ef97"},
+		{"AES256-ECB", config.BarkEncryptionConfig{Mode: "ecb", Key: key256}, "f3eed1bdb5d2a03c064b5a7e3db181f8"},
+	}
+	for _, tt := range tests {
+		c, err := newBarkCipher(tt.cfg)
+		if err != nil {
+			t.Errorf("%s: newBarkCipher() = %v", tt.name, err)
+			continue
+		}
+		ciphertext, gotIV, err := c.encrypt([]byte(plain))
+		if err != nil {
+			t.Errorf("%s: encrypt() = %v", tt.name, err)
+			continue
+		}
+		if gotIV != tt.cfg.IV {
+			t.Errorf("%s: IV = %q, want %q", tt.name, gotIV, tt.cfg.IV)
+		}
+		data, _ := base64.StdEncoding.DecodeString(ciphertext)
+		if len(data) != 2*aes.BlockSize {
+			t.Errorf("%s: 密文长度 = %d, want %d", tt.name, len(data), 2*aes.BlockSize)
+			continue
+		}
+		if got := hex.EncodeToString(data[:aes.BlockSize]); got != tt.want {
+			t.Errorf("%s: 密文 = %s, want %s", tt.name, got, tt.want)
+		}
+		if got, err := decrypt(tt.cfg, ciphertext, gotIV); err != nil || string(got) != plain {
+			t.Errorf("%s: 解密结果 = %x %v", tt.name, got, err)
+		}
+	}
+}
+
+func TestEncryptRandomIV(t *testing.T) {
+	cfg := config.BarkEncryptionConfig{Key: "1234567890123456"}
+	c, err := newBarkCipher(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	plain := `{"title":"登录失败","body":"root 登录失败"}`
+	seen := map[string]bool{}
+	for i := 0; i < 3; i++ {
+		ciphertext, iv, err := c.encrypt([]byte(plain))
+		if err != nil {
+			t.Fatal(err)
+		}
+		// 未配置 IV 时默认使用 CBC，每条消息随机生成字母数字 IV
+		if len(iv) != aes.BlockSize {
+			t.Errorf("IV = %q, 长度应为 %d", iv, aes.BlockSize)
+		}
+		for _, r := range iv {
+			if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9') {
+				t.Errorf("IV %q 包含非字母数字字符", iv)
+				break
+			}
+		}
+		if seen[iv] {
+			t.Errorf("IV %q 重复", iv)
+		}
+		seen[iv] = true
+		if got, err := decrypt(cfg, ciphertext, iv); err != nil || string(got) != plain {
+			t.Errorf("解密结果 = %q %v, want %q", got, err, plain)
+		}
+	}
+}
+
+func TestPKCS7Pad(t *testing.T) {
+	tests := []struct {
+		size    int
+		padding int
+	}{
+		{0, 16},
+		{1, 15},
+		{15, 1},
+		{16, 16},
+		{17, 15},
+	}
+	for _, tt := range tests {
+		data := bytes.Repeat([]byte{'x'}, tt.size)
+		got := pkcs7Pad(data, aes.BlockSize)
+		want := append(bytes.Repeat([]byte{'x'}, tt.size), bytes.Repeat([]byte{byte(tt.padding)}, tt.padding)...)
+		if !bytes.Equal(got, want) {
+			t.Errorf("pkcs7Pad(%d 字节) = % x, want % x", tt.size, got, want)
+		}
+	}
+}
+
+func TestNewBarkCipherErrors(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.BarkEncryptionConfig
+	}{
+		{"空密钥", config.BarkEncryptionConfig{}},
+		{"密钥过短", config.BarkEncryptionConfig{Key: "123456789012345"}},
+		{"密钥长度 20", config.BarkEncryptionConfig{Key: "12345678901234567890"}},
+		{"密钥过长", config.BarkEncryptionConfig{Key: "123456789012345678901234567890123"}},
+		{"IV 过短", config.BarkEncryptionConfig{Key: "1234567890123456", IV: "12345678"}},
+		{"IV 过长", config.BarkEncryptionConfig{Mode: "CBC", Key: "1234567890123456", IV: "12345678901234567"}},
+		{"不支持的模式", config.BarkEncryptionConfig{Mode: "GCM", Key: "1234567890123456"}},
+	}
+	for _, tt := range tests {
+		if _, err := newBarkCipher(tt.cfg); err == nil {
+			t.Errorf("%s: newBarkCipher(%+v) 没有返回错误", tt.name, tt.cfg)
+		}
+	}
+
+	// 24 位密钥为 AES192；ECB 模式忽略 IV
+	for _, cfg := range []config.BarkEncryptionConfig{
+		{Key: "123456789012345678901234"},
+		{Mode: "ECB", Key: "1234567890123456", IV: "short"},
+	} {
+		if _, err := newBarkCipher(cfg); err != nil {
+			t.Errorf("newBarkCipher(%+v) = %v", cfg, err)
+		}
+	}
+}
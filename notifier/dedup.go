@@ -0,0 +1,168 @@
+package notifier
+
+import (
+	"fmt"
+	"loginfopush/config"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// defaultDedupWindow 默认抑制窗口
+	defaultDedupWindow = 60 * time.Second
+)
+
+// defaultDedupKeys 默认去重字段
+var defaultDedupKeys = []string{"type", "ip", "user"}
+
+// dedupEntry 抑制窗口内的事件记录
+type dedupEntry struct {
+	eventConfig config.EventConfig
+	notifiers   []string // 最近一条事件解析出的通知渠道（路由规则或按严重程度覆盖），汇总发送到这些渠道
+	first       time.Time
+	last        map[string]interface{}
+	suppressed  int
+}
+
+// deduper 在窗口期内抑制相同的事件，窗口结束时回调汇总
+type deduper struct {
+	window    time.Duration
+	keys      []string
+	summary   bool
+	onSummary func(entry *dedupEntry, now time.Time)
+	manual    bool // 不启动计时器，窗口由 expireBefore 结束
+
+	mu      sync.Mutex
+	entries map[string]*dedupEntry
+}
+
+// newDeduper 根据配置创建去重器，未启用时返回 nil
+func newDeduper(cfg *config.DedupConfig, onSummary func(entry *dedupEntry, now time.Time)) *deduper {
+	if cfg == nil || !cfg.Enabled {
+		return nil
+	}
+
+	window := cfg.Window.Std()
+	if window <= 0 {
+		window = defaultDedupWindow
+	}
+
+	keys := cfg.Keys
+	if len(keys) == 0 {
+		keys = defaultDedupKeys
+	}
+
+	return &deduper{
+		window:    window,
+		keys:      keys,
+		summary:   cfg.Summary == nil || *cfg.Summary,
+		onSummary: onSummary,
+		entries:   make(map[string]*dedupEntry),
+	}
+}
+
+// Allow 判断事件是否需要发送，窗口内的重复事件返回 false，names 为事件的通知渠道，now 为事件发生时间
+func (d *deduper) Allow(eventConfig config.EventConfig, names []string, data map[string]interface{}, now time.Time) bool {
+	if d == nil {
+		return true
+	}
+
+	key := d.key(data)
+
+	d.mu.Lock()
+	if entry, ok := d.entries[key]; ok {
+		if now.Sub(entry.first) < d.window {
+			entry.suppressed++
+			entry.last = data
+			entry.notifiers = names
+			d.mu.Unlock()
+			return false
+		}
+		// 按事件时间窗口已经结束，回放日志时计时器还未触发
+		delete(d.entries, key)
+		d.mu.Unlock()
+		d.summarize(entry, entry.first.Add(d.window))
+		d.mu.Lock()
+	}
+
+	entry := &dedupEntry{
+		eventConfig: eventConfig,
+		notifiers:   names,
+		first:       now,
+		last:        data,
+	}
+	d.entries[key] = entry
+	d.mu.Unlock()
+
+	if !d.manual {
+		time.AfterFunc(d.window, func() { d.expire(key, entry) })
+	}
+	return true
+}
+
+// expire 窗口结束，移除记录并在有被抑制的事件时发送汇总
+func (d *deduper) expire(key string, entry *dedupEntry) {
+	d.mu.Lock()
+	if d.entries[key] != entry {
+		// 已被新的窗口取代
+		d.mu.Unlock()
+		return
+	}
+	delete(d.entries, key)
+	d.mu.Unlock()
+
+	d.summarize(entry, time.Now())
+}
+
+// expireBefore 结束在 now 之前到期的窗口，按窗口开始时间依次发送汇总，now 为零值时结束所有窗口
+func (d *deduper) expireBefore(now time.Time) {
+	if d == nil {
+		return
+	}
+
+	var expired []*dedupEntry
+	d.mu.Lock()
+	for key, entry := range d.entries {
+		if now.IsZero() || !now.Before(entry.first.Add(d.window)) {
+			expired = append(expired, entry)
+			delete(d.entries, key)
+		}
+	}
+	d.mu.Unlock()
+
+	sort.Slice(expired, func(i, j int) bool { return expired[i].first.Before(expired[j].first) })
+	for _, entry := range expired {
+		d.summarize(entry, entry.first.Add(d.window))
+	}
+}
+
+// summarize 有被抑制的事件时发送汇总，now 为窗口结束的时间
+func (d *deduper) summarize(entry *dedupEntry, now time.Time) {
+	if entry.suppressed > 0 && d.summary && d.onSummary != nil {
+		d.onSummary(entry, now)
+	}
+}
+
+// key 根据配置的字段生成去重键
+func (d *deduper) key(data map[string]interface{}) string {
+	parts := make([]string, len(d.keys))
+	for i, field := range d.keys {
+		parts[i] = fmt.Sprintf("%s=%v", field, lookupField(data, field))
+	}
+	return strings.Join(parts, "|")
+}
+
+// lookupField 忽略大小写查找事件字段
+func lookupField(data map[string]interface{}, field string) interface{} {
+	if v, ok := data[field]; ok {
+		return v
+	}
+	for k, v := range data {
+		if strings.EqualFold(k, field) {
+			return v
+		}
+	}
+	return nil
+}
@@ -0,0 +1,279 @@
+package notifier
+
+import (
+	"loginfopush/config"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// recorder 记录发送到各通知渠道的消息
+type recorder struct {
+	mu   sync.Mutex
+	sent []string // "渠道 内容"，抑制汇总的内容记为 "汇总"
+}
+
+func (r *recorder) create(name string, _ config.NotifierConfig) (Notifier, error) {
+	return dryRunNotifier{name: name, print: r.record}, nil
+}
+
+func (r *recorder) record(name string, msg Message) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	content := msg.Content
+	if strings.Contains(content, "条相似事件已被抑制") {
+		content = "汇总"
+	}
+	r.sent = append(r.sent, name+" "+content)
+}
+
+// take 返回并清空已发送的消息
+func (r *recorder) take() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	sent := r.sent
+	r.sent = nil
+	return sent
+}
+
+// newTestManager 创建消息由 recorder 记录的通知管理器
+func newTestManager(t *testing.T, cfg *config.Config) (*NotifierManager, *recorder) {
+	t.Helper()
+	r := &recorder{}
+	m, err := newManager(cfg, r.create)
+	if err != nil {
+		t.Fatalf("newManager() = %v", err)
+	}
+	t.Cleanup(m.Close)
+	return m, r
+}
+
+// testNotifiers 测试使用的通知渠道
+func testNotifiers(names ...string) map[string]config.NotifierConfig {
+	notifiers := make(map[string]config.NotifierConfig)
+	for _, name := range names {
+		notifiers[name] = config.NotifierConfig{Type: config.NotifierTypeTelegram, Enabled: true}
+	}
+	return notifiers
+}
+
+// failEvent 测试使用的 fail 事件数据
+func failEvent(ip, user string, severity config.Severity) map[string]interface{} {
+	return map[string]interface{}{"IP": ip, "User": user, "Severity": string(severity)}
+}
+
+func TestDeduperWindow(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local)
+	a := failEvent("203.0.113.7", "root", config.SeverityWarning)
+	b := failEvent("203.0.113.8", "root", config.SeverityWarning)
+	c := failEvent("203.0.113.7", "admin", config.SeverityWarning)
+
+	tests := []struct {
+		name      string
+		keys      []string
+		summary   bool
+		events    []map[string]interface{}
+		offsets   []time.Duration // 事件相对 start 的时间
+		allowed   []bool
+		summaries []string // "抑制次数@窗口结束时间"
+	}{
+		{
+			name:      "窗口内的重复事件",
+			summary:   true,
+			events:    []map[string]interface{}{a, a, b, a},
+			offsets:   []time.Duration{0, 10 * time.Second, 20 * time.Second, 59 * time.Second},
+			allowed:   []bool{true, false, true, false},
+			summaries: []string{"2@10:01:00"},
+		},
+		{
+			name:      "窗口结束后重新发送",
+			summary:   true,
+			events:    []map[string]interface{}{a, a, a, a},
+			offsets:   []time.Duration{0, 30 * time.Second, time.Minute, 90 * time.Second},
+			allowed:   []bool{true, false, true, false},
+			summaries: []string{"1@10:01:00", "1@10:02:00"},
+		},
+		{
+			name:      "默认按类型、IP 与用户去重",
+			summary:   true,
+			events:    []map[string]interface{}{a, c},
+			offsets:   []time.Duration{0, time.Second},
+			allowed:   []bool{true, true},
+			summaries: nil,
+		},
+		{
+			name:      "只按 IP 去重",
+			keys:      []string{"ip"},
+			summary:   true,
+			events:    []map[string]interface{}{a, c},
+			offsets:   []time.Duration{0, time.Second},
+			allowed:   []bool{true, false},
+			summaries: []string{"1@10:01:00"},
+		},
+		{
+			name:    "不发送汇总",
+			events:  []map[string]interface{}{a, a},
+			offsets: []time.Duration{0, time.Second},
+			allowed: []bool{true, false},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var summaries []string
+			summary := tt.summary
+			d := newDeduper(&config.DedupConfig{
+				Enabled: true,
+				Window:  config.Duration(time.Minute),
+				Keys:    tt.keys,
+				Summary: &summary,
+			}, func(entry *dedupEntry, now time.Time) {
+				summaries = append(summaries, strconv.Itoa(entry.suppressed)+"@"+now.Format("15:04:05"))
+			})
+			d.manual = true
+
+			evt := config.EventConfig{Type: config.EventTypeFailure}
+			for i, data := range tt.events {
+				data = copyData(data)
+				data["Type"] = "fail"
+				if got := d.Allow(evt, nil, data, start.Add(tt.offsets[i])); got != tt.allowed[i] {
+					t.Errorf("Allow(#%d) = %v, want %v", i, got, tt.allowed[i])
+				}
+			}
+			d.expireBefore(time.Time{})
+			if !reflect.DeepEqual(summaries, tt.summaries) {
+				t.Errorf("summaries = %v, want %v", summaries, tt.summaries)
+			}
+		})
+	}
+}
+
+func TestDeduperExpireBefore(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local)
+	var summaries []string
+	d := newDeduper(&config.DedupConfig{Enabled: true, Window: config.Duration(time.Minute)}, func(entry *dedupEntry, now time.Time) {
+		summaries = append(summaries, stringValue(entry.last, "IP")+"@"+now.Format("15:04:05"))
+	})
+	d.manual = true
+
+	evt := config.EventConfig{Type: config.EventTypeFailure}
+	for i, ip := range []string{"203.0.113.8", "203.0.113.7"} {
+		at := start.Add(time.Duration(i) * 30 * time.Second)
+		d.Allow(evt, nil, failEvent(ip, "root", ""), at)
+		d.Allow(evt, nil, failEvent(ip, "root", ""), at.Add(time.Second))
+	}
+
+	// 只结束已经到期的窗口
+	d.expireBefore(start.Add(70 * time.Second))
+	if want := []string{"203.0.113.8@10:01:00"}; !reflect.DeepEqual(summaries, want) {
+		t.Errorf("summaries = %v, want %v", summaries, want)
+	}
+	d.expireBefore(time.Time{})
+	if want := []string{"203.0.113.8@10:01:00", "203.0.113.7@10:01:30"}; !reflect.DeepEqual(summaries, want) {
+		t.Errorf("summaries = %v, want %v", summaries, want)
+	}
+}
+
+func TestDedupSummaryNotifiers(t *testing.T) {
+	tests := []struct {
+		name string
+		data map[string]interface{}
+		want []string
+	}{
+		{
+			name: "事件的通知渠道",
+			data: failEvent("203.0.113.7", "root", config.SeverityWarning),
+			want: []string{"phone 203.0.113.7", "phone 汇总"},
+		},
+		{
+			name: "按严重程度覆盖的通知渠道",
+			data: failEvent("203.0.113.7", "root", config.SeverityCritical),
+			want: []string{"pager 203.0.113.7", "pager 汇总"},
+		},
+		{
+			name: "路由规则覆盖的通知渠道",
+			data: failEvent("203.0.113.7", "admin", config.SeverityCritical),
+			want: []string{"audit 203.0.113.7", "audit 汇总"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, r := newTestManager(t, &config.Config{
+				Notifiers: testNotifiers("phone", "pager", "audit"),
+				Dedup:     &config.DedupConfig{Enabled: true},
+				Rules:     []config.RuleConfig{{When: `user == "admin"`, Notifiers: []string{"audit"}}},
+				Events: map[string]config.EventConfig{
+					"fail": {
+						Type:              config.EventTypeFailure,
+						Enabled:           true,
+						Template:          "{{.IP}}",
+						Notifiers:         []string{"phone"},
+						SeverityNotifiers: map[config.Severity][]string{config.SeverityCritical: {"pager"}},
+					},
+				},
+			})
+			m.EnableReplay()
+
+			start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local)
+			for i := 0; i < 2; i++ {
+				if _, err := m.SendEventAt(config.EventTypeFailure, copyData(tt.data), start.Add(time.Duration(i)*time.Second)); err != nil {
+					t.Fatal(err)
+				}
+			}
+			m.FlushDedup()
+			if got := r.take(); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("sent = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestDedupAfterMinSeverity(t *testing.T) {
+	m, r := newTestManager(t, &config.Config{
+		Notifiers: testNotifiers("phone"),
+		Dedup:     &config.DedupConfig{Enabled: true},
+		Events: map[string]config.EventConfig{
+			"fail": {
+				Type:        config.EventTypeFailure,
+				Enabled:     true,
+				Template:    "{{.IP}} {{.Severity}}",
+				Notifiers:   []string{"phone"},
+				MinSeverity: config.SeverityWarning,
+			},
+		},
+	})
+	m.EnableReplay()
+
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local)
+	steps := []struct {
+		severity config.Severity
+		status   string
+	}{
+		{config.SeverityInfo, StatusFiltered},
+		// 被过滤的事件没有开启抑制窗口
+		{config.SeverityWarning, StatusSent},
+		{config.SeverityInfo, StatusFiltered},
+		{config.SeverityCritical, StatusSuppressed},
+	}
+	for i, step := range steps {
+		result, err := m.SendEventAt(config.EventTypeFailure, failEvent("203.0.113.7", "root", step.severity), start.Add(time.Duration(i)*time.Second))
+		if err != nil || result.Status != step.status {
+			t.Errorf("SendEventAt(%s) = %s %v, want %s", step.severity, result.Status, err, step.status)
+		}
+	}
+	m.FlushDedup()
+	if got, want := r.take(), []string{"phone 203.0.113.7 warning", "phone 汇总"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("sent = %q, want %q", got, want)
+	}
+}
+
+// copyData 复制事件数据，发送时会写入 Type 等字段
+func copyData(data map[string]interface{}) map[string]interface{} {
+	c := make(map[string]interface{}, len(data))
+	for k, v := range data {
+		c[k] = v
+	}
+	return c
+}
@@ -0,0 +1,199 @@
+package notifier
+
+import (
+	"loginfopush/config"
+	"loginfopush/schedule"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	defaultDigestSchedule  = "@hourly"
+	defaultDigestMaxEvents = 100
+	defaultDigestTopN      = 5
+)
+
+// DefaultDigestTemplate 内置汇总模板
+const DefaultDigestTemplate = `📊 服务器: {{.Server.Name}} ({{.Server.Tag}})
+时间: {{.Start}} ~ {{.End}}
+共 {{.Count}} 条 {{.Type}} 事件{{if .TopIPs}}
+Top IP:{{range .TopIPs}}
+  {{.Name}} × {{.Count}}{{end}}{{end}}{{if .TopCountries}}
+Top 地区:{{range .TopCountries}}
+  {{.Name}} × {{.Count}}{{end}}{{end}}{{if .TopUsers}}
+Top 用户:{{range .TopUsers}}
+  {{.Name}} × {{.Count}}{{end}}{{end}}`
+
+// CountItem 排行榜条目
+type CountItem struct {
+	Name  string // 名称
+	Count int    // 次数
+}
+
+// DigestData 汇总模板数据
+type DigestData struct {
+	Server       config.ServerConfig // 服务器信息
+	Type         config.EventType    // 事件类型
+	Title        string              // 事件标题
+	Start        string              // 统计开始时间
+	End          string              // 统计结束时间
+	Count        int                 // 事件总数
+	Events       []TemplateData      // 事件明细（最多 max_events 条）
+	TopIPs       []CountItem         // 出现最多的 IP
+	TopCountries []CountItem         // 出现最多的国家/地区
+	TopUsers     []CountItem         // 出现最多的用户
+}
+
+// digestBuffer 缓存待汇总的事件
+type digestBuffer struct {
+	eventConfig config.EventConfig
+	sched       schedule.Schedule
+	maxEvents   int
+	topN        int
+
+	mu        sync.Mutex
+	manual    bool      // 回放模式，不按实际时间发送，由 expire 按事件时间推进
+	started   bool      // 回放时是否已开始第一个统计周期
+	due       time.Time // 回放时下一次发送的时间
+	start     time.Time
+	count     int
+	events    []TemplateData
+	ips       map[string]int
+	countries map[string]int
+	users     map[string]int
+}
+
+// newDigestBuffer 创建汇总缓存，sched 为发送计划
+func newDigestBuffer(eventConfig config.EventConfig, sched schedule.Schedule) *digestBuffer {
+	b := &digestBuffer{
+		eventConfig: eventConfig,
+		sched:       sched,
+		maxEvents:   eventConfig.Digest.MaxEvents,
+		topN:        eventConfig.Digest.TopN,
+	}
+	if b.maxEvents <= 0 {
+		b.maxEvents = defaultDigestMaxEvents
+	}
+	if b.topN <= 0 {
+		b.topN = defaultDigestTopN
+	}
+	b.reset(time.Now())
+	return b
+}
+
+// reset 清空缓存并开始新的统计周期
+func (b *digestBuffer) reset(start time.Time) {
+	b.start = start
+	b.count = 0
+	b.events = nil
+	b.ips = make(map[string]int)
+	b.countries = make(map[string]int)
+	b.users = make(map[string]int)
+}
+
+// Add 记录一条事件
+func (b *digestBuffer) Add(item TemplateData) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.count++
+	if len(b.events) < b.maxEvents {
+		b.events = append(b.events, item)
+	}
+	if item.IP != "" {
+		b.ips[item.IP]++
+	}
+	if country := countryOf(item); country != "" {
+		b.countries[country]++
+	}
+	if item.User != "" {
+		b.users[item.User]++
+	}
+}
+
+// isManual 是否为回放模式
+func (b *digestBuffer) isManual() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.manual
+}
+
+// expire 回放时按事件时间推进发送计划，返回 now 之前到期的第一个与最后一个发送时间；
+// 第一次调用时以 now 开始统计周期
+func (b *digestBuffer) expire(now time.Time) (first, last time.Time, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !b.started {
+		b.started = true
+		b.reset(now)
+		b.due = b.sched.Next(now)
+		return time.Time{}, time.Time{}, false
+	}
+	if b.due.IsZero() || now.Before(b.due) {
+		return time.Time{}, time.Time{}, false
+	}
+	first = b.due
+	for !b.due.IsZero() && !now.Before(b.due) {
+		last = b.due
+		b.due = b.sched.Next(b.due)
+	}
+	return first, last, true
+}
+
+// Flush 取出当前周期的汇总数据并开始新周期，没有事件时返回 false
+func (b *digestBuffer) Flush(server config.ServerConfig, now time.Time) (DigestData, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.count == 0 {
+		b.reset(now)
+		return DigestData{}, false
+	}
+
+	data := DigestData{
+		Server:       server,
+		Type:         b.eventConfig.Type,
+		Title:        b.eventConfig.Title,
+		Start:        b.start.Format("2006-01-02 15:04:05"),
+		End:          now.Format("2006-01-02 15:04:05"),
+		Count:        b.count,
+		Events:       b.events,
+		TopIPs:       TopN(b.ips, b.topN),
+		TopCountries: TopN(b.countries, b.topN),
+		TopUsers:     TopN(b.users, b.topN),
+	}
+	b.reset(now)
+	return data, true
+}
+
+// countryOf 获取事件的国家/地区，位置格式为 "国家-城市"
+func countryOf(item TemplateData) string {
+	if country := stringValue(item.Extra, "Country"); country != "" {
+		return country
+	}
+	if item.Location == "" || item.Location == "未知位置" || item.Location == "未查询" {
+		return ""
+	}
+	return strings.SplitN(item.Location, "-", 2)[0]
+}
+
+// TopN 按次数降序取前 n 项，次数相同时按名称排序
+func TopN(counts map[string]int, n int) []CountItem {
+	items := make([]CountItem, 0, len(counts))
+	for name, count := range counts {
+		items = append(items, CountItem{Name: name, Count: count})
+	}
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].Count != items[j].Count {
+			return items[i].Count > items[j].Count
+		}
+		return items[i].Name < items[j].Name
+	})
+	if n > 0 && len(items) > n {
+		items = items[:n]
+	}
+	return items
+}
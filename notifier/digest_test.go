@@ -0,0 +1,249 @@
+package notifier
+
+import (
+	"loginfopush/config"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestDigestBuffer(t *testing.T) {
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local)
+	events := []TemplateData{
+		{IP: "203.0.113.7", User: "root", Location: "中国-北京"},
+		{IP: "203.0.113.7", User: "admin", Location: "中国-上海"},
+		{IP: "198.51.100.2", User: "root", Location: "未知位置"},
+		{IP: "198.51.100.2", Location: "未查询"},
+		{IP: "192.0.2.1", User: "root", Extra: map[string]interface{}{"Country": "美国"}},
+		{User: "git"},
+	}
+
+	tests := []struct {
+		name      string
+		digest    config.DigestConfig
+		events    []TemplateData
+		count     int
+		kept      int
+		ips       []CountItem
+		countries []CountItem
+		users     []CountItem
+	}{
+		{
+			name:      "统计排行",
+			events:    events,
+			count:     6,
+			kept:      6,
+			ips:       []CountItem{{"198.51.100.2", 2}, {"203.0.113.7", 2}, {"192.0.2.1", 1}},
+			countries: []CountItem{{"中国", 2}, {"美国", 1}},
+			users:     []CountItem{{"root", 3}, {"admin", 1}, {"git", 1}},
+		},
+		{
+			// 明细最多保留 max_events 条，统计包含全部事件
+			name:      "限制明细与排行数量",
+			digest:    config.DigestConfig{MaxEvents: 2, TopN: 1},
+			events:    events,
+			count:     6,
+			kept:      2,
+			ips:       []CountItem{{"198.51.100.2", 2}},
+			countries: []CountItem{{"中国", 2}},
+			users:     []CountItem{{"root", 3}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			digest := tt.digest
+			b := newDigestBuffer(config.EventConfig{Type: config.EventTypeFailure, Title: "登录失败", Digest: &digest}, nil)
+			b.reset(start)
+			for _, item := range tt.events {
+				b.Add(item)
+			}
+
+			end := start.Add(time.Hour)
+			data, ok := b.Flush(config.ServerConfig{Name: "web"}, end)
+			if !ok {
+				t.Fatal("Flush() = false")
+			}
+			if data.Count != tt.count || len(data.Events) != tt.kept {
+				t.Errorf("Count = %d, Events = %d, want %d %d", data.Count, len(data.Events), tt.count, tt.kept)
+			}
+			if data.Type != config.EventTypeFailure || data.Title != "登录失败" || data.Server.Name != "web" {
+				t.Errorf("data = %+v", data)
+			}
+			if data.Start != "2024-01-01 10:00:00" || data.End != "2024-01-01 11:00:00" {
+				t.Errorf("时间范围 = %s ~ %s", data.Start, data.End)
+			}
+			if !reflect.DeepEqual(data.TopIPs, tt.ips) {
+				t.Errorf("TopIPs = %v, want %v", data.TopIPs, tt.ips)
+			}
+			if !reflect.DeepEqual(data.TopCountries, tt.countries) {
+				t.Errorf("TopCountries = %v, want %v", data.TopCountries, tt.countries)
+			}
+			if !reflect.DeepEqual(data.TopUsers, tt.users) {
+				t.Errorf("TopUsers = %v, want %v", data.TopUsers, tt.users)
+			}
+
+			// 下一个周期从上次发送的时间开始，且不包含已发送的事件
+			if _, ok := b.Flush(config.ServerConfig{}, end.Add(time.Hour)); ok {
+				t.Error("空周期 Flush() = true")
+			}
+			b.Add(events[0])
+			data, _ = b.Flush(config.ServerConfig{}, end.Add(2*time.Hour))
+			if data.Count != 1 || data.Start != "2024-01-01 12:00:00" {
+				t.Errorf("下一周期 Count = %d, Start = %s", data.Count, data.Start)
+			}
+		})
+	}
+}
+
+func TestTopN(t *testing.T) {
+	counts := map[string]int{"b": 2, "a": 2, "c": 5, "d": 1}
+	tests := []struct {
+		n    int
+		want []CountItem
+	}{
+		{2, []CountItem{{"c", 5}, {"a", 2}}},
+		{0, []CountItem{{"c", 5}, {"a", 2}, {"b", 2}, {"d", 1}}},
+		{10, []CountItem{{"c", 5}, {"a", 2}, {"b", 2}, {"d", 1}}},
+	}
+	for _, tt := range tests {
+		if got := TopN(counts, tt.n); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("TopN(%d) = %v, want %v", tt.n, got, tt.want)
+		}
+	}
+}
+
+func TestFlushDigest(t *testing.T) {
+	m, r := newTestManager(t, &config.Config{
+		Notifiers: testNotifiers("phone", "mail"),
+		Events: map[string]config.EventConfig{
+			"fail": {
+				Type:      config.EventTypeFailure,
+				Enabled:   true,
+				Template:  "{{.IP}}",
+				Notifiers: []string{"phone"},
+				Digest: &config.DigestConfig{
+					Enabled:  true,
+					Schedule: "0 0 1 1 *",
+					Template: "{{.Count}} 次失败: {{range .TopIPs}}{{.Name}}×{{.Count}} {{end}}",
+				},
+			},
+		},
+	})
+
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local)
+	for i, ip := range []string{"203.0.113.7", "203.0.113.8", "203.0.113.7"} {
+		result, err := m.SendEventAt(config.EventTypeFailure, failEvent(ip, "root", ""), start.Add(time.Duration(i)*time.Minute))
+		if err != nil || result.Status != StatusDigest {
+			t.Errorf("SendEventAt(%s) = %s %v, want digest", ip, result.Status, err)
+		}
+	}
+	// 汇总模式下事件不单独发送
+	if got := r.take(); len(got) != 0 {
+		t.Errorf("汇总前发送了 %q", got)
+	}
+
+	buf := m.digests[config.EventTypeFailure]
+	m.flushDigest(buf, start.Add(time.Hour))
+	want := []string{"phone 3 次失败: 203.0.113.7×2 203.0.113.8×1 "}
+	if got := r.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("sent = %q, want %q", got, want)
+	}
+
+	// 没有新事件时不发送
+	m.flushDigest(buf, start.Add(2*time.Hour))
+	if got := r.take(); len(got) != 0 {
+		t.Errorf("空周期发送了 %q", got)
+	}
+}
+
+func TestDefaultDigestTemplate(t *testing.T) {
+	content, err := RenderTemplate(DefaultDigestTemplate, DigestData{
+		Server: config.ServerConfig{Name: "web", Tag: "prod"},
+		Type:   config.EventTypeFailure,
+		Count:  3,
+		TopIPs: []CountItem{{"203.0.113.7", 3}},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range []string{"web (prod)", "共 3 条 fail 事件", "203.0.113.7 × 3"} {
+		if !strings.Contains(content, s) {
+			t.Errorf("汇总内容缺少 %q:\n%s", s, content)
+		}
+	}
+	if strings.Contains(content, "Top 用户") {
+		t.Errorf("没有用户时不应输出用户排行:\n%s", content)
+	}
+}
+
+func TestReplayDigests(t *testing.T) {
+	m, r := newTestManager(t, &config.Config{
+		Notifiers: testNotifiers("phone"),
+		Events: map[string]config.EventConfig{
+			"fail": {
+				Type:      config.EventTypeFailure,
+				Enabled:   true,
+				Template:  "{{.IP}}",
+				Notifiers: []string{"phone"},
+				Digest: &config.DigestConfig{
+					Enabled:  true,
+					Template: "{{.Start}} ~ {{.End}} {{.Count}}",
+				},
+			},
+		},
+	})
+	m.EnableReplay()
+
+	// 按日志时间发送汇总：前两个事件在 11:00 汇总，13:20 的事件属于 13:00 开始的周期，在文件末尾汇总
+	start := time.Date(2024, 1, 1, 10, 20, 0, 0, time.Local)
+	for _, at := range []time.Time{start, start.Add(30 * time.Minute), start.Add(3 * time.Hour)} {
+		m.ExpireDigests(at)
+		m.SendEventAt(config.EventTypeFailure, failEvent("203.0.113.7", "root", ""), at)
+	}
+	want := []string{"phone 2024-01-01 10:20:00 ~ 2024-01-01 11:00:00 2"}
+	if got := r.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("sent = %q, want %q", got, want)
+	}
+
+	m.FlushDigests(start.Add(3*time.Hour + time.Minute))
+	want = []string{"phone 2024-01-01 13:00:00 ~ 2024-01-01 13:21:00 1"}
+	if got := r.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("FlushDigests() sent = %q, want %q", got, want)
+	}
+}
+
+func TestCloseFlushesPending(t *testing.T) {
+	r := &recorder{}
+	m, err := newManager(&config.Config{
+		Notifiers: testNotifiers("phone"),
+		Dedup:     &config.DedupConfig{Enabled: true, Window: config.Duration(time.Hour)},
+		Events: map[string]config.EventConfig{
+			"fail": {
+				Type:      config.EventTypeFailure,
+				Enabled:   true,
+				Template:  "{{.IP}}",
+				Notifiers: []string{"phone"},
+				Digest:    &config.DigestConfig{Enabled: true, Template: "{{.Count}} 次失败"},
+			},
+			"success": {Type: config.EventTypeSuccess, Enabled: true, Template: "{{.User}}", Notifiers: []string{"phone"}},
+		},
+	}, r.create)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	now := time.Now()
+	m.SendEventAt(config.EventTypeFailure, failEvent("203.0.113.7", "root", ""), now)
+	m.SendEventAt(config.EventTypeSuccess, failEvent("203.0.113.7", "root", ""), now)
+	m.SendEventAt(config.EventTypeSuccess, failEvent("203.0.113.7", "root", ""), now)
+	if got, want := r.take(), []string{"phone root"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("sent = %q, want %q", got, want)
+	}
+
+	// 退出时发送汇总缓存中的事件与未结束的抑制窗口的汇总
+	m.Close()
+	if got, want := r.take(), []string{"phone 1 次失败", "phone 汇总"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Close() 后 sent = %q, want %q", got, want)
+	}
+}
@@ -0,0 +1,197 @@
+package exec
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"loginfopush/config"
+	"loginfopush/notifier"
+	"os"
+	osexec "os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+func init() {
+	notifier.RegisterNotifier(config.NotifierTypeExec, NewExecNotifier)
+}
+
+// 默认值
+const (
+	defaultTimeout     = 30 * time.Second
+	defaultConcurrency = 4
+	maxStderr          = 512 // 错误信息中保留的标准错误输出长度
+)
+
+// envPrefix 事件字段环境变量的前缀
+const envPrefix = "LOGINFOPUSH_"
+
+// ExecNotifier 执行外部命令的通知器
+type ExecNotifier struct {
+	config  config.ExecConfig
+	timeout time.Duration
+	slots   chan struct{}
+}
+
+// NewExecNotifier 创建 Exec 通知器
+func NewExecNotifier(cfg interface{}, opts notifier.Options) (notifier.Notifier, error) {
+	execConfig, ok := cfg.(config.ExecConfig)
+	if !ok {
+		return nil, fmt.Errorf("无效的 Exec 配置")
+	}
+	if execConfig.Command == "" {
+		return nil, fmt.Errorf("Exec 通知器需要配置 command")
+	}
+	if _, err := osexec.LookPath(execConfig.Command); err != nil {
+		return nil, fmt.Errorf("找不到命令 %s: %v", execConfig.Command, err)
+	}
+
+	timeout := time.Duration(execConfig.Timeout)
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	concurrency := execConfig.MaxConcurrency
+	if concurrency <= 0 {
+		concurrency = defaultConcurrency
+	}
+
+	return &ExecNotifier{
+		config:  execConfig,
+		timeout: timeout,
+		slots:   make(chan struct{}, concurrency),
+	}, nil
+}
+
+// Send 执行命令，消息内容写入标准输入
+func (n *ExecNotifier) Send(msg notifier.Message) error {
+	ctx, cancel := context.WithTimeout(context.Background(), n.timeout)
+	defer cancel()
+
+	// 等待空闲的执行槽位，超时仍未获得时放弃
+	select {
+	case n.slots <- struct{}{}:
+		defer func() { <-n.slots }()
+	case <-ctx.Done():
+		return fmt.Errorf("等待执行超时，同时执行的命令已达上限 %d", cap(n.slots))
+	}
+
+	cmd := osexec.CommandContext(ctx, n.config.Command, n.config.Args...)
+	cmd.Dir = n.config.WorkDir
+	cmd.Env = append(os.Environ(), n.environ(msg)...)
+	cmd.Stdin = strings.NewReader(msg.Content)
+	// 超时后子进程可能仍持有输出管道，最多再等待 1 秒
+	cmd.WaitDelay = time.Second
+
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	if ctx.Err() == context.DeadlineExceeded {
+		return fmt.Errorf("命令执行超时 (%s)", n.timeout)
+	}
+
+	var exitErr *osexec.ExitError
+	if errors.As(err, &exitErr) {
+		code := exitErr.ExitCode()
+		if n.success(code) {
+			return nil
+		}
+		return fmt.Errorf("命令退出码 %d: %s", code, truncate(strings.TrimSpace(stderr.String()), maxStderr))
+	}
+	if err != nil {
+		return fmt.Errorf("执行命令失败: %v", err)
+	}
+	if !n.success(0) {
+		return fmt.Errorf("命令退出码 0 不在成功退出码 %v 中", n.config.SuccessCodes)
+	}
+	return nil
+}
+
+// success 判断退出码是否视为成功
+func (n *ExecNotifier) success(code int) bool {
+	if len(n.config.SuccessCodes) == 0 {
+		return code == 0
+	}
+	for _, c := range n.config.SuccessCodes {
+		if c == code {
+			return true
+		}
+	}
+	return false
+}
+
+// environ 生成环境变量: 配置的 env、消息信息以及 LOGINFOPUSH_<字段名> 形式的事件字段
+func (n *ExecNotifier) environ(msg notifier.Message) []string {
+	env := make([]string, 0, len(n.config.Env)+len(msg.Metadata)+3)
+	for k, v := range n.config.Env {
+		env = append(env, k+"="+v)
+	}
+
+	vars := map[string]string{
+		"EVENT_TYPE": string(msg.EventType),
+		"SEVERITY":   string(msg.Severity),
+		"TITLE":      msg.Title,
+	}
+	if msg.IsTest() {
+		vars["TEST"] = "1"
+	}
+	for k, v := range msg.Metadata {
+		name := envName(k)
+		if name == "" {
+			continue
+		}
+		if _, exists := vars[name]; exists {
+			continue
+		}
+		switch x := v.(type) {
+		case nil:
+			continue
+		case string:
+			vars[name] = x
+		case []string:
+			vars[name] = strings.Join(x, ",")
+		case map[string]string:
+			// 字段已展开为顶层的事件数据
+			continue
+		default:
+			vars[name] = fmt.Sprint(x)
+		}
+	}
+
+	names := make([]string, 0, len(vars))
+	for name := range vars {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		env = append(env, envPrefix+name+"="+vars[name])
+	}
+	return env
+}
+
+// envName 将字段名转换为大写的环境变量名，非字母数字替换为下划线
+func envName(key string) string {
+	var b strings.Builder
+	for _, r := range key {
+		switch {
+		case r >= 'a' && r <= 'z':
+			b.WriteRune(r - 'a' + 'A')
+		case r >= 'A' && r <= 'Z', r >= '0' && r <= '9':
+			b.WriteRune(r)
+		default:
+			b.WriteByte('_')
+		}
+	}
+	return b.String()
+}
+
+// truncate 截断过长的输出
+func truncate(s string, n int) string {
+	r := []rune(s)
+	if len(r) <= n {
+		return s
+	}
+	return string(r[:n]) + "..."
+}
@@ -0,0 +1,206 @@
+package exec
+
+import (
+	"io/ioutil"
+	"loginfopush/config"
+	"loginfopush/notifier"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestNotifier 创建通过 /bin/sh 执行 script 的 Exec 通知器，脚本中 $1 为 args 的第一个值
+func newTestNotifier(t *testing.T, cfg config.ExecConfig, script string, args ...string) *ExecNotifier {
+	t.Helper()
+	cfg.Command = "/bin/sh"
+	cfg.Args = append([]string{"-c", script, "sh"}, args...)
+	n, err := NewExecNotifier(cfg, notifier.Options{})
+	if err != nil {
+		t.Fatalf("NewExecNotifier() = %v", err)
+	}
+	return n.(*ExecNotifier)
+}
+
+// readOutput 读取脚本写入的文件
+func readOutput(t *testing.T, path string) string {
+	t.Helper()
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestExecStdin(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "stdin")
+	n := newTestNotifier(t, config.ExecConfig{}, `cat > "$1"`, out)
+	content := "root 登录失败\n来源: 203.0.113.7"
+	if err := n.Send(notifier.Message{Content: content}); err != nil {
+		t.Fatal(err)
+	}
+	if got := readOutput(t, out); got != content {
+		t.Errorf("标准输入 = %q, want %q", got, content)
+	}
+}
+
+func TestExecEnv(t *testing.T) {
+	out := filepath.Join(t.TempDir(), "env")
+	n := newTestNotifier(t, config.ExecConfig{
+		Env: map[string]string{"ALERT_CHANNEL": "ops", "LOGINFOPUSH_IP": "被事件字段覆盖"},
+	}, `env > "$1"`, out)
+	err := n.Send(notifier.Message{
+		EventType: config.EventTypeFailure,
+		Severity:  config.SeverityWarning,
+		Title:     "登录失败",
+		Metadata: map[string]interface{}{
+			"IP":        "203.0.113.7",
+			"Port":      22,
+			"Methods":   []string{"password", "publickey"},
+			"user-name": "root",
+			"Title":     "不覆盖标题",
+			"None":      nil,
+			"Extra":     map[string]string{"a": "b"},
+			"Test":      true,
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	env := map[string]string{}
+	for _, line := range strings.Split(readOutput(t, out), "\n") {
+		if k, v, ok := strings.Cut(line, "="); ok {
+			env[k] = v
+		}
+	}
+	want := map[string]string{
+		"ALERT_CHANNEL":          "ops",
+		"LOGINFOPUSH_EVENT_TYPE": "fail",
+		"LOGINFOPUSH_SEVERITY":   "warning",
+		"LOGINFOPUSH_TITLE":      "登录失败",
+		"LOGINFOPUSH_TEST":       "1",
+		"LOGINFOPUSH_IP":         "203.0.113.7",
+		"LOGINFOPUSH_PORT":       "22",
+		"LOGINFOPUSH_METHODS":    "password,publickey",
+		"LOGINFOPUSH_USER_NAME":  "root",
+	}
+	for k, v := range want {
+		if env[k] != v {
+			t.Errorf("%s = %q, want %q", k, env[k], v)
+		}
+	}
+	for _, k := range []string{"LOGINFOPUSH_NONE", "LOGINFOPUSH_EXTRA"} {
+		if _, ok := env[k]; ok {
+			t.Errorf("不应设置 %s", k)
+		}
+	}
+	// 继承当前进程的环境变量
+	if env["PATH"] == "" {
+		t.Error("没有继承 PATH")
+	}
+}
+
+func TestExecWorkDir(t *testing.T) {
+	dir := t.TempDir()
+	n := newTestNotifier(t, config.ExecConfig{WorkDir: dir}, `pwd > out`)
+	if err := n.Send(notifier.Message{}); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := filepath.EvalSymlinks(strings.TrimSpace(readOutput(t, filepath.Join(dir, "out"))))
+	want, _ := filepath.EvalSymlinks(dir)
+	if got != want {
+		t.Errorf("工作目录 = %s, want %s", got, want)
+	}
+}
+
+func TestExecExitCodes(t *testing.T) {
+	tests := []struct {
+		name    string
+		script  string
+		codes   []int
+		wantErr string
+	}{
+		{"成功", "exit 0", nil, ""},
+		{"非零退出码", "echo '参数错误' >&2; exit 3", nil, "命令退出码 3: 参数错误"},
+		{"自定义成功退出码", "exit 3", []int{0, 3}, ""},
+		{"0 不在成功退出码中", "exit 0", []int{1}, "命令退出码 0 不在成功退出码 [1] 中"},
+		// 错误信息中的标准错误输出被截断
+		{"截断标准错误", "printf '%0600d' 0 >&2; exit 1", nil, "命令退出码 1: " + strings.Repeat("0", maxStderr) + "..."},
+	}
+	for _, tt := range tests {
+		n := newTestNotifier(t, config.ExecConfig{SuccessCodes: tt.codes}, tt.script)
+		err := n.Send(notifier.Message{})
+		switch {
+		case tt.wantErr == "" && err != nil:
+			t.Errorf("%s: Send() = %v, want nil", tt.name, err)
+		case tt.wantErr != "" && (err == nil || err.Error() != tt.wantErr):
+			t.Errorf("%s: Send() = %v, want %s", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestExecTimeout(t *testing.T) {
+	tests := []struct {
+		name   string
+		script string
+	}{
+		{"命令超时", "sleep 10"},
+		// 子进程继承了标准错误管道，超时后不会一直等待管道关闭
+		{"子进程持有管道", "sleep 10 & wait"},
+	}
+	for _, tt := range tests {
+		n := newTestNotifier(t, config.ExecConfig{Timeout: config.Duration(200 * time.Millisecond)}, tt.script)
+		start := time.Now()
+		err := n.Send(notifier.Message{})
+		if err == nil || !strings.Contains(err.Error(), "命令执行超时") {
+			t.Errorf("%s: Send() = %v, want 超时错误", tt.name, err)
+		}
+		if elapsed := time.Since(start); elapsed > 5*time.Second {
+			t.Errorf("%s: 超时后等待了 %s", tt.name, elapsed)
+		}
+	}
+}
+
+func TestExecConcurrency(t *testing.T) {
+	n := newTestNotifier(t, config.ExecConfig{MaxConcurrency: 1, Timeout: config.Duration(100 * time.Millisecond)}, "exit 0")
+	// 占用唯一的执行槽位
+	n.slots <- struct{}{}
+	err := n.Send(notifier.Message{})
+	if err == nil || !strings.Contains(err.Error(), "同时执行的命令已达上限 1") {
+		t.Errorf("Send() = %v, want 等待执行超时", err)
+	}
+	<-n.slots
+	if err := n.Send(notifier.Message{}); err != nil {
+		t.Errorf("释放槽位后 Send() = %v", err)
+	}
+}
+
+func TestNewExecNotifierErrors(t *testing.T) {
+	for _, cfg := range []config.ExecConfig{
+		{},
+		{Command: "/nonexistent/notify"},
+	} {
+		if _, err := NewExecNotifier(cfg, notifier.Options{}); err == nil {
+			t.Errorf("NewExecNotifier(%+v) 没有返回错误", cfg)
+		}
+	}
+}
+
+func TestEnvName(t *testing.T) {
+	tests := map[string]string{
+		"IP":        "IP",
+		"user":      "USER",
+		"Ban Time":  "BAN_TIME",
+		"user-name": "USER_NAME",
+		"位置":        "__",
+	}
+	got := map[string]string{}
+	for key := range tests {
+		got[key] = envName(key)
+	}
+	if !reflect.DeepEqual(got, tests) {
+		t.Errorf("envName() = %v, want %v", got, tests)
+	}
+}
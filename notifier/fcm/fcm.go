@@ -17,17 +17,27 @@ func init() {
 // FCMNotifier FCM 通知器
 type FCMNotifier struct {
 	config config.FCMConfig
+	client *http.Client
 }
 
 // NewFCMNotifier 创建 FCM 通知器
-func NewFCMNotifier(cfg interface{}) (notifier.Notifier, error) {
+func NewFCMNotifier(cfg interface{}, opts notifier.Options) (notifier.Notifier, error) {
 	fcmConfig, ok := cfg.(config.FCMConfig)
 	if !ok {
 		return nil, fmt.Errorf("无效的 FCM 配置")
 	}
 
+	switch fcmConfig.Mode {
+	case "", config.FCMModeWebhook:
+	case config.FCMModeV1:
+		return newFCMV1Notifier(fcmConfig, opts.HTTPClient)
+	default:
+		return nil, fmt.Errorf("不支持的 FCM 模式: %s", fcmConfig.Mode)
+	}
+
 	return &FCMNotifier{
 		config: fcmConfig,
+		client: opts.HTTPClient,
 	}, nil
 }
 
@@ -52,7 +62,7 @@ func (n *FCMNotifier) Send(msg notifier.Message) error {
 	payload := FCMPayload{}
 	payload.Data.To = n.config.DeviceToken
 	payload.Data.TTL = 60
-	payload.Data.Priority = "high"
+	payload.Data.Priority = priorityFor(msg.Severity)
 	payload.Data.Data.Text.Title = msg.Title
 	payload.Data.Data.Text.Message = msg.Content
 	payload.Data.Data.Text.Clipboard = false
@@ -62,7 +72,7 @@ func (n *FCMNotifier) Send(msg notifier.Message) error {
 		return fmt.Errorf("marshal json error: %v", err)
 	}
 
-	resp, err := http.Post(n.config.WebhookURL, "application/json", bytes.NewBuffer(jsonData))
+	resp, err := n.client.Post(n.config.WebhookURL, "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("send webhook error: %v", err)
 	}
@@ -75,3 +85,11 @@ func (n *FCMNotifier) Send(msg notifier.Message) error {
 
 	return nil
 }
+
+// priorityFor 严重程度为 info 的消息使用普通优先级，其余使用高优先级
+func priorityFor(severity config.Severity) string {
+	if severity == config.SeverityInfo {
+		return "normal"
+	}
+	return "high"
+}
@@ -0,0 +1,222 @@
+package fcm
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	// fcmScope FCM 推送所需的 OAuth2 权限范围
+	fcmScope = "https://www.googleapis.com/auth/firebase.messaging"
+	// defaultTokenURL Google OAuth2 Token 地址
+	defaultTokenURL = "https://oauth2.googleapis.com/token"
+	// tokenRefreshSkew Token 提前刷新的时间
+	tokenRefreshSkew = 5 * time.Minute
+)
+
+// serviceAccount 服务账号 JSON 文件中用到的字段
+type serviceAccount struct {
+	Type         string `json:"type"`
+	ProjectID    string `json:"project_id"`
+	PrivateKeyID string `json:"private_key_id"`
+	PrivateKey   string `json:"private_key"`
+	ClientEmail  string `json:"client_email"`
+	TokenURI     string `json:"token_uri"`
+}
+
+// loadServiceAccount 读取服务账号文件
+func loadServiceAccount(path string) (*serviceAccount, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取服务账号文件失败: %v", err)
+	}
+
+	sa := &serviceAccount{}
+	if err := json.Unmarshal(data, sa); err != nil {
+		return nil, fmt.Errorf("解析服务账号文件失败: %v", err)
+	}
+	if sa.Type != "" && sa.Type != "service_account" {
+		return nil, fmt.Errorf("不支持的凭据类型: %s", sa.Type)
+	}
+	if sa.ClientEmail == "" || sa.PrivateKey == "" {
+		return nil, fmt.Errorf("服务账号缺少 client_email 或 private_key")
+	}
+
+	return sa, nil
+}
+
+// tokenSource 使用服务账号签发 JWT 换取并缓存 OAuth2 访问令牌
+type tokenSource struct {
+	email    string
+	keyID    string
+	key      *rsa.PrivateKey
+	tokenURL string
+	client   *http.Client
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// newTokenSource 创建令牌源，tokenURL 为空时使用服务账号中的 token_uri
+func newTokenSource(sa *serviceAccount, tokenURL string, client *http.Client) (*tokenSource, error) {
+	key, err := parsePrivateKey(sa.PrivateKey)
+	if err != nil {
+		return nil, err
+	}
+
+	if tokenURL == "" {
+		tokenURL = sa.TokenURI
+	}
+	if tokenURL == "" {
+		tokenURL = defaultTokenURL
+	}
+
+	return &tokenSource{
+		email:    sa.ClientEmail,
+		keyID:    sa.PrivateKeyID,
+		key:      key,
+		tokenURL: tokenURL,
+		client:   client,
+	}, nil
+}
+
+// parsePrivateKey 解析 PEM 格式的 RSA 私钥（PKCS#8 或 PKCS#1）
+func parsePrivateKey(pemKey string) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode([]byte(pemKey))
+	if block == nil {
+		return nil, fmt.Errorf("私钥不是有效的 PEM 格式")
+	}
+
+	if key, err := x509.ParsePKCS8PrivateKey(block.Bytes); err == nil {
+		rsaKey, ok := key.(*rsa.PrivateKey)
+		if !ok {
+			return nil, fmt.Errorf("私钥不是 RSA 类型")
+		}
+		return rsaKey, nil
+	}
+
+	key, err := x509.ParsePKCS1PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("解析私钥失败: %v", err)
+	}
+	return key, nil
+}
+
+// Token 获取有效的访问令牌，过期前自动刷新
+func (s *tokenSource) Token() (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && time.Now().Add(tokenRefreshSkew).Before(s.expiry) {
+		return s.token, nil
+	}
+
+	token, expiry, err := s.fetch()
+	if err != nil {
+		return "", err
+	}
+
+	s.token = token
+	s.expiry = expiry
+	return token, nil
+}
+
+// Invalidate 清除缓存的令牌，下次调用 Token 时重新获取
+func (s *tokenSource) Invalidate() {
+	s.mu.Lock()
+	s.token = ""
+	s.mu.Unlock()
+}
+
+// fetch 通过 JWT Bearer 授权获取新令牌
+func (s *tokenSource) fetch() (string, time.Time, error) {
+	now := time.Now()
+	assertion, err := s.signJWT(now)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("签发 JWT 失败: %v", err)
+	}
+
+	form := url.Values{}
+	form.Set("grant_type", "urn:ietf:params:oauth:grant-type:jwt-bearer")
+	form.Set("assertion", assertion)
+
+	resp, err := s.client.PostForm(s.tokenURL, form)
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("请求 OAuth2 令牌失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", time.Time{}, fmt.Errorf("OAuth2 令牌响应错误: status=%d, body=%s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", time.Time{}, fmt.Errorf("解析 OAuth2 令牌响应失败: %v", err)
+	}
+	if result.AccessToken == "" {
+		return "", time.Time{}, fmt.Errorf("OAuth2 令牌响应缺少 access_token: %s", string(body))
+	}
+	if result.ExpiresIn <= 0 {
+		result.ExpiresIn = 3600
+	}
+
+	return result.AccessToken, now.Add(time.Duration(result.ExpiresIn) * time.Second), nil
+}
+
+// signJWT 使用 RS256 签发 JWT 断言
+func (s *tokenSource) signJWT(now time.Time) (string, error) {
+	header := map[string]string{
+		"alg": "RS256",
+		"typ": "JWT",
+	}
+	if s.keyID != "" {
+		header["kid"] = s.keyID
+	}
+
+	claims := map[string]interface{}{
+		"iss":   s.email,
+		"scope": fcmScope,
+		"aud":   s.tokenURL,
+		"iat":   now.Unix(),
+		"exp":   now.Add(time.Hour).Unix(),
+	}
+
+	headerJSON, err := json.Marshal(header)
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	enc := base64.RawURLEncoding
+	signingInput := strings.Join([]string{enc.EncodeToString(headerJSON), enc.EncodeToString(claimsJSON)}, ".")
+
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, s.key, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", err
+	}
+
+	return signingInput + "." + enc.EncodeToString(signature), nil
+}
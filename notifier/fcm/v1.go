@@ -0,0 +1,183 @@
+package fcm
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"loginfopush/config"
+	"loginfopush/notifier"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// defaultEndpoint FCM HTTP v1 API 地址
+const defaultEndpoint = "https://fcm.googleapis.com"
+
+// fcmV1Notifier 直接调用 FCM HTTP v1 API 的通知器
+type fcmV1Notifier struct {
+	config  config.FCMConfig
+	sendURL string
+	tokens  *tokenSource
+	client  *http.Client
+}
+
+// newFCMV1Notifier 创建 FCM v1 通知器
+func newFCMV1Notifier(cfg config.FCMConfig, client *http.Client) (notifier.Notifier, error) {
+	if cfg.ServiceAccountFile == "" {
+		return nil, fmt.Errorf("FCM v1 模式需要配置 service_account_file")
+	}
+	if (cfg.DeviceToken == "") == (cfg.Topic == "") {
+		return nil, fmt.Errorf("FCM v1 模式需要且只能配置 device_token 或 topic 其中之一")
+	}
+
+	sa, err := loadServiceAccount(cfg.ServiceAccountFile)
+	if err != nil {
+		return nil, err
+	}
+
+	projectID := cfg.ProjectID
+	if projectID == "" {
+		projectID = sa.ProjectID
+	}
+	if projectID == "" {
+		return nil, fmt.Errorf("FCM v1 模式需要 project_id")
+	}
+
+	tokens, err := newTokenSource(sa, cfg.TokenURL, client)
+	if err != nil {
+		return nil, err
+	}
+
+	endpoint := strings.TrimRight(cfg.Endpoint, "/")
+	if endpoint == "" {
+		endpoint = defaultEndpoint
+	}
+
+	return &fcmV1Notifier{
+		config:  cfg,
+		sendURL: fmt.Sprintf("%s/v1/projects/%s/messages:send", endpoint, projectID),
+		tokens:  tokens,
+		client:  client,
+	}, nil
+}
+
+// v1Request FCM v1 请求体
+type v1Request struct {
+	Message v1Message `json:"message"`
+}
+
+// v1Message FCM v1 消息
+type v1Message struct {
+	Token        string            `json:"token,omitempty"`
+	Topic        string            `json:"topic,omitempty"`
+	Notification *v1Notification   `json:"notification,omitempty"`
+	Data         map[string]string `json:"data,omitempty"`
+	Android      *v1Android        `json:"android,omitempty"`
+}
+
+// v1Notification FCM v1 通知内容
+type v1Notification struct {
+	Title string `json:"title,omitempty"`
+	Body  string `json:"body,omitempty"`
+}
+
+// v1Android Android 平台配置
+type v1Android struct {
+	Priority string `json:"priority,omitempty"`
+	TTL      string `json:"ttl,omitempty"`
+}
+
+// Send 发送通知
+func (n *fcmV1Notifier) Send(msg notifier.Message) error {
+	jsonData, err := json.Marshal(n.buildRequest(msg))
+	if err != nil {
+		return fmt.Errorf("marshal json error: %v", err)
+	}
+
+	resp, body, err := n.post(jsonData)
+	if err != nil {
+		return err
+	}
+
+	// 令牌被提前吊销时刷新一次后重试
+	if resp.StatusCode == http.StatusUnauthorized {
+		n.tokens.Invalidate()
+		if resp, body, err = n.post(jsonData); err != nil {
+			return err
+		}
+	}
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("fcm response error: status=%d, body=%s", resp.StatusCode, string(body))
+	}
+
+	return nil
+}
+
+// buildRequest 构建 v1 请求，data 中携带事件元数据（值必须为字符串）
+func (n *fcmV1Notifier) buildRequest(msg notifier.Message) v1Request {
+	data := map[string]string{
+		"title":   msg.Title,
+		"message": msg.Content,
+	}
+	if msg.EventType != "" {
+		data["event_type"] = string(msg.EventType)
+	}
+	for k, v := range msg.Metadata {
+		if v == nil {
+			continue
+		}
+		data[strings.ToLower(k)] = fmt.Sprint(v)
+	}
+
+	return v1Request{
+		Message: v1Message{
+			Token: n.config.DeviceToken,
+			Topic: n.config.Topic,
+			Notification: &v1Notification{
+				Title: msg.Title,
+				Body:  msg.Content,
+			},
+			Data: data,
+			Android: &v1Android{
+				Priority: priorityFor(msg.Severity),
+				TTL:      formatTTL(n.config.TTL.Std()),
+			},
+		},
+	}
+}
+
+// formatTTL 按 FCM 的 Duration 格式（以 s 结尾的秒数）输出有效期，未配置时返回空字符串使用 FCM 默认值
+func formatTTL(ttl time.Duration) string {
+	if ttl <= 0 {
+		return ""
+	}
+	return strconv.FormatFloat(ttl.Seconds(), 'f', -1, 64) + "s"
+}
+
+// post 携带访问令牌发送请求
+func (n *fcmV1Notifier) post(jsonData []byte) (*http.Response, []byte, error) {
+	token, err := n.tokens.Token()
+	if err != nil {
+		return nil, nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, n.sendURL, bytes.NewReader(jsonData))
+	if err != nil {
+		return nil, nil, fmt.Errorf("create request error: %v", err)
+	}
+	req.Header.Set("Content-Type", "application/json; charset=utf-8")
+	req.Header.Set("Authorization", "Bearer "+token)
+
+	resp, err := n.client.Do(req)
+	if err != nil {
+		return nil, nil, fmt.Errorf("send fcm error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	return resp, body, nil
+}
@@ -0,0 +1,268 @@
+package fcm
+
+import (
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"io/ioutil"
+	"loginfopush/config"
+	"loginfopush/notifier"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeFCM 模拟 Google OAuth2 令牌接口与 FCM v1 发送接口
+type fakeFCM struct {
+	t   *testing.T
+	key *rsa.PublicKey
+
+	mu       sync.Mutex
+	tokens   int
+	requests []v1Request
+	reject   int // 之后的发送请求返回 401 的次数
+}
+
+func (f *fakeFCM) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	switch r.URL.Path {
+	case "/token":
+		if err := r.ParseForm(); err != nil {
+			f.t.Errorf("解析令牌请求失败: %v", err)
+		}
+		if got := r.PostForm.Get("grant_type"); got != "urn:ietf:params:oauth:grant-type:jwt-bearer" {
+			f.t.Errorf("grant_type = %q", got)
+		}
+		f.verifyJWT(r.PostForm.Get("assertion"))
+		f.tokens++
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"access_token": "token-" + strconv.Itoa(f.tokens),
+			"expires_in":   3600,
+		})
+	case "/v1/projects/demo/messages:send":
+		if f.reject > 0 {
+			f.reject--
+			http.Error(w, `{"error":{"code":401}}`, http.StatusUnauthorized)
+			return
+		}
+		if got, want := r.Header.Get("Authorization"), "Bearer token-"+strconv.Itoa(f.tokens); got != want {
+			f.t.Errorf("Authorization = %q, want %q", got, want)
+		}
+		var req v1Request
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			f.t.Errorf("解析发送请求失败: %v", err)
+		}
+		f.requests = append(f.requests, req)
+		w.Write([]byte(`{"name":"projects/demo/messages/1"}`))
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// verifyJWT 校验 RS256 签名与声明
+func (f *fakeFCM) verifyJWT(assertion string) {
+	parts := strings.Split(assertion, ".")
+	if len(parts) != 3 {
+		f.t.Errorf("JWT 格式错误: %q", assertion)
+		return
+	}
+	enc := base64.RawURLEncoding
+	signature, _ := enc.DecodeString(parts[2])
+	digest := sha256.Sum256([]byte(parts[0] + "." + parts[1]))
+	if err := rsa.VerifyPKCS1v15(f.key, crypto.SHA256, digest[:], signature); err != nil {
+		f.t.Errorf("JWT 签名无效: %v", err)
+	}
+
+	claimsJSON, _ := enc.DecodeString(parts[1])
+	var claims map[string]interface{}
+	json.Unmarshal(claimsJSON, &claims)
+	if claims["iss"] != "push@demo.iam.gserviceaccount.com" || claims["scope"] != fcmScope {
+		f.t.Errorf("JWT claims = %v", claims)
+	}
+}
+
+// sent 已收到的发送请求
+func (f *fakeFCM) sent() []v1Request {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]v1Request(nil), f.requests...)
+}
+
+// tokenCount 获取令牌的次数
+func (f *fakeFCM) tokenCount() int {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return f.tokens
+}
+
+// rejectNext 之后的 n 次发送请求返回 401
+func (f *fakeFCM) rejectNext(n int) {
+	f.mu.Lock()
+	f.reject = n
+	f.mu.Unlock()
+}
+
+// newFakeFCM 启动模拟服务并生成对应的服务账号文件，返回指向模拟服务的配置
+func newFakeFCM(t *testing.T) (*fakeFCM, config.FCMConfig) {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatal(err)
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fake := &fakeFCM{t: t, key: &key.PublicKey}
+	server := httptest.NewServer(fake)
+	t.Cleanup(server.Close)
+
+	sa, _ := json.Marshal(serviceAccount{
+		Type:         "service_account",
+		ProjectID:    "demo",
+		PrivateKeyID: "kid-1",
+		PrivateKey:   string(pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der})),
+		ClientEmail:  "push@demo.iam.gserviceaccount.com",
+		TokenURI:     server.URL + "/token",
+	})
+	file := filepath.Join(t.TempDir(), "service-account.json")
+	if err := ioutil.WriteFile(file, sa, 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	return fake, config.FCMConfig{
+		Mode:               config.FCMModeV1,
+		DeviceToken:        "device-1",
+		ServiceAccountFile: file,
+		Endpoint:           server.URL,
+	}
+}
+
+func newTestNotifier(t *testing.T, cfg config.FCMConfig) notifier.Notifier {
+	t.Helper()
+	n, err := NewFCMNotifier(cfg, notifier.Options{HTTPClient: &http.Client{Timeout: 5 * time.Second}})
+	if err != nil {
+		t.Fatalf("NewFCMNotifier() = %v", err)
+	}
+	return n
+}
+
+func TestV1Send(t *testing.T) {
+	fake, cfg := newFakeFCM(t)
+	n := newTestNotifier(t, cfg)
+
+	msg := notifier.Message{
+		EventType: config.EventTypeFailure,
+		Severity:  config.SeverityWarning,
+		Title:     "登录失败",
+		Content:   "root@203.0.113.7",
+		Metadata:  map[string]interface{}{"IP": "203.0.113.7", "Port": 22, "Empty": nil},
+	}
+	for i := 0; i < 2; i++ {
+		if err := n.Send(msg); err != nil {
+			t.Fatalf("Send() = %v", err)
+		}
+	}
+
+	// 令牌被缓存，只获取一次
+	if n := fake.tokenCount(); n != 1 {
+		t.Errorf("获取令牌 %d 次, want 1", n)
+	}
+	sent := fake.sent()
+	if len(sent) != 2 {
+		t.Fatalf("收到 %d 条消息, want 2", len(sent))
+	}
+	got := sent[0].Message
+	if got.Token != "device-1" || got.Topic != "" {
+		t.Errorf("token = %q, topic = %q", got.Token, got.Topic)
+	}
+	if got.Notification == nil || got.Notification.Title != "登录失败" || got.Notification.Body != "root@203.0.113.7" {
+		t.Errorf("notification = %+v", got.Notification)
+	}
+	want := map[string]string{
+		"title":      "登录失败",
+		"message":    "root@203.0.113.7",
+		"event_type": "fail",
+		"ip":         "203.0.113.7",
+		"port":       "22",
+	}
+	if len(got.Data) != len(want) {
+		t.Errorf("data = %v, want %v", got.Data, want)
+	}
+	for k, v := range want {
+		if got.Data[k] != v {
+			t.Errorf("data[%s] = %q, want %q", k, got.Data[k], v)
+		}
+	}
+	// 未配置 ttl 时不设置，使用 FCM 默认值
+	if got.Android == nil || got.Android.Priority != "high" || got.Android.TTL != "" {
+		t.Errorf("android = %+v", got.Android)
+	}
+}
+
+func TestV1TTLAndTopic(t *testing.T) {
+	fake, cfg := newFakeFCM(t)
+	cfg.DeviceToken = ""
+	cfg.Topic = "alerts"
+	cfg.TTL = config.Duration(90 * time.Second)
+	n := newTestNotifier(t, cfg)
+
+	if err := n.Send(notifier.Message{Severity: config.SeverityInfo, Title: "t", Content: "c"}); err != nil {
+		t.Fatalf("Send() = %v", err)
+	}
+	got := fake.sent()[0].Message
+	if got.Topic != "alerts" || got.Token != "" {
+		t.Errorf("token = %q, topic = %q", got.Token, got.Topic)
+	}
+	if got.Android.TTL != "90s" || got.Android.Priority != "normal" {
+		t.Errorf("android = %+v", got.Android)
+	}
+}
+
+func TestV1RefreshOnUnauthorized(t *testing.T) {
+	fake, cfg := newFakeFCM(t)
+	n := newTestNotifier(t, cfg)
+	fake.rejectNext(1)
+
+	if err := n.Send(notifier.Message{Title: "t", Content: "c"}); err != nil {
+		t.Fatalf("Send() = %v", err)
+	}
+	if fake.tokenCount() != 2 || len(fake.sent()) != 1 {
+		t.Errorf("tokens = %d, sent = %d, want 2, 1", fake.tokenCount(), len(fake.sent()))
+	}
+
+	// 刷新后仍被拒绝时返回错误
+	fake.rejectNext(2)
+	if err := n.Send(notifier.Message{Title: "t", Content: "c"}); err == nil || !strings.Contains(err.Error(), "status=401") {
+		t.Errorf("Send() = %v, want status=401", err)
+	}
+}
+
+func TestFormatTTL(t *testing.T) {
+	tests := []struct {
+		ttl  time.Duration
+		want string
+	}{
+		{0, ""},
+		{time.Hour, "3600s"},
+		{1500 * time.Millisecond, "1.5s"},
+	}
+	for _, tt := range tests {
+		if got := formatTTL(tt.ttl); got != tt.want {
+			t.Errorf("formatTTL(%v) = %q, want %q", tt.ttl, got, tt.want)
+		}
+	}
+}
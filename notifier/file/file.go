@@ -0,0 +1,153 @@
+package file
+
+import (
+	"encoding/json"
+	"fmt"
+	"loginfopush/config"
+	"loginfopush/notifier"
+	"os"
+	"path/filepath"
+	"sync"
+	"time"
+)
+
+func init() {
+	notifier.RegisterNotifier(config.NotifierTypeFile, NewFileNotifier)
+}
+
+// 默认值
+const (
+	defaultMaxSizeMB  = 100
+	defaultMaxBackups = 5
+)
+
+// record 写入文件的一行事件
+type record struct {
+	Time     string                 `json:"time"`               // 写入时间，RFC 3339
+	Host     string                 `json:"host"`               // 本机主机名
+	Server   string                 `json:"server"`             // 服务器名称
+	Tag      string                 `json:"tag,omitempty"`      // 服务器标签
+	Type     config.EventType       `json:"type"`               // 事件类型
+	Severity config.Severity        `json:"severity,omitempty"` // 严重程度
+	Title    string                 `json:"title,omitempty"`    // 标题
+	Message  string                 `json:"message"`            // 渲染后的消息
+	Data     map[string]interface{} `json:"data,omitempty"`     // 事件的全部字段
+	Test     bool                   `json:"test,omitempty"`     // test-notify 发送的测试消息
+}
+
+// FileNotifier 将事件以 JSON Lines 格式写入文件，超过大小后轮转
+type FileNotifier struct {
+	mu         sync.Mutex
+	path       string
+	maxSize    int64
+	maxBackups int
+	host       string
+	server     config.ServerConfig
+	file       *os.File
+	size       int64
+}
+
+// NewFileNotifier 创建 File 通知器
+func NewFileNotifier(cfg interface{}, opts notifier.Options) (notifier.Notifier, error) {
+	fileConfig, ok := cfg.(config.FileConfig)
+	if !ok {
+		return nil, fmt.Errorf("无效的 File 配置")
+	}
+	if fileConfig.Path == "" {
+		return nil, fmt.Errorf("File 通知器需要配置 path")
+	}
+
+	maxSizeMB := fileConfig.MaxSizeMB
+	if maxSizeMB <= 0 {
+		maxSizeMB = defaultMaxSizeMB
+	}
+	maxBackups := fileConfig.MaxBackups
+	if maxBackups <= 0 {
+		maxBackups = defaultMaxBackups
+	}
+	host, _ := os.Hostname()
+
+	n := &FileNotifier{
+		path:       fileConfig.Path,
+		maxSize:    int64(maxSizeMB) << 20,
+		maxBackups: maxBackups,
+		host:       host,
+		server:     opts.Server,
+	}
+	if err := n.open(); err != nil {
+		return nil, err
+	}
+	return n, nil
+}
+
+// Send 追加一行事件记录
+func (n *FileNotifier) Send(msg notifier.Message) error {
+	line, err := json.Marshal(record{
+		Time:     time.Now().Format(time.RFC3339Nano),
+		Host:     n.host,
+		Server:   n.server.Name,
+		Tag:      n.server.Tag,
+		Type:     msg.EventType,
+		Severity: msg.Severity,
+		Title:    msg.Title,
+		Message:  msg.Content,
+		Data:     msg.Metadata,
+		Test:     msg.IsTest(),
+	})
+	if err != nil {
+		return fmt.Errorf("序列化事件失败: %v", err)
+	}
+	line = append(line, '\n')
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.size > 0 && n.size+int64(len(line)) > n.maxSize {
+		if err := n.rotate(); err != nil {
+			return err
+		}
+	}
+	written, err := n.file.Write(line)
+	n.size += int64(written)
+	if err != nil {
+		return fmt.Errorf("写入 %s 失败: %v", n.path, err)
+	}
+	return nil
+}
+
+// open 以追加方式打开文件
+func (n *FileNotifier) open() error {
+	if err := os.MkdirAll(filepath.Dir(n.path), 0755); err != nil {
+		return fmt.Errorf("创建目录失败: %v", err)
+	}
+	f, err := os.OpenFile(n.path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0640)
+	if err != nil {
+		return fmt.Errorf("打开 %s 失败: %v", n.path, err)
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return fmt.Errorf("读取 %s 失败: %v", n.path, err)
+	}
+	n.file = f
+	n.size = info.Size()
+	return nil
+}
+
+// rotate 将 path 重命名为 path.1，已有的 path.N 依次后移，超出数量的删除
+func (n *FileNotifier) rotate() error {
+	n.file.Close()
+
+	os.Remove(fmt.Sprintf("%s.%d", n.path, n.maxBackups))
+	for i := n.maxBackups - 1; i >= 1; i-- {
+		os.Rename(fmt.Sprintf("%s.%d", n.path, i), fmt.Sprintf("%s.%d", n.path, i+1))
+	}
+	if err := os.Rename(n.path, n.path+".1"); err != nil && !os.IsNotExist(err) {
+		// 无法轮转时继续写入原文件
+		if openErr := n.open(); openErr != nil {
+			return openErr
+		}
+		return fmt.Errorf("轮转 %s 失败: %v", n.path, err)
+	}
+	return n.open()
+}
@@ -0,0 +1,170 @@
+package file
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"loginfopush/config"
+	"loginfopush/notifier"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// newTestNotifier 创建写入临时目录的 File 通知器，单个文件的上限为 maxSize 字节
+func newTestNotifier(t *testing.T, maxSize int64, maxBackups int) *FileNotifier {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "log", "events.jsonl")
+	n, err := NewFileNotifier(config.FileConfig{Path: path, MaxBackups: maxBackups}, notifier.Options{
+		Server: config.ServerConfig{Name: "web", Tag: "prod"},
+	})
+	if err != nil {
+		t.Fatalf("NewFileNotifier() = %v", err)
+	}
+	fn := n.(*FileNotifier)
+	fn.maxSize = maxSize
+	t.Cleanup(func() { fn.file.Close() })
+	return fn
+}
+
+// readRecords 读取文件中的所有记录，文件不存在时返回 nil
+func readRecords(t *testing.T, path string) []record {
+	t.Helper()
+	f, err := os.Open(path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	var records []record
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		var r record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			t.Fatalf("解析 %s 失败: %v", path, err)
+		}
+		records = append(records, r)
+	}
+	return records
+}
+
+// messages 文件中各记录的消息内容
+func messages(t *testing.T, path string) []string {
+	var got []string
+	for _, r := range readRecords(t, path) {
+		got = append(got, r.Message)
+	}
+	return got
+}
+
+func TestFileRecord(t *testing.T) {
+	n := newTestNotifier(t, 1<<20, 0)
+	err := n.Send(notifier.Message{
+		EventType: config.EventTypeFailure,
+		Severity:  config.SeverityWarning,
+		Title:     "登录失败",
+		Content:   "IP 203.0.113.7 登录失败",
+		Metadata:  map[string]interface{}{"IP": "203.0.113.7", "Test": true},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	records := readRecords(t, n.path)
+	if len(records) != 1 {
+		t.Fatalf("记录数 = %d, want 1", len(records))
+	}
+	r := records[0]
+	if r.Server != "web" || r.Tag != "prod" || r.Type != config.EventTypeFailure || r.Severity != config.SeverityWarning ||
+		r.Title != "登录失败" || r.Message != "IP 203.0.113.7 登录失败" || r.Data["IP"] != "203.0.113.7" || !r.Test {
+		t.Errorf("record = %+v", r)
+	}
+	if r.Time == "" || r.Host == "" {
+		t.Errorf("缺少时间或主机名: %+v", r)
+	}
+}
+
+func TestFileRotation(t *testing.T) {
+	n := newTestNotifier(t, 1<<20, 2)
+	for i := 1; i <= 7; i++ {
+		if err := n.Send(notifier.Message{EventType: config.EventTypeFailure, Content: fmt.Sprintf("事件 %d", i)}); err != nil {
+			t.Fatalf("Send(%d) = %v", i, err)
+		}
+		if i == 1 {
+			// 按第一条记录的长度设置上限，每个文件最多两条（时间戳长度不固定）
+			n.maxSize = n.size * 5 / 2
+		}
+	}
+
+	tests := []struct {
+		path string
+		want []string
+	}{
+		{n.path, []string{"事件 7"}},
+		{n.path + ".1", []string{"事件 5", "事件 6"}},
+		{n.path + ".2", []string{"事件 3", "事件 4"}},
+		// 超过 max_backups 的备份被删除
+		{n.path + ".3", nil},
+	}
+	for _, tt := range tests {
+		if got := messages(t, tt.path); strings.Join(got, ",") != strings.Join(tt.want, ",") {
+			t.Errorf("%s = %q, want %q", filepath.Base(tt.path), got, tt.want)
+		}
+	}
+	for _, path := range []string{n.path, n.path + ".1", n.path + ".2"} {
+		info, err := os.Stat(path)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Size() > n.maxSize {
+			t.Errorf("%s 大小 %d 超过上限", filepath.Base(path), info.Size())
+		}
+	}
+}
+
+func TestFileReopenAppends(t *testing.T) {
+	n := newTestNotifier(t, 1<<20, 0)
+	if err := n.Send(notifier.Message{Content: "第一条"}); err != nil {
+		t.Fatal(err)
+	}
+	n.file.Close()
+
+	// 重新打开时接着已有内容写入，并沿用已有的大小计算轮转
+	m, err := NewFileNotifier(config.FileConfig{Path: n.path}, notifier.Options{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fm := m.(*FileNotifier)
+	defer fm.file.Close()
+	data, _ := ioutil.ReadFile(n.path)
+	if fm.size != int64(len(data)) {
+		t.Errorf("size = %d, want %d", fm.size, len(data))
+	}
+	if err := fm.Send(notifier.Message{Content: "第二条"}); err != nil {
+		t.Fatal(err)
+	}
+	if got := messages(t, n.path); strings.Join(got, ",") != "第一条,第二条" {
+		t.Errorf("messages = %q", got)
+	}
+}
+
+func TestFileSingleOversizedRecord(t *testing.T) {
+	// 空文件中超过上限的记录仍然写入，不会反复轮转
+	n := newTestNotifier(t, 10, 1)
+	for i := 1; i <= 2; i++ {
+		if err := n.Send(notifier.Message{Content: fmt.Sprintf("事件 %d", i)}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if got := messages(t, n.path); strings.Join(got, ",") != "事件 2" {
+		t.Errorf("当前文件 = %q", got)
+	}
+	if got := messages(t, n.path+".1"); strings.Join(got, ",") != "事件 1" {
+		t.Errorf("备份 = %q", got)
+	}
+}
@@ -0,0 +1,117 @@
+package notifier
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"loginfopush/config"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultHTTPTimeout 未配置超时时使用的默认值，避免请求挂起阻塞事件处理
+const DefaultHTTPTimeout = 10 * time.Second
+
+// MergeHTTPConfig 合并全局与通知器级别的 HTTP 配置，通知器配置中已设置的字段优先
+func MergeHTTPConfig(global, local *config.HTTPConfig) config.HTTPConfig {
+	var merged config.HTTPConfig
+	if global != nil {
+		merged = *global
+	}
+	if local == nil {
+		return merged
+	}
+
+	if local.Timeout != 0 {
+		merged.Timeout = local.Timeout
+	}
+	if local.Proxy != "" {
+		merged.Proxy = local.Proxy
+	}
+	if local.CAFile != "" {
+		merged.CAFile = local.CAFile
+	}
+	if local.CertFile != "" {
+		merged.CertFile = local.CertFile
+		merged.KeyFile = local.KeyFile
+	}
+	if local.InsecureSkipVerify != nil {
+		merged.InsecureSkipVerify = local.InsecureSkipVerify
+	}
+	return merged
+}
+
+// NewHTTPClient 根据配置创建 HTTP 客户端
+func NewHTTPClient(cfg config.HTTPConfig) (*http.Client, error) {
+	transport := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   30 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		ForceAttemptHTTP2:     true,
+		MaxIdleConns:          10,
+		IdleConnTimeout:       90 * time.Second,
+		TLSHandshakeTimeout:   10 * time.Second,
+		ExpectContinueTimeout: 1 * time.Second,
+	}
+
+	// 代理
+	switch cfg.Proxy {
+	case "":
+	case "direct":
+		transport.Proxy = nil
+	default:
+		proxyURL, err := url.Parse(cfg.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("无效的代理地址: %v", err)
+		}
+		switch proxyURL.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return nil, fmt.Errorf("不支持的代理协议: %s", proxyURL.Scheme)
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+	}
+
+	// TLS
+	tlsConfig := &tls.Config{}
+	if cfg.InsecureSkipVerify != nil {
+		tlsConfig.InsecureSkipVerify = *cfg.InsecureSkipVerify
+	}
+	if cfg.CAFile != "" {
+		pem, err := ioutil.ReadFile(cfg.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("读取 CA 证书失败: %v", err)
+		}
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("CA 证书文件 %s 中没有有效的证书", cfg.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("加载客户端证书失败: %v", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	timeout := cfg.Timeout.Std()
+	if timeout <= 0 {
+		timeout = DefaultHTTPTimeout
+	}
+
+	return &http.Client{
+		Transport: transport,
+		Timeout:   timeout,
+	}, nil
+}
@@ -3,13 +3,55 @@ package notifier
 import (
 	"fmt"
 	"loginfopush/config"
+	"loginfopush/logger"
+	"loginfopush/schedule"
+	"net/http"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 )
 
 // Message 消息结构
 type Message struct {
-	Title    string                 // 标题
-	Content  string                 // 内容
-	Metadata map[string]interface{} // 元数据
+	EventType config.EventType       // 事件类型
+	Severity  config.Severity        // 严重程度
+	Title     string                 // 标题
+	Content   string                 // 内容
+	Metadata  map[string]interface{} // 元数据
+}
+
+// 事件的处理结果
+const (
+	StatusSent       = "sent"        // 已发送到通知渠道
+	StatusDropped    = "dropped"     // 被路由规则丢弃
+	StatusDigest     = "digest"      // 进入汇总缓存
+	StatusSuppressed = "suppressed"  // 重复事件被抑制
+	StatusFiltered   = "filtered"    // 低于最低严重程度
+	StatusMuted      = "muted"       // 被免打扰规则丢弃
+	StatusDelayed    = "delayed"     // 被免打扰规则延迟发送，窗口结束后的发送结果只记录在日志中
+	StatusNoNotifier = "no_notifier" // 没有可用的通知渠道
+)
+
+// 单个通知渠道的发送结果
+const (
+	DeliverySent        = "sent"         // 发送成功
+	DeliveryFailed      = "failed"       // 发送失败
+	DeliveryRateLimited = "rate_limited" // 超出频率限制被跳过
+	DeliveryUnavailable = "unavailable"  // 通知渠道未启用或不存在
+)
+
+// Delivery 一个通知渠道的发送结果
+type Delivery struct {
+	Notifier string `json:"notifier"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Result 事件的处理结果
+type Result struct {
+	Status     string     `json:"status"`
+	Deliveries []Delivery `json:"deliveries,omitempty"`
 }
 
 // Notifier 通知器接口
@@ -17,8 +59,14 @@ type Notifier interface {
 	Send(msg Message) error
 }
 
+// Options 创建通知器时的公共参数
+type Options struct {
+	HTTPClient *http.Client        // 按全局与通知器配置创建的 HTTP 客户端
+	Server     config.ServerConfig // 服务器信息
+}
+
 // notifierFactory 通知器工厂函数类型
-type notifierFactory func(config interface{}) (Notifier, error)
+type notifierFactory func(config interface{}, opts Options) (Notifier, error)
 
 // 注册的通知器工厂
 var factories = make(map[config.NotifierType]notifierFactory)
@@ -28,28 +76,100 @@ func RegisterNotifier(typ config.NotifierType, factory notifierFactory) {
 	factories[typ] = factory
 }
 
-// CreateNotifier 创建通知器实例
-func CreateNotifier(cfg config.NotifierConfig) (Notifier, error) {
+// CreateNotifier 创建通知器实例，opts.HTTPClient 为空时按 cfg.HTTP 创建
+func CreateNotifier(cfg config.NotifierConfig, opts Options) (Notifier, error) {
 	factory, ok := factories[cfg.Type]
 	if !ok {
 		return nil, fmt.Errorf("未知的通知器类型: %s", cfg.Type)
 	}
 
-	return factory(cfg.Config)
+	if opts.HTTPClient == nil {
+		client, err := NewHTTPClient(MergeHTTPConfig(nil, cfg.HTTP))
+		if err != nil {
+			return nil, err
+		}
+		opts.HTTPClient = client
+	}
+
+	return factory(cfg.Config, opts)
 }
 
 // NotifierManager 通知管理器
 type NotifierManager struct {
-	notifiers map[string]Notifier
-	config    *config.Config
+	notifiers  map[string]Notifier
+	sinks      []string // 归档渠道，由 Archive 写入每个事件
+	limiters   map[string]*tokenBucket
+	dedup      *deduper
+	digests    map[config.EventType]*digestBuffer
+	quietRules []*quietRule
+	rules      []*routeRule
+	stop       chan struct{}
+	delayMu    sync.Mutex
+	delayed    []*delayedMessage // 免打扰延迟中的消息，见 delay
+	config     *config.Config
+	replay     bool // 回放日志，见 EnableReplay
 }
 
 // NewNotifierManager 创建通知管理器
 func NewNotifierManager(cfg *config.Config) (*NotifierManager, error) {
+	return newManager(cfg, func(name string, notifierCfg config.NotifierConfig) (Notifier, error) {
+		client, err := NewHTTPClient(MergeHTTPConfig(cfg.HTTP, notifierCfg.HTTP))
+		if err != nil {
+			return nil, fmt.Errorf("创建通知器 %s 的 HTTP 客户端失败: %v", name, err)
+		}
+
+		notifier, err := CreateNotifier(notifierCfg, Options{
+			HTTPClient: client,
+			Server:     cfg.Server,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("创建通知器 %s 失败: %v", name, err)
+		}
+		return notifier, nil
+	})
+}
+
+// NewDryRunManager 创建演练用的通知管理器，消息不会真正发送，而是交给 print 输出
+func NewDryRunManager(cfg *config.Config, print func(name string, msg Message)) (*NotifierManager, error) {
+	return newManager(cfg, func(name string, _ config.NotifierConfig) (Notifier, error) {
+		return dryRunNotifier{name: name, print: print}, nil
+	})
+}
+
+// dryRunNotifier 演练用的通知器
+type dryRunNotifier struct {
+	name  string
+	print func(name string, msg Message)
+}
+
+// Send 输出消息
+func (n dryRunNotifier) Send(msg Message) error {
+	n.print(n.name, msg)
+	return nil
+}
+
+// newManager 创建通知管理器，create 为每个启用的通知渠道创建通知器
+func newManager(cfg *config.Config, create func(name string, notifierCfg config.NotifierConfig) (Notifier, error)) (*NotifierManager, error) {
 	manager := &NotifierManager{
 		notifiers: make(map[string]Notifier),
+		limiters:  make(map[string]*tokenBucket),
+		digests:   make(map[config.EventType]*digestBuffer),
+		stop:      make(chan struct{}),
 		config:    cfg,
 	}
+	manager.dedup = newDeduper(cfg.Dedup, manager.sendSuppressedSummary)
+
+	quietRules, err := compileQuietRules(cfg.QuietHours)
+	if err != nil {
+		return nil, err
+	}
+	manager.quietRules = quietRules
+
+	routeRules, err := compileRules(cfg.Rules, cfg.EventFields())
+	if err != nil {
+		return nil, err
+	}
+	manager.rules = routeRules
 
 	// 初始化所有启用的通知器
 	for name, notifierCfg := range cfg.Notifiers {
@@ -57,66 +177,312 @@ func NewNotifierManager(cfg *config.Config) (*NotifierManager, error) {
 			continue
 		}
 
-		notifier, err := CreateNotifier(notifierCfg)
+		notifier, err := create(name, notifierCfg)
 		if err != nil {
-			return nil, fmt.Errorf("创建通知器 %s 失败: %v", name, err)
+			return nil, err
 		}
 
 		manager.notifiers[name] = notifier
+		manager.limiters[name] = newTokenBucket(notifierCfg.RateLimit)
+		if notifierCfg.Type.Sink() {
+			manager.sinks = append(manager.sinks, name)
+		}
+	}
+	sort.Strings(manager.sinks)
+
+	// 启动汇总推送
+	for name, evt := range cfg.Events {
+		if !evt.Enabled || evt.Digest == nil || !evt.Digest.Enabled {
+			continue
+		}
+
+		spec := evt.Digest.Schedule
+		if spec == "" {
+			spec = defaultDigestSchedule
+		}
+		sched, err := schedule.Parse(spec)
+		if err != nil {
+			return nil, fmt.Errorf("事件 %s 的汇总计划无效: %v", name, err)
+		}
+
+		buf := newDigestBuffer(evt, sched)
+		manager.digests[evt.Type] = buf
+		go schedule.Run(sched, manager.stop, func(now time.Time) {
+			if !buf.isManual() {
+				manager.flushDigest(buf, now)
+			}
+		})
 	}
 
 	return manager, nil
 }
 
-// SendEvent 发送事件通知
-func (m *NotifierManager) SendEvent(eventType config.EventType, data map[string]interface{}) error {
-	// 查找事件配置
-	var eventConfig config.EventConfig
-	for _, evt := range m.config.Events {
-		if evt.Type == eventType && evt.Enabled {
-			eventConfig = evt
-			break
+// EnableReplay 切换为回放模式，需在发送事件前调用
+//
+// 回放时免打扰延迟的消息立即发送（状态仍为 delayed），重复抑制窗口与事件汇总只按事件时间结束，
+// 由调用方通过 ExpireDedup、ExpireDigests 与 FlushDedup、FlushDigests 发送，不再启动计时器。
+func (m *NotifierManager) EnableReplay() {
+	m.replay = true
+	if m.dedup != nil {
+		m.dedup.manual = true
+	}
+	for _, buf := range m.digests {
+		buf.mu.Lock()
+		buf.manual = true
+		buf.mu.Unlock()
+	}
+}
+
+// ExpireDedup 结束按事件时间在 now 之前到期的抑制窗口并发送汇总，用于回放日志
+func (m *NotifierManager) ExpireDedup(now time.Time) {
+	m.dedup.expireBefore(now)
+}
+
+// FlushDedup 结束所有抑制窗口并发送汇总，用于回放到文件末尾时
+func (m *NotifierManager) FlushDedup() {
+	m.dedup.expireBefore(time.Time{})
+}
+
+// ExpireDigests 按事件时间发送在 now 之前到期的事件汇总，用于回放日志
+func (m *NotifierManager) ExpireDigests(now time.Time) {
+	for _, buf := range m.sortedDigests() {
+		first, last, ok := buf.expire(now)
+		if !ok {
+			continue
+		}
+		m.flushDigest(buf, first)
+		// 之后到期的周期没有事件，只需从最后一次到期的时间开始新周期
+		if last.After(first) {
+			m.flushDigest(buf, last)
 		}
 	}
+}
 
-	if eventConfig.Type == "" {
-		return fmt.Errorf("未找到事件配置或事件未启用: %s", eventType)
+// FlushDigests 立即发送所有汇总缓存中的事件，用于回放到文件末尾或退出时
+func (m *NotifierManager) FlushDigests(now time.Time) {
+	for _, buf := range m.sortedDigests() {
+		m.flushDigest(buf, now)
 	}
+}
 
-	// 准备模板数据
-	templateData := TemplateData{
-		Server:   m.config.Server,
-		IP:       data["IP"].(string),
-		Location: data["Location"].(string),
-		Time:     data["Time"].(string),
-		Details:  data["Details"].(string),
-		Raw:      data["Raw"].(string),
-		Extra:    data,
+// sortedDigests 按事件类型排序的汇总缓存，保证发送顺序稳定
+func (m *NotifierManager) sortedDigests() []*digestBuffer {
+	types := make([]string, 0, len(m.digests))
+	for t := range m.digests {
+		types = append(types, string(t))
+	}
+	sort.Strings(types)
+	bufs := make([]*digestBuffer, len(types))
+	for i, t := range types {
+		bufs[i] = m.digests[config.EventType(t)]
+	}
+	return bufs
+}
+
+// Close 停止汇总推送等后台任务，并立即发送汇总缓存中的事件、未结束的抑制窗口的汇总与免打扰延迟中的消息
+func (m *NotifierManager) Close() {
+	close(m.stop)
+	m.FlushDigests(time.Now())
+	m.FlushDedup()
+	m.FlushDelayed()
+}
+
+// SendEvent 发送事件通知，返回事件的处理结果与各通知渠道的发送结果
+func (m *NotifierManager) SendEvent(eventType config.EventType, data map[string]interface{}) (Result, error) {
+	return m.SendEventAt(eventType, data, time.Now())
+}
+
+// SendEventAt 发送在 now 发生的事件，重复抑制与免打扰规则按事件时间判断，用于回放日志
+func (m *NotifierManager) SendEventAt(eventType config.EventType, data map[string]interface{}, now time.Time) (Result, error) {
+	// 查找事件配置
+	eventConfig, ok := m.config.FindEvent(eventType)
+	if !ok {
+		return Result{}, fmt.Errorf("未找到事件配置或事件未启用: %s", eventType)
+	}
+
+	if _, ok := data["Type"]; !ok {
+		data["Type"] = string(eventType)
+	}
+
+	// 路由规则可能丢弃事件、修改严重程度与标签，或覆盖通知渠道与模板
+	result := m.applyRules(data)
+	if result.drop {
+		return Result{Status: StatusDropped}, nil
+	}
+
+	// 汇总模式下只缓存事件，由计划任务统一发送
+	if buf, ok := m.digests[eventType]; ok {
+		buf.Add(newTemplateData(m.config.Server, data))
+		return Result{Status: StatusDigest}, nil
+	}
+
+	// 低于最低严重程度的事件不推送，也不进入抑制窗口
+	severity := config.Severity(stringValue(data, "Severity"))
+	if eventConfig.MinSeverity.Valid() && severity.Rank() < eventConfig.MinSeverity.Rank() {
+		return Result{Status: StatusFiltered}, nil
+	}
+
+	// 按严重程度选择通知渠道
+	names := eventConfig.Notifiers
+	if override, ok := eventConfig.SeverityNotifiers[severity]; ok {
+		names = override
+	}
+	if result.notifiers != nil {
+		names = result.notifiers
+	}
+
+	// 抑制窗口内的重复事件，汇总发送到相同的通知渠道
+	if !m.dedup.Allow(eventConfig, names, data, now) {
+		logger.Debugf("已抑制重复事件: %s %s", eventType, stringValue(data, "IP"))
+		return Result{Status: StatusSuppressed}, nil
 	}
 
 	// 渲染模板
-	content, err := RenderTemplate(eventConfig.Template, templateData)
+	tmpl := eventConfig.Template
+	if result.template != "" {
+		tmpl = result.template
+	}
+	msg, err := m.eventMessage(eventConfig, tmpl, data)
 	if err != nil {
-		return fmt.Errorf("渲染模板失败: %v", err)
+		return Result{}, err
 	}
 
-	// 构建消息
-	msg := Message{
-		Title:    eventConfig.Title,
-		Content:  content,
-		Metadata: data,
+	// 免打扰规则可能丢弃、延迟或改变通知渠道
+	names, status := m.applyQuietHours(names, msg, now)
+	if len(names) == 0 {
+		if status == "" {
+			status = StatusNoNotifier
+		}
+		return Result{Status: status}, nil
 	}
 
-	// 发送到指定的通知渠道
+	deliveries, err := m.dispatch(names, msg, true)
+	if status == "" {
+		status = StatusSent
+	}
+	return Result{Status: status, Deliveries: deliveries}, err
+}
+
+// eventMessage 渲染事件模板并构建消息
+func (m *NotifierManager) eventMessage(eventConfig config.EventConfig, tmpl string, data map[string]interface{}) (Message, error) {
+	content, err := RenderTemplate(tmpl, newTemplateData(m.config.Server, data))
+	if err != nil {
+		return Message{}, fmt.Errorf("渲染模板失败: %v", err)
+	}
+	return Message{
+		EventType: eventConfig.Type,
+		Severity:  config.Severity(stringValue(data, "Severity")),
+		Title:     eventConfig.Title,
+		Content:   content,
+		Metadata:  data,
+	}, nil
+}
+
+// dispatch 发送到指定的通知渠道，返回各渠道的发送结果与最后一个错误；limit 为 true 时跳过超出频率限制的渠道
+//
+// 归档渠道已由 Archive 收到事件，这里直接跳过，汇总与抑制汇总也不会写入归档。
+func (m *NotifierManager) dispatch(names []string, msg Message, limit bool) ([]Delivery, error) {
 	var lastErr error
-	for _, name := range eventConfig.Notifiers {
-		if notifier, ok := m.notifiers[name]; ok {
-			if err := notifier.Send(msg); err != nil {
-				lastErr = fmt.Errorf("通知器 %s 发送失败: %v", name, err)
-				fmt.Printf("警告: %v\n", lastErr)
-			}
+	deliveries := make([]Delivery, 0, len(names))
+	for _, name := range names {
+		if m.isSink(name) {
+			continue
 		}
+		notifier, ok := m.notifiers[name]
+		if !ok {
+			deliveries = append(deliveries, Delivery{Notifier: name, Status: DeliveryUnavailable})
+			continue
+		}
+		if limit && !m.limiters[name].Allow() {
+			logger.Warnf("通知器 %s 超出发送频率限制，已跳过", name)
+			deliveries = append(deliveries, Delivery{Notifier: name, Status: DeliveryRateLimited})
+			continue
+		}
+		if err := notifier.Send(msg); err != nil {
+			lastErr = fmt.Errorf("通知器 %s 发送失败: %v", name, err)
+			logger.Warnf("%v", lastErr)
+			deliveries = append(deliveries, Delivery{Notifier: name, Status: DeliveryFailed, Error: err.Error()})
+			continue
+		}
+		deliveries = append(deliveries, Delivery{Notifier: name, Status: DeliverySent})
+	}
+
+	return deliveries, lastErr
+}
+
+// flushDigest 发送一个周期的汇总消息
+func (m *NotifierManager) flushDigest(buf *digestBuffer, now time.Time) {
+	data, ok := buf.Flush(m.config.Server, now)
+	if !ok {
+		return
+	}
+
+	digest := buf.eventConfig.Digest
+	tmpl := digest.Template
+	if tmpl == "" {
+		tmpl = DefaultDigestTemplate
+	}
+	content, err := RenderTemplate(tmpl, data)
+	if err != nil {
+		logger.Errorf("渲染汇总模板失败: %v", err)
+		return
 	}
 
-	return lastErr
+	title := digest.Title
+	if title == "" {
+		title = buf.eventConfig.Title
+	}
+
+	msg := Message{
+		EventType: buf.eventConfig.Type,
+		Title:     title,
+		Content:   content,
+		Metadata: map[string]interface{}{
+			"Type":  string(buf.eventConfig.Type),
+			"Count": data.Count,
+			"Start": data.Start,
+			"End":   data.End,
+		},
+	}
+	if err := m.deliver(buf.eventConfig.Notifiers, msg, now); err != nil {
+		logger.Errorf("发送汇总失败: %v", err)
+	}
+}
+
+// sendSuppressedSummary 抑制窗口结束时发送被抑制事件的汇总，now 为窗口结束的时间
+func (m *NotifierManager) sendSuppressedSummary(entry *dedupEntry, now time.Time) {
+	var b strings.Builder
+	fmt.Fprintf(&b, "🔁 服务器: %s (%s)\n", m.config.Server.Name, m.config.Server.Tag)
+	fmt.Fprintf(&b, "%s 内另有 %d 条相似事件已被抑制\n", m.dedup.window, entry.suppressed)
+	fmt.Fprintf(&b, "类型: %s\n", stringValue(entry.last, "Type"))
+	if ip := stringValue(entry.last, "IP"); ip != "" {
+		fmt.Fprintf(&b, "IP: %s\n", ip)
+	}
+	if user := stringValue(entry.last, "User"); user != "" {
+		fmt.Fprintf(&b, "用户: %s\n", user)
+	}
+	fmt.Fprintf(&b, "首次: %s\n", entry.first.Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&b, "最后: %s", stringValue(entry.last, "Time"))
+
+	msg := Message{
+		EventType: entry.eventConfig.Type,
+		Title:     entry.eventConfig.Title,
+		Content:   b.String(),
+		Severity:  config.Severity(stringValue(entry.last, "Severity")),
+		Metadata:  entry.last,
+	}
+	if err := m.deliver(entry.notifiers, msg, now); err != nil {
+		logger.Errorf("发送抑制汇总失败: %v", err)
+	}
+}
+
+// deliver 按免打扰规则发送汇总与报告消息，被丢弃或延迟时不返回错误
+func (m *NotifierManager) deliver(names []string, msg Message, now time.Time) error {
+	names, status := m.applyQuietHours(names, msg, now)
+	if len(names) == 0 {
+		logger.Debugf("%s 汇总未立即发送: %s", msg.EventType, status)
+		return nil
+	}
+	_, err := m.dispatch(names, msg, true)
+	return err
 }
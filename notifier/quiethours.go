@@ -0,0 +1,218 @@
+package notifier
+
+import (
+	"fmt"
+	"loginfopush/config"
+	"loginfopush/logger"
+	"sort"
+	"strings"
+	"time"
+)
+
+// quietRule 编译后的免打扰规则
+type quietRule struct {
+	index     int
+	start     int // 开始时间（当天的分钟数）
+	end       int // 结束时间（当天的分钟数）
+	loc       *time.Location
+	days      map[time.Weekday]bool
+	types     map[config.EventType]bool
+	severity  map[config.Severity]bool
+	action    string
+	notifiers []string
+}
+
+// compileQuietRules 校验并编译免打扰规则
+func compileQuietRules(rules []config.QuietHoursRule) ([]*quietRule, error) {
+	compiled := make([]*quietRule, 0, len(rules))
+	for i, r := range rules {
+		rule := &quietRule{
+			index:     i,
+			loc:       time.Local,
+			action:    r.Action,
+			notifiers: r.Notifiers,
+		}
+
+		var err error
+		if rule.start, err = config.ParseClock(r.Start); err != nil {
+			return nil, fmt.Errorf("quiet_hours[%d].start: %v", i, err)
+		}
+		if rule.end, err = config.ParseClock(r.End); err != nil {
+			return nil, fmt.Errorf("quiet_hours[%d].end: %v", i, err)
+		}
+		if rule.start == rule.end {
+			return nil, fmt.Errorf("quiet_hours[%d]: 开始时间与结束时间不能相同", i)
+		}
+
+		if r.Timezone != "" {
+			if rule.loc, err = time.LoadLocation(r.Timezone); err != nil {
+				return nil, fmt.Errorf("quiet_hours[%d].timezone: %v", i, err)
+			}
+		}
+
+		if len(r.Days) > 0 {
+			rule.days = make(map[time.Weekday]bool)
+			for _, d := range r.Days {
+				wd, ok := config.Weekdays[strings.ToLower(d)]
+				if !ok {
+					return nil, fmt.Errorf("quiet_hours[%d].days: 无效的星期 %q", i, d)
+				}
+				rule.days[wd] = true
+			}
+		}
+
+		if len(r.EventTypes) > 0 {
+			rule.types = make(map[config.EventType]bool)
+			for _, t := range r.EventTypes {
+				rule.types[t] = true
+			}
+		}
+
+		if len(r.Severities) > 0 {
+			rule.severity = make(map[config.Severity]bool)
+			for _, s := range r.Severities {
+				if !s.Valid() {
+					return nil, fmt.Errorf("quiet_hours[%d].severities: 无效的严重程度 %q", i, s)
+				}
+				rule.severity[s] = true
+			}
+		}
+
+		switch r.Action {
+		case config.QuietActionMute, config.QuietActionDelay:
+		case config.QuietActionReroute:
+			if len(r.Notifiers) == 0 {
+				return nil, fmt.Errorf("quiet_hours[%d]: reroute 需要配置 notifiers", i)
+			}
+		default:
+			return nil, fmt.Errorf("quiet_hours[%d].action: 不支持的动作 %q", i, r.Action)
+		}
+
+		compiled = append(compiled, rule)
+	}
+	return compiled, nil
+}
+
+// matches 判断消息是否命中规则，命中时返回本次时间窗口的结束时间
+func (r *quietRule) matches(msg Message, now time.Time) (time.Time, bool) {
+	if r.types != nil && !r.types[msg.EventType] {
+		return time.Time{}, false
+	}
+	if r.severity != nil && !r.severity[msg.Severity] {
+		return time.Time{}, false
+	}
+
+	local := now.In(r.loc)
+	minute := local.Hour()*60 + local.Minute()
+	today := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, r.loc)
+
+	// windowStart 为本次窗口开始所在的日期
+	var windowStart time.Time
+	switch {
+	case r.start < r.end && minute >= r.start && minute < r.end:
+		windowStart = today
+	case r.start > r.end && minute >= r.start:
+		windowStart = today
+	case r.start > r.end && minute < r.end:
+		windowStart = today.AddDate(0, 0, -1)
+	default:
+		return time.Time{}, false
+	}
+
+	if r.days != nil && !r.days[windowStart.Weekday()] {
+		return time.Time{}, false
+	}
+
+	endDay := windowStart
+	if r.start > r.end {
+		endDay = windowStart.AddDate(0, 0, 1)
+	}
+	end := time.Date(endDay.Year(), endDay.Month(), endDay.Day(), r.end/60, r.end%60, 0, 0, r.loc)
+	return end, true
+}
+
+// applyQuietHours 按免打扰规则处理消息，返回实际需要立即发送的通知渠道，被丢弃或延迟时同时返回原因
+func (m *NotifierManager) applyQuietHours(names []string, msg Message, now time.Time) ([]string, string) {
+	for _, rule := range m.quietRules {
+		end, ok := rule.matches(msg, now)
+		if !ok {
+			continue
+		}
+
+		switch rule.action {
+		case config.QuietActionMute:
+			logger.Debugf("免打扰规则 %d 生效，已丢弃 %s 事件通知", rule.index, msg.EventType)
+			return nil, StatusMuted
+		case config.QuietActionDelay:
+			if m.replay {
+				// 回放结束后进程即退出，延迟的消息等不到窗口结束，改为立即发送
+				logger.Debugf("免打扰规则 %d 生效，回放时 %s 事件通知立即发送", rule.index, msg.EventType)
+				return names, StatusDelayed
+			}
+			logger.Debugf("免打扰规则 %d 生效，%s 事件通知将于 %s 发送", rule.index, msg.EventType, end.Format("2006-01-02 15:04"))
+			m.delay(names, msg, end, now)
+			return nil, StatusDelayed
+		case config.QuietActionReroute:
+			return rule.notifiers, ""
+		}
+	}
+	return names, ""
+}
+
+// delayedMessage 被免打扰规则延迟、等待窗口结束后发送的消息
+type delayedMessage struct {
+	names []string
+	msg   Message
+	due   time.Time // 窗口结束时间
+	timer *time.Timer
+}
+
+// delay 在窗口结束时间 end 发送消息，now 为事件发生时间；进程退出前未发送的消息由 Close 立即发送
+func (m *NotifierManager) delay(names []string, msg Message, end, now time.Time) {
+	dm := &delayedMessage{names: names, msg: msg, due: end}
+
+	m.delayMu.Lock()
+	defer m.delayMu.Unlock()
+	m.delayed = append(m.delayed, dm)
+	dm.timer = time.AfterFunc(end.Sub(now), func() {
+		if m.takeDelayed(dm) {
+			m.sendDelayed(dm)
+		}
+	})
+}
+
+// takeDelayed 从等待列表中取出消息，已被 FlushDelayed 取出时返回 false
+func (m *NotifierManager) takeDelayed(dm *delayedMessage) bool {
+	m.delayMu.Lock()
+	defer m.delayMu.Unlock()
+	for i, p := range m.delayed {
+		if p == dm {
+			m.delayed = append(m.delayed[:i], m.delayed[i+1:]...)
+			return true
+		}
+	}
+	return false
+}
+
+// sendDelayed 发送延迟的消息
+//
+// 窗口结束时积压的消息集中发送，不受频率限制，避免被跳过而丢失。
+func (m *NotifierManager) sendDelayed(dm *delayedMessage) {
+	if _, err := m.dispatch(dm.names, dm.msg, false); err != nil {
+		logger.Errorf("发送延迟通知失败: %v", err)
+	}
+}
+
+// FlushDelayed 立即按原定发送时间的顺序发送所有延迟中的消息
+func (m *NotifierManager) FlushDelayed() {
+	m.delayMu.Lock()
+	pending := m.delayed
+	m.delayed = nil
+	m.delayMu.Unlock()
+
+	sort.SliceStable(pending, func(i, j int) bool { return pending[i].due.Before(pending[j].due) })
+	for _, dm := range pending {
+		dm.timer.Stop()
+		m.sendDelayed(dm)
+	}
+}
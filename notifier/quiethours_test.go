@@ -0,0 +1,197 @@
+package notifier
+
+import (
+	"loginfopush/config"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestQuietRuleMatches(t *testing.T) {
+	loc := time.Local
+	// 2024-01-01 为星期一
+	at := func(day, hour, minute int) time.Time {
+		return time.Date(2024, 1, day, hour, minute, 0, 0, loc)
+	}
+
+	tests := []struct {
+		name string
+		rule config.QuietHoursRule
+		msg  Message
+		now  time.Time
+		end  time.Time // 零值表示不命中
+	}{
+		{"当天窗口开始", config.QuietHoursRule{Start: "12:00", End: "13:30"}, Message{}, at(1, 12, 0), at(1, 13, 30)},
+		{"当天窗口内", config.QuietHoursRule{Start: "12:00", End: "13:30"}, Message{}, at(1, 13, 29), at(1, 13, 30)},
+		{"当天窗口结束", config.QuietHoursRule{Start: "12:00", End: "13:30"}, Message{}, at(1, 13, 30), time.Time{}},
+		{"当天窗口之前", config.QuietHoursRule{Start: "12:00", End: "13:30"}, Message{}, at(1, 11, 59), time.Time{}},
+
+		{"跨天窗口午夜前", config.QuietHoursRule{Start: "22:00", End: "07:00"}, Message{}, at(1, 23, 30), at(2, 7, 0)},
+		{"跨天窗口午夜", config.QuietHoursRule{Start: "22:00", End: "07:00"}, Message{}, at(2, 0, 0), at(2, 7, 0)},
+		{"跨天窗口午夜后", config.QuietHoursRule{Start: "22:00", End: "07:00"}, Message{}, at(2, 6, 59), at(2, 7, 0)},
+		{"跨天窗口结束", config.QuietHoursRule{Start: "22:00", End: "07:00"}, Message{}, at(2, 7, 0), time.Time{}},
+		{"跨天窗口白天", config.QuietHoursRule{Start: "22:00", End: "07:00"}, Message{}, at(2, 12, 0), time.Time{}},
+		{"跨月", config.QuietHoursRule{Start: "22:00", End: "07:00"}, Message{}, time.Date(2024, 1, 31, 23, 0, 0, 0, loc), time.Date(2024, 2, 1, 7, 0, 0, 0, loc)},
+
+		// 星期按窗口开始的日期判断
+		{"星期一开始的窗口在星期二凌晨", config.QuietHoursRule{Start: "22:00", End: "07:00", Days: []string{"mon"}}, Message{}, at(2, 3, 0), at(2, 7, 0)},
+		{"星期一开始的窗口在星期一凌晨", config.QuietHoursRule{Start: "22:00", End: "07:00", Days: []string{"mon"}}, Message{}, at(1, 3, 0), time.Time{}},
+		{"星期二晚上", config.QuietHoursRule{Start: "22:00", End: "07:00", Days: []string{"mon"}}, Message{}, at(2, 23, 0), time.Time{}},
+		{"周末", config.QuietHoursRule{Start: "00:00", End: "23:59", Days: []string{"SAT", "sun"}}, Message{}, at(7, 10, 0), at(7, 23, 59)},
+
+		{"事件类型", config.QuietHoursRule{Start: "22:00", End: "07:00", EventTypes: []config.EventType{config.EventTypeFailure}}, Message{EventType: config.EventTypeFailure}, at(1, 23, 0), at(2, 7, 0)},
+		{"其他事件类型", config.QuietHoursRule{Start: "22:00", End: "07:00", EventTypes: []config.EventType{config.EventTypeFailure}}, Message{EventType: config.EventTypeSuccess}, at(1, 23, 0), time.Time{}},
+		{"严重程度", config.QuietHoursRule{Start: "22:00", End: "07:00", Severities: []config.Severity{config.SeverityInfo}}, Message{Severity: config.SeverityInfo}, at(1, 23, 0), at(2, 7, 0)},
+		{"其他严重程度", config.QuietHoursRule{Start: "22:00", End: "07:00", Severities: []config.Severity{config.SeverityInfo}}, Message{Severity: config.SeverityCritical}, at(1, 23, 0), time.Time{}},
+
+		// 规则按 UTC 判断，东八区 07:30 为 UTC 前一天 23:30
+		{"时区", config.QuietHoursRule{Start: "22:00", End: "07:00", Timezone: "UTC"}, Message{}, time.Date(2024, 1, 2, 7, 30, 0, 0, time.FixedZone("CST", 8*3600)), time.Date(2024, 1, 2, 7, 0, 0, 0, time.UTC)},
+	}
+	for _, tt := range tests {
+		rule := tt.rule
+		rule.Action = config.QuietActionMute
+		rules, err := compileQuietRules([]config.QuietHoursRule{rule})
+		if err != nil {
+			t.Errorf("%s: compileQuietRules() = %v", tt.name, err)
+			continue
+		}
+		end, ok := rules[0].matches(tt.msg, tt.now)
+		if ok != !tt.end.IsZero() || !end.Equal(tt.end) {
+			t.Errorf("%s: matches(%s) = %s %v, want %s", tt.name, tt.now.Format("Mon 15:04"), end, ok, tt.end)
+		}
+	}
+}
+
+func TestCompileQuietRulesErrors(t *testing.T) {
+	tests := []config.QuietHoursRule{
+		{Start: "25:00", End: "07:00", Action: config.QuietActionMute},
+		{Start: "07:00", End: "07:00", Action: config.QuietActionMute},
+		{Start: "22:00", End: "07:00", Days: []string{"monday"}, Action: config.QuietActionMute},
+		{Start: "22:00", End: "07:00", Timezone: "Nowhere/City", Action: config.QuietActionMute},
+		{Start: "22:00", End: "07:00", Severities: []config.Severity{"urgent"}, Action: config.QuietActionMute},
+		{Start: "22:00", End: "07:00", Action: config.QuietActionReroute},
+		{Start: "22:00", End: "07:00", Action: "snooze"},
+	}
+	for _, rule := range tests {
+		if _, err := compileQuietRules([]config.QuietHoursRule{rule}); err == nil {
+			t.Errorf("compileQuietRules(%+v) 没有返回错误", rule)
+		}
+	}
+}
+
+// quietConfig 只有一个 fail 事件与指定免打扰规则的配置
+func quietConfig(rules ...config.QuietHoursRule) *config.Config {
+	return &config.Config{
+		Notifiers:  testNotifiers("phone", "mail"),
+		QuietHours: rules,
+		Events: map[string]config.EventConfig{
+			"fail":   {Type: config.EventTypeFailure, Enabled: true, Template: "{{.IP}}", Notifiers: []string{"phone"}},
+			"report": {Type: config.EventTypeReport, Enabled: true, Template: "日报", Notifiers: []string{"phone"}},
+		},
+	}
+}
+
+func TestApplyQuietHours(t *testing.T) {
+	// 2024-01-01 23:00 处于 22:00 ~ 07:00 的窗口内
+	night := time.Date(2024, 1, 1, 23, 0, 0, 0, time.Local)
+	tests := []struct {
+		action    string
+		notifiers []string
+		status    string
+		sent      []string
+	}{
+		{config.QuietActionMute, nil, StatusMuted, nil},
+		{config.QuietActionReroute, []string{"mail"}, StatusSent, []string{"mail 203.0.113.7"}},
+		{config.QuietActionDelay, nil, StatusDelayed, nil},
+	}
+	for _, tt := range tests {
+		m, r := newTestManager(t, quietConfig(config.QuietHoursRule{Start: "22:00", End: "07:00", Action: tt.action, Notifiers: tt.notifiers}))
+		result, err := m.SendEventAt(config.EventTypeFailure, failEvent("203.0.113.7", "root", ""), night)
+		if err != nil || result.Status != tt.status {
+			t.Errorf("%s: SendEventAt() = %s %v, want %s", tt.action, result.Status, err, tt.status)
+		}
+		if got := r.take(); !reflect.DeepEqual(got, tt.sent) {
+			t.Errorf("%s: sent = %q, want %q", tt.action, got, tt.sent)
+		}
+
+		// 窗口外正常发送
+		result, _ = m.SendEventAt(config.EventTypeFailure, failEvent("203.0.113.8", "root", ""), night.Add(9*time.Hour))
+		if got := r.take(); result.Status != StatusSent || !reflect.DeepEqual(got, []string{"phone 203.0.113.8"}) {
+			t.Errorf("%s: 窗口外 = %s %q", tt.action, result.Status, got)
+		}
+	}
+}
+
+func TestDelayedMessages(t *testing.T) {
+	night := time.Date(2024, 1, 1, 23, 0, 0, 0, time.Local)
+	cfg := quietConfig(config.QuietHoursRule{Start: "22:00", End: "07:00", Action: config.QuietActionDelay})
+	phone := cfg.Notifiers["phone"]
+	phone.RateLimit = &config.RateLimitConfig{Rate: 1, Burst: 1}
+	cfg.Notifiers["phone"] = phone
+
+	r := &recorder{}
+	m, err := newManager(cfg, r.create)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i, ip := range []string{"203.0.113.7", "203.0.113.8", "203.0.113.9"} {
+		result, _ := m.SendEventAt(config.EventTypeFailure, failEvent(ip, "root", ""), night.Add(time.Duration(i)*time.Minute))
+		if result.Status != StatusDelayed {
+			t.Errorf("SendEventAt(%s) = %s, want delayed", ip, result.Status)
+		}
+	}
+	if got := r.take(); len(got) != 0 {
+		t.Errorf("窗口结束前发送了 %q", got)
+	}
+
+	// 退出时立即发送延迟中的消息，不受频率限制
+	m.Close()
+	want := []string{"phone 203.0.113.7", "phone 203.0.113.8", "phone 203.0.113.9"}
+	if got := r.take(); !reflect.DeepEqual(got, want) {
+		t.Errorf("Close() 后 sent = %q, want %q", got, want)
+	}
+	m.FlushDelayed()
+	if got := r.take(); len(got) != 0 {
+		t.Errorf("重复发送了 %q", got)
+	}
+}
+
+func TestDelayedMessageTimer(t *testing.T) {
+	m, r := newTestManager(t, quietConfig())
+	now := time.Now()
+	m.delay([]string{"phone"}, Message{Content: "延迟"}, now.Add(10*time.Millisecond), now)
+
+	deadline := time.Now().Add(2 * time.Second)
+	for time.Now().Before(deadline) {
+		r.mu.Lock()
+		n := len(r.sent)
+		r.mu.Unlock()
+		if n > 0 {
+			break
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	if got := r.take(); !reflect.DeepEqual(got, []string{"phone 延迟"}) {
+		t.Errorf("sent = %q, want [phone 延迟]", got)
+	}
+	m.delayMu.Lock()
+	pending := len(m.delayed)
+	m.delayMu.Unlock()
+	if pending != 0 {
+		t.Errorf("发送后仍有 %d 条延迟消息", pending)
+	}
+}
+
+func TestReportQuietHours(t *testing.T) {
+	// 两条规则覆盖全天，报告总是被丢弃
+	m, r := newTestManager(t, quietConfig(
+		config.QuietHoursRule{Start: "00:00", End: "12:00", EventTypes: []config.EventType{config.EventTypeReport}, Action: config.QuietActionMute},
+		config.QuietHoursRule{Start: "12:00", End: "00:00", EventTypes: []config.EventType{config.EventTypeReport}, Action: config.QuietActionMute},
+	))
+	if err := m.SendReport(ReportData{}); err != nil {
+		t.Fatal(err)
+	}
+	if got := r.take(); len(got) != 0 {
+		t.Errorf("免打扰时发送了报告 %q", got)
+	}
+}
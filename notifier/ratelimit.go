@@ -0,0 +1,65 @@
+package notifier
+
+import (
+	"loginfopush/config"
+	"sync"
+	"time"
+)
+
+// tokenBucket 令牌桶限流器
+type tokenBucket struct {
+	mu       sync.Mutex
+	rate     float64 // 每秒补充的令牌数
+	capacity float64
+	tokens   float64
+	last     time.Time
+}
+
+// newTokenBucket 根据配置创建令牌桶，未配置或 rate 不大于 0 时返回 nil 表示不限流
+func newTokenBucket(cfg *config.RateLimitConfig) *tokenBucket {
+	if cfg == nil || cfg.Rate <= 0 {
+		return nil
+	}
+
+	capacity := float64(cfg.Burst)
+	if capacity <= 0 {
+		capacity = cfg.Rate
+	}
+	if capacity < 1 {
+		capacity = 1
+	}
+
+	return &tokenBucket{
+		rate:     cfg.Rate / 60,
+		capacity: capacity,
+		tokens:   capacity,
+		last:     time.Now(),
+	}
+}
+
+// Allow 尝试消耗一个令牌
+func (b *tokenBucket) Allow() bool {
+	return b.allowAt(time.Now())
+}
+
+// allowAt 按 now 补充令牌后尝试消耗一个令牌
+func (b *tokenBucket) allowAt(now time.Time) bool {
+	if b == nil {
+		return true
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.tokens += now.Sub(b.last).Seconds() * b.rate
+	if b.tokens > b.capacity {
+		b.tokens = b.capacity
+	}
+	b.last = now
+
+	if b.tokens < 1 {
+		return false
+	}
+	b.tokens--
+	return true
+}
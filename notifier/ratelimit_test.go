@@ -0,0 +1,91 @@
+package notifier
+
+import (
+	"loginfopush/config"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestTokenBucket(t *testing.T) {
+	if b := newTokenBucket(nil); b != nil || !b.Allow() {
+		t.Error("未配置限流时应不限流")
+	}
+	if b := newTokenBucket(&config.RateLimitConfig{Rate: 0, Burst: 5}); b != nil {
+		t.Error("rate 为 0 时应不限流")
+	}
+
+	start := time.Date(2024, 1, 1, 10, 0, 0, 0, time.Local)
+	tests := []struct {
+		name  string
+		cfg   config.RateLimitConfig
+		steps []time.Duration // 每次尝试相对 start 的时间
+		want  []bool
+	}{
+		{
+			// 每分钟 6 条即每 10 秒补充一个令牌
+			name:  "突发后按速率补充",
+			cfg:   config.RateLimitConfig{Rate: 6, Burst: 2},
+			steps: []time.Duration{0, 0, 0, 5 * time.Second, 10 * time.Second, 15 * time.Second, 20 * time.Second},
+			want:  []bool{true, true, false, false, true, false, true},
+		},
+		{
+			name:  "空闲后最多积累突发容量",
+			cfg:   config.RateLimitConfig{Rate: 6, Burst: 2},
+			steps: []time.Duration{time.Hour, time.Hour, time.Hour},
+			want:  []bool{true, true, false},
+		},
+		{
+			name:  "突发容量默认与速率相同",
+			cfg:   config.RateLimitConfig{Rate: 3},
+			steps: []time.Duration{0, 0, 0, 0, 20 * time.Second},
+			want:  []bool{true, true, true, false, true},
+		},
+		{
+			name:  "突发容量至少为 1",
+			cfg:   config.RateLimitConfig{Rate: 0.5},
+			steps: []time.Duration{0, 0, time.Minute, 2 * time.Minute},
+			want:  []bool{true, false, false, true},
+		},
+	}
+	for _, tt := range tests {
+		b := newTokenBucket(&tt.cfg)
+		b.last = start
+		for i, step := range tt.steps {
+			if got := b.allowAt(start.Add(step)); got != tt.want[i] {
+				t.Errorf("%s: allowAt(+%s) #%d = %v, want %v", tt.name, step, i, got, tt.want[i])
+			}
+		}
+	}
+}
+
+func TestDispatchRateLimited(t *testing.T) {
+	notifiers := testNotifiers("phone", "mail")
+	phone := notifiers["phone"]
+	phone.RateLimit = &config.RateLimitConfig{Rate: 1, Burst: 1}
+	notifiers["phone"] = phone
+
+	m, r := newTestManager(t, &config.Config{
+		Notifiers: notifiers,
+		Events: map[string]config.EventConfig{
+			"fail": {Type: config.EventTypeFailure, Enabled: true, Template: "{{.IP}}", Notifiers: []string{"phone", "mail"}},
+		},
+	})
+
+	want := [][]Delivery{
+		{{Notifier: "phone", Status: DeliverySent}, {Notifier: "mail", Status: DeliverySent}},
+		{{Notifier: "phone", Status: DeliveryRateLimited}, {Notifier: "mail", Status: DeliverySent}},
+	}
+	for i := range want {
+		result, err := m.SendEvent(config.EventTypeFailure, failEvent("203.0.113.7", "root", ""))
+		if err != nil || result.Status != StatusSent {
+			t.Fatalf("SendEvent() = %+v, %v", result, err)
+		}
+		if !reflect.DeepEqual(result.Deliveries, want[i]) {
+			t.Errorf("#%d deliveries = %+v, want %+v", i, result.Deliveries, want[i])
+		}
+	}
+	if got := r.take(); len(got) != 3 {
+		t.Errorf("sent = %q, want 3 条", got)
+	}
+}
@@ -0,0 +1,88 @@
+package notifier
+
+import (
+	"fmt"
+	"loginfopush/config"
+	"loginfopush/fail2ban"
+	"time"
+)
+
+// DefaultReportTemplate 内置安全报告模板
+const DefaultReportTemplate = `📋 服务器: {{.Server.Name}} ({{.Server.Tag}}) 安全日报
+时间: {{.Start}} ~ {{.End}}
+成功登录: {{.LoginTotal}} 次{{range .Logins}}
+  {{.Name}} × {{.Count}}{{end}}
+登录失败: {{.Failures}} 次
+封禁: {{.Bans}} 次
+来源 IP: {{.DistinctIPs}} 个{{if .TopCountries}}
+攻击来源地区:{{range .TopCountries}}
+  {{.Name}} × {{.Count}}{{end}}{{end}}{{if .TopASNs}}
+攻击来源 ASN:{{range .TopASNs}}
+  {{.Name}} × {{.Count}}{{end}}{{end}}{{if .NewUsers}}
+新登录用户: {{range $i, $u := .NewUsers}}{{if $i}}, {{end}}{{$u}}{{end}}{{end}}{{if .Jails}}
+fail2ban 当前封禁:{{range .Jails}}
+  {{.Name}}: {{.CurrentlyBanned}} 个 IP（累计 {{.TotalBanned}}）{{end}}{{end}}`
+
+// ReportData 安全报告模板数据
+type ReportData struct {
+	Server       config.ServerConfig   // 服务器信息
+	Start        string                // 统计开始时间
+	End          string                // 统计结束时间
+	LoginTotal   int                   // 成功登录次数
+	Logins       []CountItem           // 按用户统计的成功登录次数
+	Failures     int                   // 登录失败次数
+	Bans         int                   // 封禁次数
+	DistinctIPs  int                   // 不同来源 IP 数
+	TopIPs       []CountItem           // 出现最多的 IP
+	TopCountries []CountItem           // 失败与封禁事件中出现最多的国家/地区
+	TopASNs      []CountItem           // 失败与封禁事件中出现最多的 ASN
+	NewUsers     []string              // 首次成功登录的用户
+	Jails        []fail2ban.JailStatus // fail2ban 各 jail 的实时状态，未启用控制套接字时为空
+}
+
+// SendReport 发送定时安全报告，使用 report 类型的事件配置
+func (m *NotifierManager) SendReport(data ReportData) error {
+	eventConfig, ok := m.config.FindEvent(config.EventTypeReport)
+	if !ok {
+		return fmt.Errorf("未找到事件配置或事件未启用: %s", config.EventTypeReport)
+	}
+
+	msg, err := m.reportMessage(eventConfig, data)
+	if err != nil {
+		return err
+	}
+	return m.deliver(eventConfig.Notifiers, msg, time.Now())
+}
+
+// RenderReport 按 report 事件配置的模板渲染安全报告，事件未启用时同样渲染
+func (m *NotifierManager) RenderReport(data ReportData) (Message, error) {
+	eventConfig, ok := m.config.LookupEvent(config.EventTypeReport)
+	if !ok {
+		return Message{}, fmt.Errorf("未找到事件配置: %s", config.EventTypeReport)
+	}
+	return m.reportMessage(eventConfig, data)
+}
+
+// reportMessage 渲染安全报告消息
+func (m *NotifierManager) reportMessage(eventConfig config.EventConfig, data ReportData) (Message, error) {
+	data.Server = m.config.Server
+	tmpl := eventConfig.Template
+	if tmpl == "" {
+		tmpl = DefaultReportTemplate
+	}
+	content, err := RenderTemplate(tmpl, data)
+	if err != nil {
+		return Message{}, fmt.Errorf("渲染报告模板失败: %v", err)
+	}
+
+	return Message{
+		EventType: config.EventTypeReport,
+		Title:     eventConfig.Title,
+		Content:   content,
+		Metadata: map[string]interface{}{
+			"Type":  string(config.EventTypeReport),
+			"Start": data.Start,
+			"End":   data.End,
+		},
+	}, nil
+}
@@ -0,0 +1,92 @@
+package notifier
+
+import (
+	"fmt"
+	"loginfopush/config"
+	"loginfopush/logger"
+	"loginfopush/rules"
+)
+
+// routeRule 编译后的路由规则
+type routeRule struct {
+	index int
+	expr  *rules.Expr
+	cfg   config.RuleConfig
+}
+
+// ruleResult 规则对单个事件的处理结果
+type ruleResult struct {
+	drop      bool     // 事件被丢弃
+	notifiers []string // 覆盖的通知渠道，为空表示不覆盖
+	template  string   // 覆盖的消息模板，为空表示不覆盖
+}
+
+// compileRules 编译路由规则
+func compileRules(cfgs []config.RuleConfig, fields []string) ([]*routeRule, error) {
+	compiled := make([]*routeRule, 0, len(cfgs))
+	for i, r := range cfgs {
+		expr, err := rules.Compile(r.When, fields)
+		if err != nil {
+			return nil, fmt.Errorf("rules[%d].when: %v", i, err)
+		}
+		compiled = append(compiled, &routeRule{index: i, expr: expr, cfg: r})
+	}
+	return compiled, nil
+}
+
+// applyRules 按顺序执行路由规则，严重程度与标签直接写回事件数据，后续规则可以看到前面规则的修改
+func (m *NotifierManager) applyRules(data map[string]interface{}) ruleResult {
+	var result ruleResult
+	for _, rule := range m.rules {
+		ok, err := rule.expr.Eval(data)
+		if err != nil {
+			logger.Warnf("规则 %d 求值失败: %v", rule.index, err)
+			continue
+		}
+		if !ok {
+			continue
+		}
+
+		if rule.cfg.Drop {
+			logger.Debugf("规则 %d 生效，已丢弃 %s 事件", rule.index, stringValue(data, "Type"))
+			return ruleResult{drop: true}
+		}
+		if rule.cfg.Severity != "" {
+			data["Severity"] = string(rule.cfg.Severity)
+		}
+		if len(rule.cfg.Tags) > 0 {
+			data["Tags"] = appendTags(tagsOf(data), rule.cfg.Tags)
+		}
+		if len(rule.cfg.Notifiers) > 0 {
+			result.notifiers = rule.cfg.Notifiers
+		}
+		if rule.cfg.Template != "" {
+			result.template = rule.cfg.Template
+		}
+		if rule.cfg.Stop {
+			break
+		}
+	}
+	return result
+}
+
+// tagsOf 读取事件数据中的标签
+func tagsOf(data map[string]interface{}) []string {
+	tags, _ := data["Tags"].([]string)
+	return tags
+}
+
+// appendTags 追加标签并去重
+func appendTags(tags []string, add []string) []string {
+	seen := make(map[string]bool, len(tags))
+	for _, t := range tags {
+		seen[t] = true
+	}
+	for _, t := range add {
+		if !seen[t] {
+			seen[t] = true
+			tags = append(tags, t)
+		}
+	}
+	return tags
+}
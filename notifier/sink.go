@@ -0,0 +1,62 @@
+package notifier
+
+import (
+	"fmt"
+	"loginfopush/config"
+	"loginfopush/logger"
+)
+
+// isSink 判断通知渠道是否为归档渠道
+func (m *NotifierManager) isSink(name string) bool {
+	for _, sink := range m.sinks {
+		if sink == name {
+			return true
+		}
+	}
+	return false
+}
+
+// Archive 将事件写入所有启用的归档渠道（File、Syslog），每个事件一条记录
+//
+// 在推送流程之前调用，不受事件是否启用、路由规则、去重、最低严重程度、免打扰、汇总与频率限制影响。
+// 事件有配置时按事件模板渲染消息，否则使用事件详情。
+func (m *NotifierManager) Archive(eventType config.EventType, data map[string]interface{}) ([]Delivery, error) {
+	if len(m.sinks) == 0 {
+		return nil, nil
+	}
+
+	record := make(map[string]interface{}, len(data)+1)
+	for k, v := range data {
+		record[k] = v
+	}
+	if _, ok := record["Type"]; !ok {
+		record["Type"] = string(eventType)
+	}
+
+	msg := Message{
+		EventType: eventType,
+		Severity:  config.Severity(stringValue(record, "Severity")),
+		Content:   stringValue(record, "Details"),
+		Metadata:  record,
+	}
+	if eventConfig, ok := m.config.LookupEvent(eventType); ok {
+		if rendered, err := m.eventMessage(eventConfig, eventConfig.Template, record); err == nil {
+			msg = rendered
+		} else {
+			logger.Warnf("归档事件 %s 时%v，使用事件详情", eventType, err)
+			msg.Title = eventConfig.Title
+		}
+	}
+
+	var lastErr error
+	deliveries := make([]Delivery, 0, len(m.sinks))
+	for _, name := range m.sinks {
+		if err := m.notifiers[name].Send(msg); err != nil {
+			lastErr = fmt.Errorf("归档渠道 %s 写入失败: %v", name, err)
+			deliveries = append(deliveries, Delivery{Notifier: name, Status: DeliveryFailed, Error: err.Error()})
+			continue
+		}
+		deliveries = append(deliveries, Delivery{Notifier: name, Status: DeliverySent})
+	}
+	return deliveries, lastErr
+}
@@ -0,0 +1,288 @@
+package syslog
+
+import (
+	"crypto/tls"
+	"fmt"
+	"loginfopush/config"
+	"loginfopush/notifier"
+	"net"
+	"net/http"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+func init() {
+	notifier.RegisterNotifier(config.NotifierTypeSyslog, NewSyslogNotifier)
+}
+
+// 默认值
+const (
+	defaultSocket  = "/dev/log"
+	defaultAppName = "loginfopush"
+	// sdID 结构化数据 ID，32473 为 RFC 5612 保留给文档示例的企业号
+	sdID = "loginfopush@32473"
+)
+
+// facilities 设施名称与编号
+var facilities = map[string]int{
+	"user":     1,
+	"daemon":   3,
+	"auth":     4,
+	"authpriv": 10,
+	"local0":   16,
+	"local1":   17,
+	"local2":   18,
+	"local3":   19,
+	"local4":   20,
+	"local5":   21,
+	"local6":   22,
+	"local7":   23,
+}
+
+// SyslogNotifier 以 RFC 5424 格式发送 syslog 消息
+type SyslogNotifier struct {
+	mu       sync.Mutex
+	network  string
+	address  string
+	facility int
+	appName  string
+	hostname string
+	timeout  time.Duration
+	tls      *tls.Config
+	conn     net.Conn
+}
+
+// NewSyslogNotifier 创建 Syslog 通知器
+func NewSyslogNotifier(cfg interface{}, opts notifier.Options) (notifier.Notifier, error) {
+	syslogConfig, ok := cfg.(config.SyslogConfig)
+	if !ok {
+		return nil, fmt.Errorf("无效的 Syslog 配置")
+	}
+
+	n := &SyslogNotifier{
+		network:  syslogConfig.Network,
+		address:  syslogConfig.Address,
+		appName:  syslogConfig.AppName,
+		hostname: syslogConfig.Hostname,
+		timeout:  notifier.DefaultHTTPTimeout,
+	}
+	switch n.network {
+	case "":
+		n.network = "unix"
+		fallthrough
+	case "unix":
+		if n.address == "" {
+			n.address = defaultSocket
+		}
+	case "udp", "tcp", "tls":
+		if n.address == "" {
+			return nil, fmt.Errorf("Syslog 通知器需要配置 address")
+		}
+	default:
+		return nil, fmt.Errorf("不支持的 syslog 传输方式: %s", n.network)
+	}
+
+	facility := syslogConfig.Facility
+	if facility == "" {
+		facility = "authpriv"
+	}
+	code, ok := facilities[facility]
+	if !ok {
+		return nil, fmt.Errorf("未知的 syslog 设施: %s", facility)
+	}
+	n.facility = code
+
+	if n.appName == "" {
+		n.appName = defaultAppName
+	}
+	if n.hostname == "" {
+		n.hostname, _ = os.Hostname()
+	}
+
+	// TLS 证书与超时沿用通知器的 http 配置
+	if opts.HTTPClient != nil {
+		if opts.HTTPClient.Timeout > 0 {
+			n.timeout = opts.HTTPClient.Timeout
+		}
+		if transport, ok := opts.HTTPClient.Transport.(*http.Transport); ok && transport.TLSClientConfig != nil {
+			n.tls = transport.TLSClientConfig.Clone()
+		}
+	}
+	if n.tls == nil {
+		n.tls = &tls.Config{}
+	}
+
+	return n, nil
+}
+
+// Send 发送一条 syslog 消息，连接断开时重连一次
+func (n *SyslogNotifier) Send(msg notifier.Message) error {
+	line := n.format(msg, time.Now())
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	var err error
+	for attempt := 0; attempt < 2; attempt++ {
+		if n.conn == nil {
+			if n.conn, err = n.dial(); err != nil {
+				return fmt.Errorf("连接 syslog 服务器失败: %v", err)
+			}
+		}
+		n.conn.SetWriteDeadline(time.Now().Add(n.timeout))
+		if _, err = n.conn.Write(n.frame(line)); err == nil {
+			return nil
+		}
+		n.conn.Close()
+		n.conn = nil
+	}
+	return fmt.Errorf("发送 syslog 消息失败: %v", err)
+}
+
+// dial 建立连接
+func (n *SyslogNotifier) dial() (net.Conn, error) {
+	dialer := &net.Dialer{Timeout: n.timeout}
+	switch n.network {
+	case "tls":
+		tlsConfig := n.tls.Clone()
+		if tlsConfig.ServerName == "" {
+			host, _, _ := net.SplitHostPort(n.address)
+			tlsConfig.ServerName = host
+		}
+		return tls.DialWithDialer(dialer, "tcp", n.address, tlsConfig)
+	case "unix":
+		// /dev/log 通常为数据报套接字，部分系统为流式套接字
+		conn, err := dialer.Dial("unixgram", n.address)
+		if err == nil {
+			return conn, nil
+		}
+		return dialer.Dial("unix", n.address)
+	}
+	return dialer.Dial(n.network, n.address)
+}
+
+// frame TCP 与 TLS 使用 RFC 6587 的长度前缀分帧，流式 unix 套接字以换行结束，数据报直接发送
+func (n *SyslogNotifier) frame(line string) []byte {
+	switch {
+	case n.network == "tcp" || n.network == "tls":
+		return []byte(strconv.Itoa(len(line)) + " " + line)
+	case n.conn.RemoteAddr() != nil && n.conn.RemoteAddr().Network() == "unix":
+		return []byte(line + "\n")
+	}
+	return []byte(line)
+}
+
+// format 生成 RFC 5424 消息: <PRI>1 TIMESTAMP HOSTNAME APP-NAME PROCID MSGID [SD] MSG
+func (n *SyslogNotifier) format(msg notifier.Message, now time.Time) string {
+	pri := n.facility*8 + severityCode(msg.Severity)
+	return fmt.Sprintf("<%d>1 %s %s %s %d %s %s \ufeff%s",
+		pri,
+		now.Format("2006-01-02T15:04:05.000000Z07:00"),
+		header(n.hostname, 255),
+		header(n.appName, 48),
+		os.Getpid(),
+		header(string(msg.EventType), 32),
+		structuredData(msg),
+		msg.Content,
+	)
+}
+
+// severityCode 事件严重程度对应的 syslog 级别
+func severityCode(severity config.Severity) int {
+	switch severity {
+	case config.SeverityCritical:
+		return 2 // crit
+	case config.SeverityWarning:
+		return 4 // warning
+	}
+	return 6 // info
+}
+
+// header 头部字段只能包含可打印 ASCII 字符，为空时为 "-"
+func header(s string, max int) string {
+	var b strings.Builder
+	for _, r := range s {
+		if r > 32 && r < 127 {
+			b.WriteRune(r)
+		}
+		if b.Len() == max {
+			break
+		}
+	}
+	if b.Len() == 0 {
+		return "-"
+	}
+	return b.String()
+}
+
+// structuredData 将事件字段编码为一个 SD-ELEMENT
+func structuredData(msg notifier.Message) string {
+	params := map[string]string{
+		"title": msg.Title,
+	}
+	if msg.Severity != "" {
+		params["severity"] = string(msg.Severity)
+	}
+	for k, v := range msg.Metadata {
+		name := sdName(k)
+		if name == "" {
+			continue
+		}
+		if _, exists := params[name]; exists {
+			continue
+		}
+		switch x := v.(type) {
+		case nil, map[string]string:
+			// 字段已展开为顶层的事件数据
+			continue
+		case string:
+			if x != "" {
+				params[name] = x
+			}
+		case []string:
+			if len(x) > 0 {
+				params[name] = strings.Join(x, ",")
+			}
+		default:
+			params[name] = fmt.Sprint(x)
+		}
+	}
+
+	names := make([]string, 0, len(params))
+	for name := range params {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var b strings.Builder
+	b.WriteString("[" + sdID)
+	for _, name := range names {
+		b.WriteString(" " + name + `="` + sdEscape(params[name]) + `"`)
+	}
+	b.WriteString("]")
+	return b.String()
+}
+
+// sdName 参数名为小写，最长 32 个字符，不能包含空格、=、]、"
+func sdName(key string) string {
+	var b strings.Builder
+	for _, r := range strings.ToLower(key) {
+		if r <= 32 || r >= 127 || r == '=' || r == ']' || r == '"' {
+			r = '_'
+		}
+		b.WriteRune(r)
+		if b.Len() == 32 {
+			break
+		}
+	}
+	return b.String()
+}
+
+// sdEscape 转义参数值中的 "、\ 和 ]
+func sdEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, `]`, `\]`).Replace(s)
+}
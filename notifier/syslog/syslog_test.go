@@ -0,0 +1,241 @@
+package syslog
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"loginfopush/config"
+	"loginfopush/notifier"
+	"net"
+	"os"
+	"strings"
+	"testing"
+	"time"
+)
+
+// newTestNotifier 按配置创建 Syslog 通知器
+func newTestNotifier(t *testing.T, cfg config.SyslogConfig) *SyslogNotifier {
+	t.Helper()
+	n, err := NewSyslogNotifier(cfg, notifier.Options{})
+	if err != nil {
+		t.Fatalf("NewSyslogNotifier() = %v", err)
+	}
+	return n.(*SyslogNotifier)
+}
+
+func TestFormat(t *testing.T) {
+	now := time.Date(2024, 1, 1, 10, 0, 0, 123456000, time.UTC)
+	pid := os.Getpid()
+
+	tests := []struct {
+		name     string
+		facility string
+		msg      notifier.Message
+		want     string
+	}{
+		{
+			name:     "authpriv 严重",
+			facility: "",
+			msg:      notifier.Message{EventType: config.EventTypeFailure, Severity: config.SeverityCritical, Title: "登录失败", Content: "root 登录失败"},
+			want:     fmt.Sprintf(`<82>1 2024-01-01T10:00:00.123456Z web loginfopush %d fail [loginfopush@32473 severity="critical" title="登录失败"] `+"\ufeff"+`root 登录失败`, pid),
+		},
+		{
+			name:     "authpriv 警告",
+			facility: "authpriv",
+			msg:      notifier.Message{EventType: config.EventTypeFailure, Severity: config.SeverityWarning},
+			want:     fmt.Sprintf(`<84>1 2024-01-01T10:00:00.123456Z web loginfopush %d fail [loginfopush@32473 severity="warning" title=""] `+"\ufeff", pid),
+		},
+		{
+			// 未设置严重程度时为 info
+			name:     "local0 信息",
+			facility: "local0",
+			msg:      notifier.Message{EventType: config.EventTypeSuccess, Content: "alice 登录成功"},
+			want:     fmt.Sprintf(`<134>1 2024-01-01T10:00:00.123456Z web loginfopush %d success [loginfopush@32473 title=""] `+"\ufeff"+`alice 登录成功`, pid),
+		},
+		{
+			// 没有事件类型时 MSGID 为 "-"
+			name:     "auth 信息",
+			facility: "auth",
+			msg:      notifier.Message{Severity: config.SeverityInfo},
+			want:     fmt.Sprintf(`<38>1 2024-01-01T10:00:00.123456Z web loginfopush %d - [loginfopush@32473 severity="info" title=""] `+"\ufeff", pid),
+		},
+	}
+	for _, tt := range tests {
+		n := newTestNotifier(t, config.SyslogConfig{Facility: tt.facility, Hostname: "web"})
+		if got := n.format(tt.msg, now); got != tt.want {
+			t.Errorf("%s: format() =\n%q\nwant\n%q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestHeader(t *testing.T) {
+	tests := []struct {
+		s    string
+		max  int
+		want string
+	}{
+		{"web-01", 255, "web-01"},
+		{"", 255, "-"},
+		// 去掉空格与非 ASCII 字符
+		{"web 服务器", 255, "web"},
+		{"服务器", 255, "-"},
+		{"loginfopush", 5, "login"},
+	}
+	for _, tt := range tests {
+		if got := header(tt.s, tt.max); got != tt.want {
+			t.Errorf("header(%q, %d) = %q, want %q", tt.s, tt.max, got, tt.want)
+		}
+	}
+}
+
+func TestSDName(t *testing.T) {
+	tests := []struct {
+		key, want string
+	}{
+		{"IP", "ip"},
+		{"Ban Time", "ban_time"},
+		{`a=b]c"d`, "a_b_c_d"},
+		{"位置", "__"},
+		{strings.Repeat("x", 40), strings.Repeat("x", 32)},
+	}
+	for _, tt := range tests {
+		if got := sdName(tt.key); got != tt.want {
+			t.Errorf("sdName(%q) = %q, want %q", tt.key, got, tt.want)
+		}
+	}
+}
+
+func TestSDEscape(t *testing.T) {
+	tests := []struct {
+		s, want string
+	}{
+		{"plain", "plain"},
+		{`say "hi"`, `say \"hi\"`},
+		{`C:\path`, `C:\\path`},
+		{"[x]", `[x\]`},
+		{`\"]`, `\\\"\]`},
+	}
+	for _, tt := range tests {
+		if got := sdEscape(tt.s); got != tt.want {
+			t.Errorf("sdEscape(%q) = %q, want %q", tt.s, got, tt.want)
+		}
+	}
+}
+
+func TestStructuredData(t *testing.T) {
+	got := structuredData(notifier.Message{
+		Title:    `登录 "失败"`,
+		Severity: config.SeverityWarning,
+		Metadata: map[string]interface{}{
+			"User":     "root",
+			"IP":       "203.0.113.7",
+			"Port":     22,
+			"Methods":  []string{"password", "publickey"},
+			"Empty":    "",
+			"None":     nil,
+			"Extra":    map[string]string{"a": "b"},
+			"Title":    "不覆盖标题",
+			"Severity": "critical",
+			"Command":  "rm -rf [tmp]",
+		},
+	})
+	want := `[loginfopush@32473 command="rm -rf [tmp\]" ip="203.0.113.7" methods="password,publickey" port="22" severity="warning" title="登录 \"失败\"" user="root"]`
+	if got != want {
+		t.Errorf("structuredData() =\n%s\nwant\n%s", got, want)
+	}
+}
+
+func TestSendUDP(t *testing.T) {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+
+	n := newTestNotifier(t, config.SyslogConfig{Network: "udp", Address: conn.LocalAddr().String(), Hostname: "web"})
+	if err := n.Send(notifier.Message{EventType: config.EventTypeFailure, Content: "root 登录失败"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// 数据报直接发送，没有分帧
+	buf := make([]byte, 4096)
+	conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+	size, _, err := conn.ReadFrom(buf)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := string(buf[:size])
+	if !strings.HasPrefix(got, "<86>1 ") || !strings.HasSuffix(got, "\ufeffroot 登录失败") {
+		t.Errorf("收到 %q", got)
+	}
+}
+
+func TestSendTCPFraming(t *testing.T) {
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+
+	received := make(chan string, 1)
+	go func() {
+		conn, err := ln.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.SetReadDeadline(time.Now().Add(5 * time.Second))
+		// RFC 6587 长度前缀: "长度 消息"
+		r := bufio.NewReader(conn)
+		var frames []string
+		for i := 0; i < 2; i++ {
+			var length int
+			if _, err := fmt.Fscanf(r, "%d ", &length); err != nil {
+				break
+			}
+			frame := make([]byte, length)
+			if _, err := io.ReadFull(r, frame); err != nil {
+				break
+			}
+			frames = append(frames, string(frame))
+		}
+		received <- strings.Join(frames, "\n")
+	}()
+
+	n := newTestNotifier(t, config.SyslogConfig{Network: "tcp", Address: ln.Addr().String(), Hostname: "web"})
+	defer func() {
+		if n.conn != nil {
+			n.conn.Close()
+		}
+	}()
+	for _, content := range []string{"第一条", "第二条"} {
+		if err := n.Send(notifier.Message{EventType: config.EventTypeFailure, Content: content}); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	select {
+	case got := <-received:
+		frames := strings.Split(got, "\n")
+		if len(frames) != 2 || !strings.HasSuffix(frames[0], "\ufeff第一条") || !strings.HasSuffix(frames[1], "\ufeff第二条") {
+			t.Errorf("收到 %q", frames)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("没有收到消息")
+	}
+}
+
+func TestNewSyslogNotifierErrors(t *testing.T) {
+	tests := []config.SyslogConfig{
+		{Network: "udp"},
+		{Network: "tcp"},
+		{Network: "tls"},
+		{Network: "http", Address: "127.0.0.1:514"},
+		{Facility: "kern"},
+	}
+	for _, cfg := range tests {
+		if _, err := NewSyslogNotifier(cfg, notifier.Options{}); err == nil {
+			t.Errorf("NewSyslogNotifier(%+v) 没有返回错误", cfg)
+		}
+	}
+}
@@ -16,10 +16,11 @@ func init() {
 // TelegramNotifier Telegram 通知器
 type TelegramNotifier struct {
 	config config.TelegramConfig
+	client *http.Client
 }
 
 // NewTelegramNotifier 创建 Telegram 通知器
-func NewTelegramNotifier(cfg interface{}) (notifier.Notifier, error) {
+func NewTelegramNotifier(cfg interface{}, opts notifier.Options) (notifier.Notifier, error) {
 	telegramConfig, ok := cfg.(config.TelegramConfig)
 	if !ok {
 		return nil, fmt.Errorf("无效的 Telegram 配置")
@@ -27,6 +28,7 @@ func NewTelegramNotifier(cfg interface{}) (notifier.Notifier, error) {
 
 	return &TelegramNotifier{
 		config: telegramConfig,
+		client: opts.HTTPClient,
 	}, nil
 }
 
@@ -41,8 +43,13 @@ func (n *TelegramNotifier) Send(msg notifier.Message) error {
 		n.config.ChatID,
 		encodedMessage)
 
+	// silent_severities 中的严重程度静默发送
+	if n.silent(msg.Severity) {
+		requestURL += "&disable_notification=true"
+	}
+
 	// 发送请求
-	resp, err := http.Get(requestURL)
+	resp, err := n.client.Get(requestURL)
 	if err != nil {
 		return fmt.Errorf("send webhook error: %v", err)
 	}
@@ -55,3 +62,13 @@ func (n *TelegramNotifier) Send(msg notifier.Message) error {
 
 	return nil
 }
+
+// silent 判断该严重程度的消息是否静默发送，未配置 silent_severities 时均正常提醒
+func (n *TelegramNotifier) silent(severity config.Severity) bool {
+	for _, s := range n.config.SilentSeverities {
+		if s == severity {
+			return true
+		}
+	}
+	return false
+}
@@ -2,24 +2,62 @@ package notifier
 
 import (
 	"bytes"
+	"fmt"
 	"loginfopush/config"
-	"text/template"
 )
 
 // TemplateData 模板数据结构
 type TemplateData struct {
 	Server   config.ServerConfig    // 服务器信息
 	IP       string                 // IP 地址
+	User     string                 // 用户
+	Severity string                 // 严重程度
 	Location string                 // 位置
 	Time     string                 // 时间
 	Details  string                 // 详细信息
 	Raw      string                 // 原始日志
+	Tags     []string               // 路由规则添加的标签
+	Fields   map[string]string      // 自定义事件的正则命名分组
 	Extra    map[string]interface{} // 额外数据
 }
 
-// RenderTemplate 渲染模板
-func RenderTemplate(tmpl string, data TemplateData) (string, error) {
-	t, err := template.New("message").Parse(tmpl)
+// newTemplateData 根据事件数据构建模板数据
+func newTemplateData(server config.ServerConfig, data map[string]interface{}) TemplateData {
+	return TemplateData{
+		Server:   server,
+		IP:       stringValue(data, "IP"),
+		User:     stringValue(data, "User"),
+		Severity: stringValue(data, "Severity"),
+		Location: stringValue(data, "Location"),
+		Time:     stringValue(data, "Time"),
+		Details:  stringValue(data, "Details"),
+		Raw:      stringValue(data, "Raw"),
+		Tags:     tagsOf(data),
+		Fields:   fieldsOf(data),
+		Extra:    data,
+	}
+}
+
+// fieldsOf 读取自定义事件的字段
+func fieldsOf(data map[string]interface{}) map[string]string {
+	fields, _ := data["Fields"].(map[string]string)
+	return fields
+}
+
+// stringValue 读取事件数据中的字符串字段，不存在时返回空字符串
+func stringValue(data map[string]interface{}, key string) string {
+	if v, ok := data[key]; ok && v != nil {
+		if s, ok := v.(string); ok {
+			return s
+		}
+		return fmt.Sprint(v)
+	}
+	return ""
+}
+
+// RenderTemplate 渲染模板，data 通常为 TemplateData 或 DigestData；解析结果会被缓存
+func RenderTemplate(tmpl string, data interface{}) (string, error) {
+	t, err := config.ParseTemplate(tmpl)
 	if err != nil {
 		return "", err
 	}
@@ -0,0 +1,77 @@
+package notifier
+
+import (
+	"fmt"
+	"loginfopush/config"
+	"sort"
+	"time"
+)
+
+// TestResult 测试消息发送到一个通知渠道的结果
+type TestResult struct {
+	Notifier string        // 通知渠道名称
+	Latency  time.Duration // 发送耗时
+	Err      error         // 发送失败时的错误，HTTP 通知器包含响应内容
+}
+
+// RenderEvent 按事件配置的模板渲染消息，事件未启用时同样渲染，不经过路由规则
+func (m *NotifierManager) RenderEvent(eventType config.EventType, data map[string]interface{}) (Message, error) {
+	eventConfig, ok := m.config.LookupEvent(eventType)
+	if !ok {
+		return Message{}, fmt.Errorf("未找到事件配置: %s", eventType)
+	}
+	if _, ok := data["Type"]; !ok {
+		data["Type"] = string(eventType)
+	}
+	return m.eventMessage(eventConfig, eventConfig.Template, data)
+}
+
+// TestTitlePrefix 测试消息标题与内容的前缀
+const TestTitlePrefix = "[测试] "
+
+// MarkTest 标记测试消息: 标题与内容加上前缀并设置 Metadata["Test"]，已标记的消息原样返回
+func MarkTest(msg Message) Message {
+	if msg.IsTest() {
+		return msg
+	}
+	metadata := make(map[string]interface{}, len(msg.Metadata)+1)
+	for k, v := range msg.Metadata {
+		metadata[k] = v
+	}
+	metadata["Test"] = true
+
+	msg.Title = TestTitlePrefix + msg.Title
+	msg.Content = TestTitlePrefix + msg.Content
+	msg.Metadata = metadata
+	return msg
+}
+
+// IsTest 判断是否为 test-notify 发送的测试消息
+func (msg Message) IsTest() bool {
+	test, _ := msg.Metadata["Test"].(bool)
+	return test
+}
+
+// SendTest 将消息标记为测试消息后逐个发送到通知渠道并记录耗时，不受频率限制与免打扰规则影响，names 为空时发送到所有启用的通知渠道
+func (m *NotifierManager) SendTest(msg Message, names []string) []TestResult {
+	msg = MarkTest(msg)
+	if len(names) == 0 {
+		for name := range m.notifiers {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+	}
+
+	results := make([]TestResult, 0, len(names))
+	for _, name := range names {
+		notifier, ok := m.notifiers[name]
+		if !ok {
+			results = append(results, TestResult{Notifier: name, Err: fmt.Errorf("通知渠道未启用或不存在")})
+			continue
+		}
+		start := time.Now()
+		err := notifier.Send(msg)
+		results = append(results, TestResult{Notifier: name, Latency: time.Since(start), Err: err})
+	}
+	return results
+}
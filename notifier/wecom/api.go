@@ -0,0 +1,57 @@
+package wecom
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+)
+
+// defaultAPIBase 企业微信 API 地址
+const defaultAPIBase = "https://qyapi.weixin.qq.com"
+
+// apiResponse 企业微信接口通用响应
+type apiResponse struct {
+	ErrCode int    `json:"errcode"`
+	ErrMsg  string `json:"errmsg"`
+}
+
+// apiError 企业微信接口返回的业务错误
+type apiError struct {
+	Code int
+	Msg  string
+}
+
+func (e *apiError) Error() string {
+	return fmt.Sprintf("wecom api error: errcode=%d, errmsg=%s", e.Code, e.Msg)
+}
+
+// postJSON 发送 JSON 请求并检查 errcode
+func postJSON(client *http.Client, url string, payload interface{}) error {
+	jsonData, err := json.Marshal(payload)
+	if err != nil {
+		return fmt.Errorf("marshal json error: %v", err)
+	}
+
+	resp, err := client.Post(url, "application/json; charset=utf-8", bytes.NewBuffer(jsonData))
+	if err != nil {
+		return fmt.Errorf("send webhook error: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("webhook response error: status=%d, body=%s", resp.StatusCode, string(body))
+	}
+
+	var result apiResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("解析响应失败: %v, body=%s", err, string(body))
+	}
+	if result.ErrCode != 0 {
+		return &apiError{Code: result.ErrCode, Msg: result.ErrMsg}
+	}
+
+	return nil
+}
@@ -0,0 +1,175 @@
+package wecom
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"loginfopush/config"
+	"loginfopush/notifier"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 需要重新获取 access_token 的错误码
+var tokenExpiredCodes = map[int]bool{
+	40001: true, // 不合法的 secret 或 access_token
+	40014: true, // 不合法的 access_token
+	42001: true, // access_token 已过期
+}
+
+// appNotifier 企业微信自建应用通知器
+type appNotifier struct {
+	config  config.WeComConfig
+	apiBase string
+	client  *http.Client
+
+	mu     sync.Mutex
+	token  string
+	expiry time.Time
+}
+
+// newAppNotifier 创建自建应用通知器
+func newAppNotifier(cfg config.WeComConfig, client *http.Client) (notifier.Notifier, error) {
+	if cfg.CorpID == "" || cfg.CorpSecret == "" {
+		return nil, fmt.Errorf("WeCom 自建应用需要配置 corp_id 和 corp_secret")
+	}
+	if cfg.AgentID == 0 {
+		return nil, fmt.Errorf("WeCom 自建应用需要配置 agent_id")
+	}
+	if cfg.ToUser == "" && cfg.ToParty == "" && cfg.ToTag == "" {
+		cfg.ToUser = "@all"
+	}
+
+	switch cfg.MsgType {
+	case "", "text", "markdown":
+	case "textcard":
+		if cfg.CardURL == "" {
+			return nil, fmt.Errorf("WeCom 文本卡片需要配置 card_url")
+		}
+	default:
+		return nil, fmt.Errorf("WeCom 自建应用不支持的消息类型: %s", cfg.MsgType)
+	}
+
+	apiBase := strings.TrimRight(cfg.APIBase, "/")
+	if apiBase == "" {
+		apiBase = defaultAPIBase
+	}
+
+	return &appNotifier{
+		config:  cfg,
+		apiBase: apiBase,
+		client:  client,
+	}, nil
+}
+
+// Send 发送通知，access_token 失效时刷新后重试一次
+func (n *appNotifier) Send(msg notifier.Message) error {
+	payload := n.buildPayload(msg)
+
+	err := n.send(payload)
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && tokenExpiredCodes[apiErr.Code] {
+		n.invalidateToken()
+		err = n.send(payload)
+	}
+	return err
+}
+
+// send 使用当前 access_token 发送应用消息
+func (n *appNotifier) send(payload map[string]interface{}) error {
+	token, err := n.accessToken()
+	if err != nil {
+		return err
+	}
+
+	sendURL := fmt.Sprintf("%s/cgi-bin/message/send?access_token=%s", n.apiBase, url.QueryEscape(token))
+	return postJSON(n.client, sendURL, payload)
+}
+
+// buildPayload 根据消息类型构建应用消息
+func (n *appNotifier) buildPayload(msg notifier.Message) map[string]interface{} {
+	payload := map[string]interface{}{
+		"touser":  n.config.ToUser,
+		"toparty": n.config.ToParty,
+		"totag":   n.config.ToTag,
+		"agentid": n.config.AgentID,
+	}
+
+	switch n.config.MsgType {
+	case "markdown":
+		content := msg.Content
+		if msg.Title != "" {
+			content = fmt.Sprintf("**%s**\n%s", msg.Title, content)
+		}
+		payload["msgtype"] = "markdown"
+		payload["markdown"] = map[string]string{"content": content}
+	case "textcard":
+		payload["msgtype"] = "textcard"
+		payload["textcard"] = map[string]string{
+			"title":       msg.Title,
+			"description": msg.Content,
+			"url":         n.config.CardURL,
+		}
+	default:
+		payload["msgtype"] = "text"
+		payload["text"] = map[string]string{"content": msg.Content}
+	}
+
+	return payload
+}
+
+// accessToken 获取缓存的 access_token，过期前 5 分钟刷新
+func (n *appNotifier) accessToken() (string, error) {
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	if n.token != "" && time.Now().Add(5*time.Minute).Before(n.expiry) {
+		return n.token, nil
+	}
+
+	tokenURL := fmt.Sprintf("%s/cgi-bin/gettoken?corpid=%s&corpsecret=%s",
+		n.apiBase,
+		url.QueryEscape(n.config.CorpID),
+		url.QueryEscape(n.config.CorpSecret))
+
+	resp, err := n.client.Get(tokenURL)
+	if err != nil {
+		return "", fmt.Errorf("获取 access_token 失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	body, _ := ioutil.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("获取 access_token 失败: status=%d, body=%s", resp.StatusCode, string(body))
+	}
+
+	var result struct {
+		apiResponse
+		AccessToken string `json:"access_token"`
+		ExpiresIn   int64  `json:"expires_in"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return "", fmt.Errorf("解析 access_token 响应失败: %v", err)
+	}
+	if result.ErrCode != 0 {
+		return "", &apiError{Code: result.ErrCode, Msg: result.ErrMsg}
+	}
+	if result.ExpiresIn <= 0 {
+		result.ExpiresIn = 7200
+	}
+
+	n.token = result.AccessToken
+	n.expiry = time.Now().Add(time.Duration(result.ExpiresIn) * time.Second)
+	return n.token, nil
+}
+
+// invalidateToken 清除缓存的 access_token
+func (n *appNotifier) invalidateToken() {
+	n.mu.Lock()
+	n.token = ""
+	n.mu.Unlock()
+}
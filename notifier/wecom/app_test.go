@@ -0,0 +1,200 @@
+package wecom
+
+import (
+	"encoding/json"
+	"errors"
+	"loginfopush/config"
+	"loginfopush/notifier"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeWeComApp 模拟企业微信的 gettoken 与应用消息发送接口
+type fakeWeComApp struct {
+	t *testing.T
+
+	mu        sync.Mutex
+	tokens    int                      // 已签发的 access_token 数量
+	expiresIn int64                    // gettoken 返回的有效期，0 时为 7200
+	reject    []int                    // 之后的发送请求依次返回的错误码
+	messages  []map[string]interface{} // 成功发送的消息
+}
+
+func (f *fakeWeComApp) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	query := r.URL.Query()
+	switch r.URL.Path {
+	case "/cgi-bin/gettoken":
+		if query.Get("corpid") != "corp" || query.Get("corpsecret") != "s&cret" {
+			json.NewEncoder(w).Encode(apiResponse{ErrCode: 40001, ErrMsg: "invalid credential"})
+			return
+		}
+		f.tokens++
+		expiresIn := f.expiresIn
+		if expiresIn == 0 {
+			expiresIn = 7200
+		}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"errcode":      0,
+			"access_token": "token-" + strconv.Itoa(f.tokens),
+			"expires_in":   expiresIn,
+		})
+	case "/cgi-bin/message/send":
+		if got, want := query.Get("access_token"), "token-"+strconv.Itoa(f.tokens); got != want {
+			f.t.Errorf("access_token = %q, want %q", got, want)
+		}
+		if len(f.reject) > 0 {
+			code := f.reject[0]
+			f.reject = f.reject[1:]
+			json.NewEncoder(w).Encode(apiResponse{ErrCode: code, ErrMsg: "token error"})
+			return
+		}
+		var payload map[string]interface{}
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			f.t.Errorf("解析发送请求失败: %v", err)
+		}
+		f.messages = append(f.messages, payload)
+		json.NewEncoder(w).Encode(apiResponse{ErrMsg: "ok"})
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+// newTestApp 创建访问 fake 的自建应用通知器
+func newTestApp(t *testing.T, f *fakeWeComApp, cfg config.WeComConfig) *appNotifier {
+	t.Helper()
+	server := httptest.NewServer(f)
+	t.Cleanup(server.Close)
+
+	cfg.Mode = config.WeComModeApp
+	cfg.APIBase = server.URL + "/"
+	if cfg.CorpID == "" {
+		cfg.CorpID = "corp"
+	}
+	cfg.CorpSecret = "s&cret"
+	cfg.AgentID = 1000002
+	n, err := NewWeComNotifer(cfg, notifier.Options{HTTPClient: server.Client()})
+	if err != nil {
+		t.Fatalf("NewWeComNotifer() = %v", err)
+	}
+	return n.(*appNotifier)
+}
+
+func TestAppTokenCache(t *testing.T) {
+	f := &fakeWeComApp{t: t}
+	n := newTestApp(t, f, config.WeComConfig{})
+
+	for i := 0; i < 3; i++ {
+		if err := n.Send(notifier.Message{Content: "root 登录失败"}); err != nil {
+			t.Fatalf("Send() = %v", err)
+		}
+	}
+	// access_token 在有效期内复用
+	if f.tokens != 1 || len(f.messages) != 3 {
+		t.Errorf("获取令牌 %d 次，发送 %d 条, want 1 3", f.tokens, len(f.messages))
+	}
+
+	// 距离过期不足 5 分钟时重新获取
+	n.expiry = time.Now().Add(4 * time.Minute)
+	if err := n.Send(notifier.Message{Content: "root 登录失败"}); err != nil {
+		t.Fatal(err)
+	}
+	if f.tokens != 2 {
+		t.Errorf("即将过期时获取令牌 %d 次, want 2", f.tokens)
+	}
+}
+
+func TestAppTokenRefresh(t *testing.T) {
+	tests := []struct {
+		name     string
+		reject   []int
+		tokens   int
+		messages int
+		errCode  int
+	}{
+		{"令牌不合法", []int{40014}, 2, 1, 0},
+		{"令牌过期", []int{42001}, 2, 1, 0},
+		{"secret 不合法", []int{40001}, 2, 1, 0},
+		// 只重试一次
+		{"刷新后仍然失败", []int{42001, 42001}, 2, 0, 42001},
+		// 其他错误不刷新令牌
+		{"其他错误", []int{81013}, 1, 0, 81013},
+	}
+	for _, tt := range tests {
+		f := &fakeWeComApp{t: t}
+		n := newTestApp(t, f, config.WeComConfig{})
+		// 先获取一次令牌，之后的请求使用缓存的令牌
+		if _, err := n.accessToken(); err != nil {
+			t.Fatal(err)
+		}
+		f.reject = tt.reject
+
+		err := n.Send(notifier.Message{Content: "root 登录失败"})
+		var apiErr *apiError
+		switch {
+		case tt.errCode == 0 && err != nil:
+			t.Errorf("%s: Send() = %v", tt.name, err)
+		case tt.errCode != 0 && (!errors.As(err, &apiErr) || apiErr.Code != tt.errCode):
+			t.Errorf("%s: Send() = %v, want errcode=%d", tt.name, err, tt.errCode)
+		}
+		if f.tokens != tt.tokens || len(f.messages) != tt.messages {
+			t.Errorf("%s: 获取令牌 %d 次，发送 %d 条, want %d %d", tt.name, f.tokens, len(f.messages), tt.tokens, tt.messages)
+		}
+	}
+}
+
+func TestAppTokenError(t *testing.T) {
+	f := &fakeWeComApp{t: t}
+	n := newTestApp(t, f, config.WeComConfig{CorpID: "other"})
+	err := n.Send(notifier.Message{Content: "root 登录失败"})
+	if err == nil || !strings.Contains(err.Error(), "errcode=40001") {
+		t.Errorf("Send() = %v, want errcode=40001", err)
+	}
+	if len(f.messages) != 0 {
+		t.Errorf("获取令牌失败时发送了 %d 条", len(f.messages))
+	}
+}
+
+func TestAppPayload(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.WeComConfig
+		want string
+	}{
+		{
+			// 未指定接收人时发送给全部成员
+			name: "文本",
+			cfg:  config.WeComConfig{},
+			want: `{"agentid":1000002,"msgtype":"text","text":{"content":"root 登录失败"},"toparty":"","totag":"","touser":"@all"}`,
+		},
+		{
+			name: "markdown",
+			cfg:  config.WeComConfig{MsgType: "markdown", ToUser: "alice|bob"},
+			want: `{"agentid":1000002,"markdown":{"content":"**登录失败**\nroot 登录失败"},"msgtype":"markdown","toparty":"","totag":"","touser":"alice|bob"}`,
+		},
+		{
+			name: "文本卡片",
+			cfg:  config.WeComConfig{MsgType: "textcard", CardURL: "https://example.com", ToParty: "2"},
+			want: `{"agentid":1000002,"msgtype":"textcard","textcard":{"description":"root 登录失败","title":"登录失败","url":"https://example.com"},"toparty":"2","totag":"","touser":""}`,
+		},
+	}
+	for _, tt := range tests {
+		f := &fakeWeComApp{t: t}
+		n := newTestApp(t, f, tt.cfg)
+		if err := n.Send(notifier.Message{Title: "登录失败", Content: "root 登录失败"}); err != nil {
+			t.Errorf("%s: Send() = %v", tt.name, err)
+			continue
+		}
+		got, _ := json.Marshal(f.messages[0])
+		if string(got) != tt.want {
+			t.Errorf("%s: 消息 = %s, want %s", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,96 @@
+package wecom
+
+import (
+	"fmt"
+	"loginfopush/config"
+	"loginfopush/notifier"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// robotNotifier 企业微信群机器人通知器
+type robotNotifier struct {
+	config     config.WeComConfig
+	webhookURL string
+	client     *http.Client
+}
+
+// newRobotNotifier 创建群机器人通知器
+func newRobotNotifier(cfg config.WeComConfig, client *http.Client) (notifier.Notifier, error) {
+	webhookURL := cfg.WebhookURL
+	if webhookURL == "" {
+		if cfg.Key == "" {
+			return nil, fmt.Errorf("WeCom 群机器人需要配置 webhook_url 或 key")
+		}
+		base := cfg.APIBase
+		if base == "" {
+			base = defaultAPIBase
+		}
+		webhookURL = fmt.Sprintf("%s/cgi-bin/webhook/send?key=%s", strings.TrimRight(base, "/"), url.QueryEscape(cfg.Key))
+	}
+
+	switch cfg.MsgType {
+	case "", "text", "markdown":
+	case "template_card":
+		if cfg.CardURL == "" {
+			return nil, fmt.Errorf("WeCom 模板卡片需要配置 card_url")
+		}
+	default:
+		return nil, fmt.Errorf("WeCom 群机器人不支持的消息类型: %s", cfg.MsgType)
+	}
+
+	return &robotNotifier{
+		config:     cfg,
+		webhookURL: webhookURL,
+		client:     client,
+	}, nil
+}
+
+// Send 发送通知
+func (n *robotNotifier) Send(msg notifier.Message) error {
+	return postJSON(n.client, n.webhookURL, n.buildPayload(msg))
+}
+
+// buildPayload 根据消息类型构建请求体
+func (n *robotNotifier) buildPayload(msg notifier.Message) map[string]interface{} {
+	switch n.config.MsgType {
+	case "markdown":
+		// markdown 消息不支持 mentioned_list，需在内容中使用 <@userid>
+		content := msg.Content
+		if msg.Title != "" {
+			content = fmt.Sprintf("**%s**\n%s", msg.Title, content)
+		}
+		for _, user := range n.config.MentionedList {
+			content += fmt.Sprintf("\n<@%s>", user)
+		}
+		return map[string]interface{}{
+			"msgtype":  "markdown",
+			"markdown": map[string]interface{}{"content": content},
+		}
+	case "template_card":
+		return map[string]interface{}{
+			"msgtype": "template_card",
+			"template_card": map[string]interface{}{
+				"card_type": "text_notice",
+				"main_title": map[string]string{
+					"title": msg.Title,
+				},
+				"sub_title_text": msg.Content,
+				"card_action": map[string]interface{}{
+					"type": 1,
+					"url":  n.config.CardURL,
+				},
+			},
+		}
+	default:
+		return map[string]interface{}{
+			"msgtype": "text",
+			"text": map[string]interface{}{
+				"content":               msg.Content,
+				"mentioned_list":        n.config.MentionedList,
+				"mentioned_mobile_list": n.config.MentionedMobileList,
+			},
+		}
+	}
+}
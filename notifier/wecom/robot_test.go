@@ -0,0 +1,86 @@
+package wecom
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"loginfopush/config"
+	"loginfopush/notifier"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestRobotPayload(t *testing.T) {
+	tests := []struct {
+		name string
+		cfg  config.WeComConfig
+		want string
+	}{
+		{
+			name: "文本",
+			cfg:  config.WeComConfig{MentionedList: []string{"alice", "@all"}, MentionedMobileList: []string{"13800000000"}},
+			want: `{"msgtype":"text","text":{"content":"root 登录失败","mentioned_list":["alice","@all"],"mentioned_mobile_list":["13800000000"]}}`,
+		},
+		{
+			// markdown 消息在内容中 @ 成员
+			name: "markdown",
+			cfg:  config.WeComConfig{MsgType: "markdown", MentionedList: []string{"alice"}},
+			want: `{"markdown":{"content":"**登录失败**\nroot 登录失败\n<@alice>"},"msgtype":"markdown"}`,
+		},
+		{
+			name: "模板卡片",
+			cfg:  config.WeComConfig{MsgType: "template_card", CardURL: "https://example.com"},
+			want: `{"msgtype":"template_card","template_card":{"card_action":{"type":1,"url":"https://example.com"},"card_type":"text_notice","main_title":{"title":"登录失败"},"sub_title_text":"root 登录失败"}}`,
+		},
+	}
+	for _, tt := range tests {
+		var got string
+		server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != "/cgi-bin/webhook/send" || r.URL.Query().Get("key") != "robot-key" {
+				t.Errorf("请求地址 = %s", r.URL)
+			}
+			body, _ := ioutil.ReadAll(r.Body)
+			got = strings.TrimSpace(string(body))
+			w.Write([]byte(`{"errcode":0,"errmsg":"ok"}`))
+		}))
+		cfg := tt.cfg
+		cfg.Mode = config.WeComModeRobot
+		cfg.Key = "robot-key"
+		cfg.APIBase = server.URL
+		n, err := NewWeComNotifer(cfg, notifier.Options{HTTPClient: server.Client()})
+		if err != nil {
+			t.Fatalf("%s: NewWeComNotifer() = %v", tt.name, err)
+		}
+		if err := n.Send(notifier.Message{Title: "登录失败", Content: "root 登录失败"}); err != nil {
+			t.Errorf("%s: Send() = %v", tt.name, err)
+		}
+		server.Close()
+
+		// 按 JSON 比较，忽略字段顺序
+		var gotValue, wantValue interface{}
+		json.Unmarshal([]byte(got), &gotValue)
+		json.Unmarshal([]byte(tt.want), &wantValue)
+		gotJSON, _ := json.Marshal(gotValue)
+		wantJSON, _ := json.Marshal(wantValue)
+		if string(gotJSON) != string(wantJSON) {
+			t.Errorf("%s: 请求体 = %s, want %s", tt.name, gotJSON, wantJSON)
+		}
+	}
+}
+
+func TestRobotErrcode(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"errcode":93000,"errmsg":"invalid webhook url"}`))
+	}))
+	defer server.Close()
+
+	n, err := NewWeComNotifer(config.WeComConfig{Mode: config.WeComModeRobot, WebhookURL: server.URL}, notifier.Options{HTTPClient: server.Client()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// HTTP 200 时仍需校验 errcode
+	if err := n.Send(notifier.Message{Content: "test"}); err == nil || !strings.Contains(err.Error(), "errcode=93000") {
+		t.Errorf("Send() = %v, want errcode=93000", err)
+	}
+}
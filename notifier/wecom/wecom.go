@@ -13,20 +13,32 @@ func init() {
 	notifier.RegisterNotifier(config.NotifierTypeWeCom, NewWeComNotifer)
 }
 
-// WeComNotifer Telegram 通知器
+// WeComNotifer 企业微信 wecomchan 通知器
 type WeComNotifer struct {
 	config config.WeComConfig
+	client *http.Client
 }
 
-// NewWeComNotifer 创建 Telegram 通知器
-func NewWeComNotifer(cfg interface{}) (notifier.Notifier, error) {
+// NewWeComNotifer 根据模式创建企业微信通知器
+func NewWeComNotifer(cfg interface{}, opts notifier.Options) (notifier.Notifier, error) {
 	wecomConfig, ok := cfg.(config.WeComConfig)
 	if !ok {
 		return nil, fmt.Errorf("无效的 Wecom 配置")
 	}
 
+	switch wecomConfig.Mode {
+	case "", config.WeComModeWecomchan:
+	case config.WeComModeRobot:
+		return newRobotNotifier(wecomConfig, opts.HTTPClient)
+	case config.WeComModeApp:
+		return newAppNotifier(wecomConfig, opts.HTTPClient)
+	default:
+		return nil, fmt.Errorf("不支持的 WeCom 模式: %s", wecomConfig.Mode)
+	}
+
 	return &WeComNotifer{
 		config: wecomConfig,
+		client: opts.HTTPClient,
 	}, nil
 }
 
@@ -43,7 +55,7 @@ func (n *WeComNotifer) Send(msg notifier.Message) error {
 		encodedMessage)
 
 	// 发送请求
-	resp, err := http.Get(requestURL)
+	resp, err := n.client.Get(requestURL)
 	if err != nil {
 		return fmt.Errorf("send webhook error: %v", err)
 	}
@@ -4,19 +4,37 @@ import (
 	"bytes"
 	"encoding/json"
 	"fmt"
+	"io/ioutil"
 	"loginfopush/config"
 	"loginfopush/notifier"
 	"net/http"
+	"strings"
 )
 
 const (
-	wxPusherAPI = "https://wxpusher.zjiecode.com/api/send/message"
+	wxPusherAPI = "https://wxpusher.zjiecode.com"
+	// wxPusherSuccessCode 接口成功时返回的业务码
+	wxPusherSuccessCode = 1000
+	// wxPusherSummaryLimit 摘要最大长度，微信只能显示 20 个字符
+	wxPusherSummaryLimit = 20
 )
 
+// 内容类型映射
+var contentTypes = map[string]int{
+	"text":     1,
+	"html":     2,
+	"markdown": 3,
+}
+
 type wxPusherNotifier struct {
-	appToken string
-	uids     []string
-	server   config.ServerConfig
+	appToken    string
+	uids        []string
+	topicIDs    []int
+	sendURL     string
+	contentType int
+	url         string
+	server      config.ServerConfig
+	client      *http.Client
 }
 
 type wxPusherRequest struct {
@@ -24,18 +42,32 @@ type wxPusherRequest struct {
 	Content       string   `json:"content"`
 	Summary       string   `json:"summary"`
 	ContentType   int      `json:"contentType"`
-	UIDs          []string `json:"uids"`
+	UIDs          []string `json:"uids,omitempty"`
+	TopicIDs      []int    `json:"topicIds,omitempty"`
 	URL           string   `json:"url,omitempty"`
 	VerifyPay     bool     `json:"verifyPay"`
 	VerifyPayType int      `json:"verifyPayType"`
 }
 
+// wxPusherResponse 接口响应
+type wxPusherResponse struct {
+	Code    int    `json:"code"`
+	Msg     string `json:"msg"`
+	Success bool   `json:"success"`
+	Data    []struct {
+		UID     string `json:"uid"`
+		TopicID int    `json:"topicId"`
+		Code    int    `json:"code"`
+		Status  string `json:"status"`
+	} `json:"data"`
+}
+
 func init() {
 	notifier.RegisterNotifier(config.NotifierTypeWxPusher, NewNotifier)
 }
 
 // NewNotifier 创建 WxPusher 通知器
-func NewNotifier(cfg interface{}) (notifier.Notifier, error) {
+func NewNotifier(cfg interface{}, opts notifier.Options) (notifier.Notifier, error) {
 	wxConfig, ok := cfg.(config.WxPusherConfig)
 	if !ok {
 		return nil, fmt.Errorf("无效的 WxPusher 配置")
@@ -45,17 +77,31 @@ func NewNotifier(cfg interface{}) (notifier.Notifier, error) {
 		return nil, fmt.Errorf("WxPusher AppToken 不能为空")
 	}
 
-	if len(wxConfig.UIDs) == 0 {
-		return nil, fmt.Errorf("WxPusher UIDs 不能为空")
+	if len(wxConfig.UIDs) == 0 && len(wxConfig.TopicIDs) == 0 {
+		return nil, fmt.Errorf("WxPusher UIDs 和 TopicIDs 不能同时为空")
+	}
+
+	contentType := contentTypes["text"]
+	if wxConfig.ContentType != "" {
+		if contentType, ok = contentTypes[strings.ToLower(wxConfig.ContentType)]; !ok {
+			return nil, fmt.Errorf("不支持的 WxPusher 内容类型: %s", wxConfig.ContentType)
+		}
 	}
 
-	// 获取全局配置中的服务器信息
-	serverConfig := config.GlobalConfig.Server
+	baseURL := strings.TrimRight(wxConfig.BaseURL, "/")
+	if baseURL == "" {
+		baseURL = wxPusherAPI
+	}
 
 	return &wxPusherNotifier{
-		appToken: wxConfig.AppToken,
-		uids:     wxConfig.UIDs,
-		server:   serverConfig,
+		appToken:    wxConfig.AppToken,
+		uids:        wxConfig.UIDs,
+		topicIDs:    wxConfig.TopicIDs,
+		sendURL:     baseURL + "/api/send/message",
+		contentType: contentType,
+		url:         wxConfig.URL,
+		server:      opts.Server,
+		client:      opts.HTTPClient,
 	}, nil
 }
 
@@ -65,9 +111,11 @@ func (w *wxPusherNotifier) Send(msg notifier.Message) error {
 	reqBody := wxPusherRequest{
 		AppToken:      w.appToken,
 		Content:       msg.Content,
-		Summary:       fmt.Sprintf("%s - %s", w.server.Name, "login"),
-		ContentType:   1, // HTML
+		Summary:       w.summary(msg),
+		ContentType:   w.contentType,
 		UIDs:          w.uids,
+		TopicIDs:      w.topicIDs,
+		URL:           w.url,
 		VerifyPay:     false,
 		VerifyPayType: 0,
 	}
@@ -79,16 +127,51 @@ func (w *wxPusherNotifier) Send(msg notifier.Message) error {
 	}
 
 	// 发送请求
-	resp, err := http.Post(wxPusherAPI, "application/json", bytes.NewBuffer(jsonData))
+	resp, err := w.client.Post(w.sendURL, "application/json", bytes.NewBuffer(jsonData))
 	if err != nil {
 		return fmt.Errorf("发送请求失败: %v", err)
 	}
 	defer resp.Body.Close()
 
+	body, _ := ioutil.ReadAll(resp.Body)
+
 	// 检查响应状态
 	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("请求失败，状态码: %d", resp.StatusCode)
+		return fmt.Errorf("请求失败，状态码: %d, body=%s", resp.StatusCode, string(body))
+	}
+
+	// HTTP 200 时仍可能携带业务错误码
+	var result wxPusherResponse
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("解析响应失败: %v, body=%s", err, string(body))
+	}
+	if result.Code != wxPusherSuccessCode {
+		return fmt.Errorf("发送失败: code=%d, msg=%s", result.Code, result.Msg)
+	}
+	for _, item := range result.Data {
+		if item.Code != 0 && item.Code != wxPusherSuccessCode {
+			return fmt.Errorf("发送失败: uid=%s, topicId=%d, code=%d, status=%s", item.UID, item.TopicID, item.Code, item.Status)
+		}
 	}
 
 	return nil
 }
+
+// summary 生成消息摘要，格式: <服务器名称> - <事件标题>
+func (w *wxPusherNotifier) summary(msg notifier.Message) string {
+	title := msg.Title
+	if title == "" {
+		title = string(msg.EventType)
+	}
+
+	summary := title
+	if w.server.Name != "" {
+		summary = fmt.Sprintf("%s - %s", w.server.Name, title)
+	}
+
+	runes := []rune(summary)
+	if len(runes) > wxPusherSummaryLimit {
+		summary = string(runes[:wxPusherSummaryLimit])
+	}
+	return summary
+}
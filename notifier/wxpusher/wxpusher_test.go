@@ -0,0 +1,144 @@
+package wxpusher
+
+import (
+	"encoding/json"
+	"loginfopush/config"
+	"loginfopush/notifier"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// fakeWxPusher 模拟 WxPusher 发送消息接口
+type fakeWxPusher struct {
+	t *testing.T
+
+	mu       sync.Mutex
+	requests []wxPusherRequest
+	status   int    // 非零时返回的 HTTP 状态码
+	response string // 非空时作为响应体，默认返回成功
+}
+
+func (f *fakeWxPusher) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if r.Method != http.MethodPost || r.URL.Path != "/api/send/message" {
+		http.NotFound(w, r)
+		return
+	}
+	var req wxPusherRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		f.t.Errorf("解析发送请求失败: %v", err)
+	}
+	f.requests = append(f.requests, req)
+
+	if f.status != 0 {
+		http.Error(w, "bad gateway", f.status)
+		return
+	}
+	if f.response != "" {
+		w.Write([]byte(f.response))
+		return
+	}
+	w.Write([]byte(`{"code":1000,"msg":"处理成功","success":true,"data":[{"uid":"UID_1","topicId":0,"code":1000,"status":"创建发送任务成功"}]}`))
+}
+
+// newTestNotifier 创建发送到 fake 的 WxPusher 通知器
+func newTestNotifier(t *testing.T, f *fakeWxPusher, cfg config.WxPusherConfig, server config.ServerConfig) notifier.Notifier {
+	t.Helper()
+	s := httptest.NewServer(f)
+	t.Cleanup(s.Close)
+	cfg.BaseURL = s.URL + "/"
+	if cfg.AppToken == "" {
+		cfg.AppToken = "AT_test"
+	}
+	n, err := NewNotifier(cfg, notifier.Options{HTTPClient: s.Client(), Server: server})
+	if err != nil {
+		t.Fatalf("NewNotifier() = %v", err)
+	}
+	return n
+}
+
+func TestSendRequest(t *testing.T) {
+	tests := []struct {
+		name   string
+		cfg    config.WxPusherConfig
+		server config.ServerConfig
+		msg    notifier.Message
+		want   wxPusherRequest
+	}{
+		{
+			name:   "用户",
+			cfg:    config.WxPusherConfig{UIDs: []string{"UID_1"}, URL: "https://example.com"},
+			server: config.ServerConfig{Name: "web"},
+			msg:    notifier.Message{EventType: config.EventTypeFailure, Title: "登录失败", Content: "root 登录失败"},
+			want:   wxPusherRequest{AppToken: "AT_test", Content: "root 登录失败", Summary: "web - 登录失败", ContentType: 1, UIDs: []string{"UID_1"}, URL: "https://example.com"},
+		},
+		{
+			// 没有标题时摘要使用事件类型
+			name:   "主题",
+			cfg:    config.WxPusherConfig{TopicIDs: []int{123, 456}, ContentType: "Markdown"},
+			server: config.ServerConfig{Name: "web"},
+			msg:    notifier.Message{EventType: config.EventTypeBan, Content: "**封禁**"},
+			want:   wxPusherRequest{AppToken: "AT_test", Content: "**封禁**", Summary: "web - ban", ContentType: 3, TopicIDs: []int{123, 456}},
+		},
+		{
+			// 摘要最多 20 个字符
+			name: "摘要截断",
+			cfg:  config.WxPusherConfig{UIDs: []string{"UID_1"}, ContentType: "html"},
+			msg:  notifier.Message{Title: strings.Repeat("登录", 15), Content: "<b>x</b>"},
+			want: wxPusherRequest{AppToken: "AT_test", Content: "<b>x</b>", Summary: strings.Repeat("登录", 10), ContentType: 2, UIDs: []string{"UID_1"}},
+		},
+	}
+	for _, tt := range tests {
+		f := &fakeWxPusher{t: t}
+		n := newTestNotifier(t, f, tt.cfg, tt.server)
+		if err := n.Send(tt.msg); err != nil {
+			t.Errorf("%s: Send() = %v", tt.name, err)
+			continue
+		}
+		if len(f.requests) != 1 || !reflect.DeepEqual(f.requests[0], tt.want) {
+			t.Errorf("%s: 请求 = %+v, want %+v", tt.name, f.requests, tt.want)
+		}
+	}
+}
+
+func TestSendErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		fake    *fakeWxPusher
+		wantErr string
+	}{
+		{"HTTP 错误", &fakeWxPusher{status: http.StatusBadGateway}, "状态码: 502"},
+		// HTTP 200 时仍需校验业务码
+		{"AppToken 错误", &fakeWxPusher{response: `{"code":1001,"msg":"appToken不正确","success":false}`}, "code=1001, msg=appToken不正确"},
+		{"参数错误", &fakeWxPusher{response: `{"code":1002,"msg":"uids和topicIds不能同时为空","success":false}`}, "code=1002"},
+		// 整体成功但个别接收者失败
+		{"接收者失败", &fakeWxPusher{response: `{"code":1000,"msg":"处理成功","success":true,"data":[{"uid":"UID_1","code":1000},{"uid":"UID_2","code":1003,"status":"用户已取消关注"}]}`}, "uid=UID_2, topicId=0, code=1003, status=用户已取消关注"},
+		{"响应不是 JSON", &fakeWxPusher{response: "<html>"}, "解析响应失败"},
+	}
+	for _, tt := range tests {
+		tt.fake.t = t
+		n := newTestNotifier(t, tt.fake, config.WxPusherConfig{UIDs: []string{"UID_1", "UID_2"}}, config.ServerConfig{})
+		if err := n.Send(notifier.Message{Content: "test"}); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+			t.Errorf("%s: Send() = %v, want 包含 %s", tt.name, err, tt.wantErr)
+		}
+	}
+}
+
+func TestNewNotifierErrors(t *testing.T) {
+	tests := []config.WxPusherConfig{
+		{UIDs: []string{"UID_1"}},
+		{AppToken: "AT_test"},
+		{AppToken: "AT_test", UIDs: []string{"UID_1"}, ContentType: "json"},
+	}
+	for _, cfg := range tests {
+		if _, err := NewNotifier(cfg, notifier.Options{}); err == nil {
+			t.Errorf("NewNotifier(%+v) 没有返回错误", cfg)
+		}
+	}
+}
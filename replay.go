@@ -0,0 +1,118 @@
+package main
+
+import (
+	"fmt"
+	_func "loginfopush/func"
+	"loginfopush/func/monitors"
+	"sort"
+	"strings"
+)
+
+// replayUsage replay 子命令的帮助信息
+const replayUsage = `用法: loginfopush replay [选项] <日志文件>
+
+从头读取日志文件，按日志中的时间逐行解析并显示会产生的事件，例如:
+  loginfopush replay /var/log/auth.log.1
+  loginfopush replay --type fail2ban /var/log/fail2ban.log.2.gz
+  loginfopush replay --send /var/log/auth.log
+
+默认只渲染消息并输出，不会发送，也不查询 IP 位置；回放不写入事件历史，也不会触发主动封禁。
+
+选项:
+`
+
+// runReplay 执行 replay 子命令
+func runReplay(args []string) error {
+	var g globalFlags
+	fs := newFlagSet("replay", replayUsage, &g)
+	logType := fs.String("type", "", "日志类型: auth、fail2ban、custom，默认按文件名判断")
+	send := fs.Bool("send", false, "实际发送通知，而不是只输出渲染后的消息")
+	geo := fs.Bool("geo", false, "查询 IP 位置，默认不查询（位置显示为 \"未查询\"）")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if fs.NArg() != 1 {
+		fs.Usage()
+		return fmt.Errorf("需要指定一个日志文件")
+	}
+
+	cfg, _, err := g.load()
+	if err != nil {
+		return err
+	}
+
+	counts := make(map[string]int)
+	total := 0
+	opts := _func.ReplayOptions{Type: monitors.LogType(*logType), DryRun: !*send, Geolocate: *geo}
+	err = _func.Replay(cfg, fs.Arg(0), opts, func(e _func.ReplayEvent) {
+		if e.Summary {
+			printReplaySummary(e)
+			return
+		}
+		total++
+		counts[e.Result.Status]++
+		printReplayEvent(e)
+	})
+	if err != nil {
+		return err
+	}
+
+	statuses := make([]string, 0, len(counts))
+	for status, n := range counts {
+		statuses = append(statuses, fmt.Sprintf("%s %d", orDash(status), n))
+	}
+	sort.Strings(statuses)
+	fmt.Printf("共 %d 个事件", total)
+	if total > 0 {
+		fmt.Printf(": %s", strings.Join(statuses, ", "))
+	}
+	fmt.Println()
+	return nil
+}
+
+// printReplayEvent 输出一个事件及其处理结果，演练时附带渲染后的消息
+func printReplayEvent(e _func.ReplayEvent) {
+	event := e.Event
+	status := orDash(e.Result.Status)
+	var names []string
+	for _, d := range e.Result.Deliveries {
+		if d.Status == "sent" {
+			names = append(names, d.Notifier)
+		} else {
+			names = append(names, d.Notifier+":"+d.Status)
+		}
+	}
+	if len(names) > 0 {
+		status += "(" + strings.Join(names, ",") + ")"
+	}
+
+	fmt.Printf("%s  %s  %s  %s  %s  %s  %s\n",
+		event.Time.Format("2006-01-02 15:04:05"),
+		event.Type,
+		orDash(string(event.Severity)),
+		orDash(event.IP),
+		orDash(event.User),
+		status,
+		event.Details,
+	)
+	if e.Err != nil {
+		fmt.Printf("    错误: %v\n", e.Err)
+	}
+	printReplayMessages(e.Messages)
+}
+
+// printReplaySummary 输出抑制窗口结束时的汇总与事件汇总消息
+func printReplaySummary(e _func.ReplayEvent) {
+	fmt.Printf("%s  汇总\n", e.Event.Time.Format("2006-01-02 15:04:05"))
+	printReplayMessages(e.Messages)
+}
+
+// printReplayMessages 输出演练时渲染的消息
+func printReplayMessages(messages []_func.ReplayMessage) {
+	for _, m := range messages {
+		fmt.Printf("    ── %s: %s\n", m.Notifier, m.Message.Title)
+		for _, line := range strings.Split(strings.TrimRight(m.Message.Content, "\n"), "\n") {
+			fmt.Printf("    %s\n", line)
+		}
+	}
+}
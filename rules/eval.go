@@ -0,0 +1,426 @@
+package rules
+
+import (
+	"fmt"
+	"net"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Eval 对事件数据求值，字段名不区分大小写，不存在的字段视为空字符串
+func (e *Expr) Eval(data map[string]interface{}) (bool, error) {
+	v, err := e.root.eval(data)
+	if err != nil {
+		return false, err
+	}
+	return truthy(v), nil
+}
+
+// node 语法树节点
+type node interface {
+	eval(data map[string]interface{}) (interface{}, error)
+}
+
+// cidrSet cidr() 的返回值
+type cidrSet []*net.IPNet
+
+// literalNode 字面量
+type literalNode struct {
+	val interface{}
+}
+
+func (n *literalNode) eval(map[string]interface{}) (interface{}, error) {
+	return n.val, nil
+}
+
+// fieldNode 事件字段
+type fieldNode struct {
+	name string
+}
+
+func (n *fieldNode) eval(data map[string]interface{}) (interface{}, error) {
+	if v, ok := data[n.name]; ok {
+		return normalize(v), nil
+	}
+	for k, v := range data {
+		if strings.EqualFold(k, n.name) {
+			return normalize(v), nil
+		}
+	}
+	return "", nil
+}
+
+// listNode 列表字面量
+type listNode struct {
+	items []node
+}
+
+func (n *listNode) eval(data map[string]interface{}) (interface{}, error) {
+	list := make([]interface{}, 0, len(n.items))
+	for _, item := range n.items {
+		v, err := item.eval(data)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	return list, nil
+}
+
+// notNode !x
+type notNode struct {
+	x node
+}
+
+func (n *notNode) eval(data map[string]interface{}) (interface{}, error) {
+	v, err := n.x.eval(data)
+	if err != nil {
+		return nil, err
+	}
+	return !truthy(v), nil
+}
+
+// logicalNode x && y 或 x || y，短路求值
+type logicalNode struct {
+	or   bool
+	x, y node
+}
+
+func (n *logicalNode) eval(data map[string]interface{}) (interface{}, error) {
+	v, err := n.x.eval(data)
+	if err != nil {
+		return nil, err
+	}
+	if truthy(v) == n.or {
+		return n.or, nil
+	}
+	v, err = n.y.eval(data)
+	if err != nil {
+		return nil, err
+	}
+	return truthy(v), nil
+}
+
+// compareNode 比较运算
+type compareNode struct {
+	op   string
+	x, y node
+	pos  int
+}
+
+func (n *compareNode) eval(data map[string]interface{}) (interface{}, error) {
+	a, err := n.x.eval(data)
+	if err != nil {
+		return nil, err
+	}
+	b, err := n.y.eval(data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch n.op {
+	case "==":
+		return equal(a, b), nil
+	case "!=":
+		return !equal(a, b), nil
+	}
+
+	c, err := compare(a, b)
+	if err != nil {
+		return nil, errorf(n.pos, "%v", err)
+	}
+	switch n.op {
+	case "<":
+		return c < 0, nil
+	case "<=":
+		return c <= 0, nil
+	case ">":
+		return c > 0, nil
+	default:
+		return c >= 0, nil
+	}
+}
+
+// inNode x in y
+type inNode struct {
+	x, y node
+	pos  int
+}
+
+func (n *inNode) eval(data map[string]interface{}) (interface{}, error) {
+	a, err := n.x.eval(data)
+	if err != nil {
+		return nil, err
+	}
+	b, err := n.y.eval(data)
+	if err != nil {
+		return nil, err
+	}
+
+	switch set := b.(type) {
+	case cidrSet:
+		ip := net.ParseIP(toString(a))
+		if ip == nil {
+			return false, nil
+		}
+		for _, ipNet := range set {
+			if ipNet.Contains(ip) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case []interface{}:
+		for _, item := range set {
+			if equal(a, item) {
+				return true, nil
+			}
+		}
+		return false, nil
+	case string:
+		return strings.Contains(set, toString(a)), nil
+	}
+	return nil, errorf(n.pos, "in 右侧必须是列表、cidr() 或字符串")
+}
+
+// callNode 函数调用
+type callNode struct {
+	name string
+	fn   *function
+	args []node
+	pos  int
+}
+
+func (n *callNode) eval(data map[string]interface{}) (interface{}, error) {
+	args := make([]interface{}, len(n.args))
+	for i, arg := range n.args {
+		v, err := arg.eval(data)
+		if err != nil {
+			return nil, err
+		}
+		args[i] = v
+	}
+	v, err := n.fn.call(args)
+	if err != nil {
+		return nil, errorf(n.pos, "%s: %v", n.name, err)
+	}
+	return v, nil
+}
+
+// function 内置函数
+type function struct {
+	minArgs int
+	maxArgs int                     // -1 表示不限
+	check   func(args []node) error // 编译期检查，可为空
+	call    func(args []interface{}) (interface{}, error)
+}
+
+// functions 内置函数表
+var functions = map[string]*function{
+	"cidr": {minArgs: 1, maxArgs: -1, call: func(args []interface{}) (interface{}, error) {
+		set := make(cidrSet, 0, len(args))
+		for _, arg := range args {
+			s := toString(arg)
+			if !strings.Contains(s, "/") {
+				if ip := net.ParseIP(s); ip != nil {
+					if ip.To4() != nil {
+						s += "/32"
+					} else {
+						s += "/128"
+					}
+				}
+			}
+			_, ipNet, err := net.ParseCIDR(s)
+			if err != nil {
+				return nil, fmt.Errorf("无效的网段 %q", toString(arg))
+			}
+			set = append(set, ipNet)
+		}
+		return set, nil
+	}},
+	"contains": {minArgs: 2, maxArgs: 2, call: func(args []interface{}) (interface{}, error) {
+		if list, ok := args[0].([]interface{}); ok {
+			for _, item := range list {
+				if equal(item, args[1]) {
+					return true, nil
+				}
+			}
+			return false, nil
+		}
+		return strings.Contains(toString(args[0]), toString(args[1])), nil
+	}},
+	"startsWith": {minArgs: 2, maxArgs: 2, call: func(args []interface{}) (interface{}, error) {
+		return strings.HasPrefix(toString(args[0]), toString(args[1])), nil
+	}},
+	"endsWith": {minArgs: 2, maxArgs: 2, call: func(args []interface{}) (interface{}, error) {
+		return strings.HasSuffix(toString(args[0]), toString(args[1])), nil
+	}},
+	"matches": {minArgs: 2, maxArgs: 2, check: checkRegexp, call: func(args []interface{}) (interface{}, error) {
+		re, err := compileRegexp(toString(args[1]))
+		if err != nil {
+			return nil, err
+		}
+		return re.MatchString(toString(args[0])), nil
+	}},
+	"lower": {minArgs: 1, maxArgs: 1, call: func(args []interface{}) (interface{}, error) {
+		return strings.ToLower(toString(args[0])), nil
+	}},
+	"upper": {minArgs: 1, maxArgs: 1, call: func(args []interface{}) (interface{}, error) {
+		return strings.ToUpper(toString(args[0])), nil
+	}},
+	"len": {minArgs: 1, maxArgs: 1, call: func(args []interface{}) (interface{}, error) {
+		if list, ok := args[0].([]interface{}); ok {
+			return float64(len(list)), nil
+		}
+		return float64(len([]rune(toString(args[0])))), nil
+	}},
+}
+
+// 正则表达式缓存
+var (
+	regexpCache = make(map[string]*regexp.Regexp)
+	regexpMutex sync.Mutex
+)
+
+// compileRegexp 编译并缓存正则表达式
+func compileRegexp(pattern string) (*regexp.Regexp, error) {
+	regexpMutex.Lock()
+	defer regexpMutex.Unlock()
+
+	if re, ok := regexpCache[pattern]; ok {
+		return re, nil
+	}
+	re, err := regexp.Compile(pattern)
+	if err != nil {
+		return nil, fmt.Errorf("无效的正则表达式 %q: %v", pattern, err)
+	}
+	regexpCache[pattern] = re
+	return re, nil
+}
+
+// checkRegexp 正则参数为字面量时在编译期校验
+func checkRegexp(args []node) error {
+	if lit, ok := args[1].(*literalNode); ok {
+		_, err := compileRegexp(toString(lit.val))
+		return err
+	}
+	return nil
+}
+
+// normalize 将事件数据转换为表达式使用的值类型: string、float64、bool、[]interface{}
+func normalize(v interface{}) interface{} {
+	switch x := v.(type) {
+	case nil:
+		return ""
+	case string, float64, bool, []interface{}:
+		return x
+	case fmt.Stringer:
+		return x.String()
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.String:
+		return rv.String()
+	case reflect.Bool:
+		return rv.Bool()
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return float64(rv.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return float64(rv.Uint())
+	case reflect.Float32, reflect.Float64:
+		return rv.Float()
+	case reflect.Slice, reflect.Array:
+		list := make([]interface{}, rv.Len())
+		for i := range list {
+			list[i] = normalize(rv.Index(i).Interface())
+		}
+		return list
+	}
+	return fmt.Sprint(v)
+}
+
+// truthy 判断值的真假，空字符串、0、空列表为假
+func truthy(v interface{}) bool {
+	switch x := v.(type) {
+	case bool:
+		return x
+	case string:
+		return x != ""
+	case float64:
+		return x != 0
+	case []interface{}:
+		return len(x) > 0
+	case cidrSet:
+		return len(x) > 0
+	}
+	return v != nil
+}
+
+// toString 将值转换为字符串
+func toString(v interface{}) string {
+	switch x := v.(type) {
+	case string:
+		return x
+	case float64:
+		return strconv.FormatFloat(x, 'f', -1, 64)
+	case nil:
+		return ""
+	}
+	return fmt.Sprint(v)
+}
+
+// toNumber 将值转换为数字，字符串需为合法数字
+func toNumber(v interface{}) (float64, bool) {
+	switch x := v.(type) {
+	case float64:
+		return x, true
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(x), 64)
+		return f, err == nil
+	}
+	return 0, false
+}
+
+// equal 判断两个值是否相等，数字与数字字符串按数值比较
+func equal(a, b interface{}) bool {
+	_, aNum := a.(float64)
+	_, bNum := b.(float64)
+	if aNum || bNum {
+		x, ok1 := toNumber(a)
+		y, ok2 := toNumber(b)
+		return ok1 && ok2 && x == y
+	}
+	if x, ok := a.(bool); ok {
+		y, ok := b.(bool)
+		return ok && x == y
+	}
+	if _, ok := b.(bool); ok {
+		return false
+	}
+	return toString(a) == toString(b)
+}
+
+// compare 比较两个值的大小，能转换为数字时按数值比较，否则按字符串比较
+func compare(a, b interface{}) (int, error) {
+	if x, ok := toNumber(a); ok {
+		if y, ok := toNumber(b); ok {
+			switch {
+			case x < y:
+				return -1, nil
+			case x > y:
+				return 1, nil
+			}
+			return 0, nil
+		}
+	}
+
+	x, ok1 := a.(string)
+	y, ok2 := b.(string)
+	if !ok1 || !ok2 {
+		return 0, fmt.Errorf("无法比较 %v 与 %v", a, b)
+	}
+	return strings.Compare(x, y), nil
+}
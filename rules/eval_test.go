@@ -0,0 +1,152 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+)
+
+// event 测试使用的事件数据，字段名大小写与推送流程一致
+var event = map[string]interface{}{
+	"Type":     "fail",
+	"IP":       "203.0.113.7",
+	"User":     "root",
+	"Country":  "CN",
+	"Severity": "warning",
+	"Tags":     []string{"brute", "ssh"},
+	"Fields":   map[string]string{"port": "22"},
+	"attempts": "12",
+	"count":    7,
+	"admin":    true,
+	"pattern":  "[",
+}
+
+func TestEval(t *testing.T) {
+	tests := []struct {
+		expr string
+		want bool
+	}{
+		// 优先级: ! > 比较 > && > ||
+		{`user == "root" || ip == "x" && country == "US"`, true},
+		{`(user == "root" || ip == "x") && country == "US"`, false},
+		{`!user == "admin"`, true},
+		{`!(user == "root" && country == "CN")`, false},
+		{`user == "root" && !admin`, false},
+
+		// 字段名不区分大小写，不存在的字段为空字符串
+		{`USER == "root" && Ip == "203.0.113.7"`, true},
+		{`missing == ""`, true},
+		{`missing`, false},
+
+		// cidr()
+		{`ip in cidr("203.0.113.0/24")`, true},
+		{`ip in cidr("10.0.0.0/8", "203.0.113.7")`, true},
+		{`ip in cidr("10.0.0.0/8", "2001:db8::/32")`, false},
+		{`user in cidr("0.0.0.0/0")`, false},
+		{`!(ip in cidr("192.168.0.0/16"))`, true},
+
+		// 列表与字符串上的 in
+		{`type in ["fail", "ban"]`, true},
+		{`type in ["success"]`, false},
+		{`"ssh" in tags`, true},
+		{`"sftp" in tags`, false},
+		{`"oo" in user`, true},
+		{`"admin" in user`, false},
+		{`attempts in [10, 12]`, true},
+
+		// 数字与数字字符串
+		{`attempts == 12`, true},
+		{`attempts == "12"`, true},
+		{`attempts == 12.0`, true},
+		{`count == "7"`, true},
+		{`attempts > 5`, true},
+		{`attempts < 100`, true},
+		{`attempts > "9"`, true},
+		{`user == 0`, false},
+		{`user != 0`, true},
+
+		// 字符串按字典序比较
+		{`user < "zz"`, true},
+		{`severity >= "warning"`, true},
+
+		// 布尔值
+		{`admin == true`, true},
+		{`admin == "true"`, false},
+		{`admin`, true},
+
+		// 函数
+		{`contains(tags, "brute")`, true},
+		{`contains(user, "oo")`, true},
+		{`startsWith(ip, "203.")`, true},
+		{`endsWith(user, "ot")`, true},
+		{`matches(user, "^r..t$")`, true},
+		{`matches(ip, "^10\.")`, false},
+		{`lower("ROOT") == user`, true},
+		{`upper(user) == "ROOT"`, true},
+		{`len(tags) == 2`, true},
+		{`len(user) == 4`, true},
+	}
+	for _, tt := range tests {
+		expr, err := Compile(tt.expr, nil)
+		if err != nil {
+			t.Errorf("Compile(%s) = %v", tt.expr, err)
+			continue
+		}
+		got, err := expr.Eval(event)
+		if err != nil {
+			t.Errorf("Eval(%s) = %v", tt.expr, err)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Eval(%s) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestShortCircuit(t *testing.T) {
+	// admin < 1 在求值时出错，短路时不会求值右侧
+	tests := []struct {
+		expr    string
+		want    bool
+		wantErr bool
+	}{
+		{`user == "root" || admin < 1`, true, false},
+		{`user == "nobody" && admin < 1`, false, false},
+		{`user == "nobody" || admin < 1`, false, true},
+		{`user == "root" && admin < 1`, false, true},
+		{`user == "nobody" && matches(user, pattern)`, false, false},
+		{`user == "root" && matches(user, pattern)`, false, true},
+	}
+	for _, tt := range tests {
+		expr, err := Compile(tt.expr, nil)
+		if err != nil {
+			t.Errorf("Compile(%s) = %v", tt.expr, err)
+			continue
+		}
+		got, err := expr.Eval(event)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Eval(%s) error = %v, wantErr %v", tt.expr, err, tt.wantErr)
+			continue
+		}
+		if got != tt.want {
+			t.Errorf("Eval(%s) = %v, want %v", tt.expr, got, tt.want)
+		}
+	}
+}
+
+func TestEvalErrorPosition(t *testing.T) {
+	expr, err := Compile(`user == "root" && admin < 1`, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	_, err = expr.Eval(event)
+	e, ok := err.(*Error)
+	if !ok {
+		t.Fatalf("Eval() error = %#v, want *Error", err)
+	}
+	if e.Pos != 25 {
+		t.Errorf("Pos = %d, want 25", e.Pos)
+	}
+	if !strings.HasPrefix(e.Error(), "位置 25: ") {
+		t.Errorf("Error() = %q", e.Error())
+	}
+}
@@ -0,0 +1,135 @@
+package rules
+
+import (
+	"fmt"
+	"strings"
+)
+
+// tokenKind 词法单元类型
+type tokenKind int
+
+const (
+	tokEOF tokenKind = iota
+	tokIdent
+	tokString
+	tokNumber
+	tokOp
+)
+
+// token 词法单元
+type token struct {
+	kind tokenKind
+	text string // 原文，字符串为解码后的内容
+	pos  int    // 在表达式中的位置，从 1 开始
+}
+
+// Error 表达式编译或求值错误
+type Error struct {
+	Pos int    // 出错位置，0 表示未知
+	Msg string // 错误描述
+}
+
+func (e *Error) Error() string {
+	if e.Pos > 0 {
+		return fmt.Sprintf("位置 %d: %s", e.Pos, e.Msg)
+	}
+	return e.Msg
+}
+
+// errorf 创建带位置的错误
+func errorf(pos int, format string, args ...interface{}) *Error {
+	return &Error{Pos: pos, Msg: fmt.Sprintf(format, args...)}
+}
+
+// 双字符运算符
+var twoCharOps = map[string]bool{
+	"==": true, "!=": true, "<=": true, ">=": true, "&&": true, "||": true,
+}
+
+// lex 将表达式切分为词法单元
+func lex(src string) ([]token, error) {
+	var tokens []token
+	i := 0
+	for i < len(src) {
+		c := src[i]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isIdentStart(c):
+			start := i
+			for i < len(src) && isIdentPart(src[i]) {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokIdent, text: src[start:i], pos: start + 1})
+		case isDigit(c):
+			start := i
+			for i < len(src) && (isDigit(src[i]) || src[i] == '.') {
+				i++
+			}
+			tokens = append(tokens, token{kind: tokNumber, text: src[start:i], pos: start + 1})
+		case c == '"' || c == '\'':
+			start := i
+			text, n, ok := readString(src[i:])
+			if !ok {
+				return nil, errorf(start+1, "字符串缺少结束引号")
+			}
+			i += n
+			tokens = append(tokens, token{kind: tokString, text: text, pos: start + 1})
+		default:
+			if i+1 < len(src) && twoCharOps[src[i:i+2]] {
+				tokens = append(tokens, token{kind: tokOp, text: src[i : i+2], pos: i + 1})
+				i += 2
+				continue
+			}
+			if strings.IndexByte("<>!()[],", c) < 0 {
+				return nil, errorf(i+1, "无法识别的字符 %q", c)
+			}
+			tokens = append(tokens, token{kind: tokOp, text: string(c), pos: i + 1})
+			i++
+		}
+	}
+	return append(tokens, token{kind: tokEOF, pos: len(src) + 1}), nil
+}
+
+// readString 读取以引号开头的字符串字面量，返回内容与消耗的字节数
+//
+// 支持 \"、\'、\\、\n、\t 转义，其余反斜杠原样保留，便于书写正则表达式。
+func readString(s string) (string, int, bool) {
+	quote := s[0]
+	var b strings.Builder
+	for i := 1; i < len(s); i++ {
+		c := s[i]
+		if c == quote {
+			return b.String(), i + 1, true
+		}
+		if c == '\\' && i+1 < len(s) {
+			switch s[i+1] {
+			case '"', '\'', '\\':
+				b.WriteByte(s[i+1])
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			default:
+				b.WriteByte(c)
+				continue
+			}
+			i++
+			continue
+		}
+		b.WriteByte(c)
+	}
+	return "", 0, false
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+func isIdentPart(c byte) bool {
+	return isIdentStart(c) || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
@@ -0,0 +1,302 @@
+package rules
+
+import (
+	"strconv"
+	"strings"
+)
+
+// Expr 编译后的规则表达式
+type Expr struct {
+	src  string
+	root node
+}
+
+// String 返回表达式原文
+func (e *Expr) String() string {
+	return e.src
+}
+
+// Compile 编译规则表达式
+//
+// 语法示例: user == "root" && country != "CN"、ip in cidr("10.0.0.0/8")、type in ["fail", "ban"]。
+// 支持 ==、!=、<、<=、>、>=、in、&&、||、! 与括号，字符串、数字、true/false 和 [...] 列表字面量，
+// 以及 cidr、contains、startsWith、endsWith、matches、lower、upper、len 函数。
+// fields 不为空时，只允许引用其中的字段（不区分大小写）。
+func Compile(src string, fields []string) (*Expr, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	if fields != nil {
+		p.fields = make(map[string]bool, len(fields))
+		for _, f := range fields {
+			p.fields[strings.ToLower(f)] = true
+		}
+	}
+
+	if p.peek().kind == tokEOF {
+		return nil, errorf(0, "表达式不能为空")
+	}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+	if t := p.peek(); t.kind != tokEOF {
+		return nil, errorf(t.pos, "多余的内容 %q", t.text)
+	}
+	return &Expr{src: src, root: root}, nil
+}
+
+// parser 递归下降语法分析器
+type parser struct {
+	tokens []token
+	pos    int
+	fields map[string]bool
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokEOF {
+		p.pos++
+	}
+	return t
+}
+
+// isOp 判断下一个词法单元是否为指定运算符
+func (p *parser) isOp(op string) bool {
+	t := p.peek()
+	return t.kind == tokOp && t.text == op
+}
+
+// expect 读取指定运算符，否则报错
+func (p *parser) expect(op string) error {
+	t := p.next()
+	if t.kind != tokOp || t.text != op {
+		return errorf(t.pos, "此处应为 %q，实际为 %s", op, describe(t))
+	}
+	return nil
+}
+
+// parseOr 解析 a || b
+func (p *parser) parseOr() (node, error) {
+	x, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("||") {
+		p.next()
+		y, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		x = &logicalNode{or: true, x: x, y: y}
+	}
+	return x, nil
+}
+
+// parseAnd 解析 a && b
+func (p *parser) parseAnd() (node, error) {
+	x, err := p.parseNot()
+	if err != nil {
+		return nil, err
+	}
+	for p.isOp("&&") {
+		p.next()
+		y, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		x = &logicalNode{x: x, y: y}
+	}
+	return x, nil
+}
+
+// parseNot 解析 !a
+func (p *parser) parseNot() (node, error) {
+	if p.isOp("!") {
+		p.next()
+		x, err := p.parseNot()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{x: x}, nil
+	}
+	return p.parseCompare()
+}
+
+// 比较运算符
+var compareOps = map[string]bool{
+	"==": true, "!=": true, "<": true, "<=": true, ">": true, ">=": true,
+}
+
+// parseCompare 解析比较与 in 运算
+func (p *parser) parseCompare() (node, error) {
+	x, err := p.parsePrimary()
+	if err != nil {
+		return nil, err
+	}
+
+	t := p.peek()
+	switch {
+	case t.kind == tokOp && compareOps[t.text]:
+		p.next()
+		y, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		return &compareNode{op: t.text, x: x, y: y, pos: t.pos}, nil
+	case t.kind == tokIdent && t.text == "in":
+		p.next()
+		y, err := p.parsePrimary()
+		if err != nil {
+			return nil, err
+		}
+		if lit, ok := y.(*literalNode); ok {
+			switch lit.val.(type) {
+			case string, []interface{}, cidrSet:
+			default:
+				return nil, errorf(t.pos, "in 右侧必须是列表、cidr() 或字符串")
+			}
+		}
+		return &inNode{x: x, y: y, pos: t.pos}, nil
+	}
+	return x, nil
+}
+
+// parsePrimary 解析字面量、字段、函数调用、列表与括号
+func (p *parser) parsePrimary() (node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokString:
+		return &literalNode{val: t.text}, nil
+	case tokNumber:
+		f, err := strconv.ParseFloat(t.text, 64)
+		if err != nil {
+			return nil, errorf(t.pos, "无效的数字 %q", t.text)
+		}
+		return &literalNode{val: f}, nil
+	case tokIdent:
+		switch t.text {
+		case "true":
+			return &literalNode{val: true}, nil
+		case "false":
+			return &literalNode{val: false}, nil
+		case "in":
+			return nil, errorf(t.pos, "in 左侧缺少操作数")
+		}
+		if p.isOp("(") {
+			return p.parseCall(t)
+		}
+		if p.fields != nil && !p.fields[strings.ToLower(t.text)] {
+			return nil, errorf(t.pos, "未知字段 %q", t.text)
+		}
+		return &fieldNode{name: t.text}, nil
+	case tokOp:
+		switch t.text {
+		case "(":
+			x, err := p.parseOr()
+			if err != nil {
+				return nil, err
+			}
+			if err := p.expect(")"); err != nil {
+				return nil, err
+			}
+			return x, nil
+		case "[":
+			return p.parseList(t)
+		}
+	}
+	return nil, errorf(t.pos, "此处应为值，实际为 %s", describe(t))
+}
+
+// parseList 解析 [a, b, ...]
+func (p *parser) parseList(open token) (node, error) {
+	var items []node
+	for !p.isOp("]") {
+		if len(items) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		item, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, item)
+	}
+	p.next()
+	return fold(&listNode{items: items}, open.pos)
+}
+
+// parseCall 解析函数调用
+func (p *parser) parseCall(name token) (node, error) {
+	fn, ok := functions[name.text]
+	if !ok {
+		return nil, errorf(name.pos, "未知函数 %q", name.text)
+	}
+	p.next() // (
+
+	var args []node
+	for !p.isOp(")") {
+		if len(args) > 0 {
+			if err := p.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		arg, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	p.next() // )
+
+	if len(args) < fn.minArgs || (fn.maxArgs >= 0 && len(args) > fn.maxArgs) {
+		return nil, errorf(name.pos, "函数 %s 的参数个数错误: %d", name.text, len(args))
+	}
+	if fn.check != nil {
+		if err := fn.check(args); err != nil {
+			return nil, errorf(name.pos, "%s: %v", name.text, err)
+		}
+	}
+	return fold(&callNode{name: name.text, fn: fn, args: args, pos: name.pos}, name.pos)
+}
+
+// fold 参数全部为字面量时在编译期求值，使 cidr("...") 等错误在加载配置时暴露
+func fold(n node, pos int) (node, error) {
+	var args []node
+	switch x := n.(type) {
+	case *listNode:
+		args = x.items
+	case *callNode:
+		args = x.args
+	}
+	for _, arg := range args {
+		if _, ok := arg.(*literalNode); !ok {
+			return n, nil
+		}
+	}
+
+	v, err := n.eval(nil)
+	if err != nil {
+		if e, ok := err.(*Error); ok && e.Pos > 0 {
+			return nil, e
+		}
+		return nil, errorf(pos, "%v", err)
+	}
+	return &literalNode{val: v}, nil
+}
+
+// describe 描述词法单元，用于错误信息
+func describe(t token) string {
+	if t.kind == tokEOF {
+		return "表达式结尾"
+	}
+	return strconv.Quote(t.text)
+}
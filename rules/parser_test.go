@@ -0,0 +1,71 @@
+package rules
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCompileErrors(t *testing.T) {
+	tests := []struct {
+		expr string
+		pos  int
+		msg  string
+	}{
+		{``, 0, "表达式不能为空"},
+		{`user == "root`, 9, "字符串缺少结束引号"},
+		{`user # "root"`, 6, "无法识别的字符"},
+		{`user == `, 9, "此处应为值，实际为 表达式结尾"},
+		{`user == "root" country`, 16, "多余的内容"},
+		{`(user == "root"`, 16, `此处应为 ")"`},
+		{`in ["a"]`, 1, "in 左侧缺少操作数"},
+		{`user in 5`, 6, "in 右侧必须是列表"},
+		{`nosuch(user)`, 1, "未知函数"},
+		{`lower(user, ip)`, 1, "参数个数错误"},
+		{`startsWith(user)`, 1, "参数个数错误"},
+		{`user == "a" && matches(user, "[")`, 16, "无效的正则表达式"},
+		{`matches(user, "(?P<x")`, 1, "无效的正则表达式"},
+		{`ip in cidr("10.0.0.0/33")`, 7, "无效的网段"},
+		{`ip in cidr("not-an-ip")`, 7, "无效的网段"},
+		{`[1, 2`, 6, `此处应为 ","`},
+	}
+	for _, tt := range tests {
+		_, err := Compile(tt.expr, nil)
+		if err == nil {
+			t.Errorf("Compile(%s) 没有返回错误", tt.expr)
+			continue
+		}
+		e, ok := err.(*Error)
+		if !ok {
+			t.Errorf("Compile(%s) error = %#v, want *Error", tt.expr, err)
+			continue
+		}
+		if e.Pos != tt.pos || !strings.Contains(e.Msg, tt.msg) {
+			t.Errorf("Compile(%s) = %d %q, want %d %q", tt.expr, e.Pos, e.Msg, tt.pos, tt.msg)
+		}
+	}
+}
+
+func TestCompileFields(t *testing.T) {
+	fields := []string{"type", "ip", "user"}
+
+	if _, err := Compile(`USER == "root" && Type in ["fail"]`, fields); err != nil {
+		t.Errorf("Compile() = %v", err)
+	}
+
+	_, err := Compile(`user == "root" && contry == "CN"`, fields)
+	e, ok := err.(*Error)
+	if !ok || e.Pos != 19 || !strings.Contains(e.Msg, `未知字段 "contry"`) {
+		t.Errorf("Compile() error = %v", err)
+	}
+}
+
+func TestString(t *testing.T) {
+	src := `ip in cidr("10.0.0.0/8")`
+	expr, err := Compile(src, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expr.String() != src {
+		t.Errorf("String() = %q, want %q", expr.String(), src)
+	}
+}
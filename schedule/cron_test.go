@@ -0,0 +1,145 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func TestParseField(t *testing.T) {
+	// bits 将取值列表转换为位图
+	bits := func(values ...int) uint64 {
+		var b uint64
+		for _, v := range values {
+			b |= 1 << uint(v)
+		}
+		return b
+	}
+
+	tests := []struct {
+		field    string
+		min, max int
+		want     uint64
+	}{
+		{"*", 0, 5, bits(0, 1, 2, 3, 4, 5)},
+		{"3", 0, 59, bits(3)},
+		{"1-4", 0, 59, bits(1, 2, 3, 4)},
+		{"1,5,9", 0, 59, bits(1, 5, 9)},
+		{"*/15", 0, 59, bits(0, 15, 30, 45)},
+		{"10-20/5", 0, 59, bits(10, 15, 20)},
+		// 单个数值带步长时表示从该值开始到最大值
+		{"50/3", 0, 59, bits(50, 53, 56, 59)},
+		{"1-2,*/10", 0, 30, bits(0, 1, 2, 10, 20, 30)},
+		{"1-31/10", 1, 31, bits(1, 11, 21, 31)},
+	}
+	for _, tt := range tests {
+		got, err := parseField(tt.field, tt.min, tt.max)
+		if err != nil || got != tt.want {
+			t.Errorf("parseField(%q, %d, %d) = %b %v, want %b", tt.field, tt.min, tt.max, got, err, tt.want)
+		}
+	}
+}
+
+func TestParseErrors(t *testing.T) {
+	tests := []string{
+		"",
+		"* * * *",
+		"* * * * * *",
+		"60 * * * *",
+		"* 24 * * *",
+		"* * 0 * *",
+		"* * * 13 *",
+		"* * * * 8",
+		"5-1 * * * *",
+		"*/0 * * * *",
+		"*/x * * * *",
+		"a * * * *",
+		"1-x * * * *",
+		"@yearly",
+		"@every 500ms",
+		"@every soon",
+	}
+	for _, spec := range tests {
+		if _, err := Parse(spec); err == nil {
+			t.Errorf("Parse(%q) 没有返回错误", spec)
+		}
+	}
+}
+
+func TestNext(t *testing.T) {
+	// 2024-01-01 为星期一
+	at := func(month time.Month, day, hour, minute int) time.Time {
+		return time.Date(2024, month, day, hour, minute, 0, 0, time.UTC)
+	}
+
+	tests := []struct {
+		spec string
+		from time.Time
+		want time.Time
+	}{
+		{"* * * * *", at(1, 1, 10, 0), at(1, 1, 10, 1)},
+		// 秒数被截断，下一次触发总在 from 之后
+		{"* * * * *", at(1, 1, 10, 0).Add(30 * time.Second), at(1, 1, 10, 1)},
+		{"30 * * * *", at(1, 1, 10, 30), at(1, 1, 11, 30)},
+		{"*/15 * * * *", at(1, 1, 10, 16), at(1, 1, 10, 30)},
+		{"0 9-17/4 * * *", at(1, 1, 13, 0), at(1, 1, 17, 0)},
+		{"0 9-17/4 * * *", at(1, 1, 17, 0), at(1, 2, 9, 0)},
+		{"0 8,20 * * *", at(1, 1, 8, 0), at(1, 1, 20, 0)},
+		{"@hourly", at(1, 1, 10, 59), at(1, 1, 11, 0)},
+		{"@daily", at(1, 1, 10, 0), at(1, 2, 0, 0)},
+		{"@monthly", at(1, 15, 0, 0), at(2, 1, 0, 0)},
+		// 跨年
+		{"0 0 1 1 *", at(1, 1, 0, 0), time.Date(2025, 1, 1, 0, 0, 0, 0, time.UTC)},
+		// 跳过没有 31 日的月份
+		{"0 0 31 * *", at(1, 31, 0, 0), at(3, 31, 0, 0)},
+		// 2024 为闰年
+		{"0 12 29 2 *", at(1, 1, 0, 0), at(2, 29, 12, 0)},
+
+		// 星期：0 与 7 都表示星期日
+		{"0 9 * * 0", at(1, 1, 0, 0), at(1, 7, 9, 0)},
+		{"0 9 * * 7", at(1, 1, 0, 0), at(1, 7, 9, 0)},
+		{"@weekly", at(1, 1, 0, 0), at(1, 7, 0, 0)},
+		{"0 9 * * 1-5", at(1, 5, 9, 0), at(1, 8, 9, 0)},
+		{"0 9 * * 6,0", at(1, 1, 0, 0), at(1, 6, 9, 0)},
+
+		// 日期与星期都有限制时满足其一即可：1 日或星期五
+		{"0 0 1 * 5", at(1, 1, 0, 0), at(1, 5, 0, 0)},
+		{"0 0 1 * 5", at(1, 26, 0, 0), at(2, 1, 0, 0)},
+		// 只有一段有限制时按该段匹配
+		{"0 0 13 * *", at(1, 1, 0, 0), at(1, 13, 0, 0)},
+		{"0 0 * * 5", at(1, 1, 0, 0), at(1, 5, 0, 0)},
+		// 日期为 * 时星期仍然生效
+		{"0 0 * 2 1", at(1, 1, 0, 0), at(2, 5, 0, 0)},
+	}
+	for _, tt := range tests {
+		s, err := Parse(tt.spec)
+		if err != nil {
+			t.Errorf("Parse(%q) = %v", tt.spec, err)
+			continue
+		}
+		if got := s.Next(tt.from); !got.Equal(tt.want) {
+			t.Errorf("Parse(%q).Next(%s) = %s, want %s", tt.spec, tt.from.Format("2006-01-02 15:04 Mon"), got.Format("2006-01-02 15:04 Mon"), tt.want.Format("2006-01-02 15:04 Mon"))
+		}
+	}
+}
+
+func TestNextNever(t *testing.T) {
+	// 2 月 30 日不存在，5 年内找不到时返回零值
+	s, err := Parse("0 0 30 2 *")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := s.Next(time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)); !got.IsZero() {
+		t.Errorf("Next() = %s, want 零值", got)
+	}
+}
+
+func TestEvery(t *testing.T) {
+	s, err := Parse("@every 1h30m")
+	if err != nil {
+		t.Fatal(err)
+	}
+	from := time.Date(2024, 1, 1, 10, 0, 30, 0, time.UTC)
+	if got, want := s.Next(from), from.Add(90*time.Minute); !got.Equal(want) {
+		t.Errorf("Next(%s) = %s, want %s", from, got, want)
+	}
+}
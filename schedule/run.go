@@ -0,0 +1,22 @@
+package schedule
+
+import "time"
+
+// Run 按计划循环执行 fn，直到 stop 被关闭；fn 在当前协程中同步执行
+func Run(s Schedule, stop <-chan struct{}, fn func(now time.Time)) {
+	for {
+		next := s.Next(time.Now())
+		if next.IsZero() {
+			return
+		}
+
+		timer := time.NewTimer(time.Until(next))
+		select {
+		case <-stop:
+			timer.Stop()
+			return
+		case now := <-timer.C:
+			fn(now)
+		}
+	}
+}
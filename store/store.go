@@ -0,0 +1,314 @@
+package store
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"loginfopush/logger"
+	"net"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 事件文件按天分段，文件名为 events-2006-01-02.jsonl
+const (
+	segmentPrefix = "events-"
+	segmentSuffix = ".jsonl"
+	segmentLayout = "2006-01-02"
+)
+
+// maxLineSize 单条记录的最大长度
+const maxLineSize = 1 << 20
+
+// Delivery 一个通知渠道的发送结果
+type Delivery struct {
+	Notifier string `json:"notifier"`
+	Status   string `json:"status"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Record 一条事件记录
+type Record struct {
+	Time       time.Time         `json:"time"`
+	Type       string            `json:"type"`
+	Severity   string            `json:"severity,omitempty"`
+	IP         string            `json:"ip,omitempty"`
+	User       string            `json:"user,omitempty"`
+	Location   string            `json:"location,omitempty"`
+	Country    string            `json:"country,omitempty"`
+	ASN        string            `json:"asn,omitempty"`
+	Details    string            `json:"details,omitempty"`
+	Raw        string            `json:"raw,omitempty"`
+	Fields     map[string]string `json:"fields,omitempty"`
+	Tags       []string          `json:"tags,omitempty"`
+	Status     string            `json:"status"`               // 事件的处理结果
+	Deliveries []Delivery        `json:"deliveries,omitempty"` // 各通知渠道的发送结果
+}
+
+// Filter 查询条件，零值字段不参与过滤
+type Filter struct {
+	Since time.Time // 起始时间（含）
+	Until time.Time // 结束时间（不含）
+	Types []string  // 事件类型
+	IP    string    // IP 地址或网段
+	User  string    // 用户名
+	Limit int       // 最多返回最近的条数
+}
+
+// Store 以按天分段的 JSON Lines 文件保存事件
+type Store struct {
+	mu        sync.Mutex
+	dir       string
+	retention time.Duration
+	readOnly  bool
+	file      *os.File
+	day       string
+}
+
+// Open 打开事件存储目录用于写入，目录不存在时创建，retention 为 0 时不清理旧数据
+//
+// Open 本身不删除数据，过期分段由 Append 切换分段时或调用 Prune 清理。
+func Open(dir string, retention time.Duration) (*Store, error) {
+	if err := os.MkdirAll(dir, 0750); err != nil {
+		return nil, fmt.Errorf("创建事件存储目录失败: %v", err)
+	}
+	return &Store{dir: dir, retention: retention}, nil
+}
+
+// OpenReadOnly 打开事件存储目录用于查询，不创建目录，也不写入或删除任何文件；目录不存在时查询结果为空
+func OpenReadOnly(dir string) (*Store, error) {
+	info, err := os.Stat(dir)
+	if err == nil && !info.IsDir() {
+		return nil, fmt.Errorf("事件存储路径 %s 不是目录", dir)
+	}
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("读取事件存储目录失败: %v", err)
+	}
+	return &Store{dir: dir, readOnly: true}, nil
+}
+
+// errReadOnly 只读打开的存储不能写入
+var errReadOnly = fmt.Errorf("事件存储以只读方式打开")
+
+// Prune 删除在 now 时已超过保留期限的分段
+func (s *Store) Prune(now time.Time) error {
+	if s.readOnly {
+		return errReadOnly
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.prune(now)
+}
+
+// Append 追加一条事件记录
+func (s *Store) Append(r Record) error {
+	if s.readOnly {
+		return errReadOnly
+	}
+	line, err := json.Marshal(r)
+	if err != nil {
+		return fmt.Errorf("序列化事件失败: %v", err)
+	}
+	line = append(line, '\n')
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	day := r.Time.Format(segmentLayout)
+	if s.file == nil || day != s.day {
+		if err := s.openSegment(day); err != nil {
+			return err
+		}
+		if err := s.prune(r.Time); err != nil {
+			logger.Warnf("%v", err)
+		}
+	}
+	if _, err := s.file.Write(line); err != nil {
+		return fmt.Errorf("写入事件失败: %v", err)
+	}
+	return nil
+}
+
+// Close 关闭当前分段文件
+func (s *Store) Close() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.file == nil {
+		return nil
+	}
+	err := s.file.Close()
+	s.file = nil
+	return err
+}
+
+// Query 按条件查询事件，结果按时间升序排列
+func (s *Store) Query(f Filter) ([]Record, error) {
+	match, err := f.matcher()
+	if err != nil {
+		return nil, err
+	}
+	segments, err := s.segments()
+	if err != nil {
+		return nil, err
+	}
+
+	var records []Record
+	for _, seg := range segments {
+		// 跳过时间范围之外的分段
+		if !f.Since.IsZero() && seg.day.Add(24*time.Hour).Before(f.Since) {
+			continue
+		}
+		if !f.Until.IsZero() && !seg.day.Before(f.Until) {
+			continue
+		}
+		if err := readSegment(seg.path, func(r Record) {
+			if match(r) {
+				records = append(records, r)
+			}
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	sort.SliceStable(records, func(i, j int) bool { return records[i].Time.Before(records[j].Time) })
+	if f.Limit > 0 && len(records) > f.Limit {
+		records = records[len(records)-f.Limit:]
+	}
+	return records, nil
+}
+
+// matcher 编译查询条件
+func (f Filter) matcher() (func(Record) bool, error) {
+	var ipNet *net.IPNet
+	if strings.Contains(f.IP, "/") {
+		_, n, err := net.ParseCIDR(f.IP)
+		if err != nil {
+			return nil, fmt.Errorf("无效的网段 %q", f.IP)
+		}
+		ipNet = n
+	}
+	types := make(map[string]bool, len(f.Types))
+	for _, t := range f.Types {
+		types[t] = true
+	}
+
+	return func(r Record) bool {
+		if !f.Since.IsZero() && r.Time.Before(f.Since) {
+			return false
+		}
+		if !f.Until.IsZero() && !r.Time.Before(f.Until) {
+			return false
+		}
+		if len(types) > 0 && !types[r.Type] {
+			return false
+		}
+		if f.User != "" && r.User != f.User {
+			return false
+		}
+		if ipNet != nil {
+			ip := net.ParseIP(r.IP)
+			if ip == nil || !ipNet.Contains(ip) {
+				return false
+			}
+		} else if f.IP != "" && r.IP != f.IP {
+			return false
+		}
+		return true
+	}, nil
+}
+
+// segment 一个分段文件
+type segment struct {
+	path string
+	day  time.Time
+}
+
+// segments 列出所有分段，按日期升序
+func (s *Store) segments() ([]segment, error) {
+	entries, err := os.ReadDir(s.dir)
+	if os.IsNotExist(err) && s.readOnly {
+		// 还没有写入过事件
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取事件存储目录失败: %v", err)
+	}
+	var segments []segment
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || !strings.HasPrefix(name, segmentPrefix) || !strings.HasSuffix(name, segmentSuffix) {
+			continue
+		}
+		day, err := time.ParseInLocation(segmentLayout, strings.TrimSuffix(strings.TrimPrefix(name, segmentPrefix), segmentSuffix), time.Local)
+		if err != nil {
+			continue
+		}
+		segments = append(segments, segment{path: filepath.Join(s.dir, name), day: day})
+	}
+	sort.Slice(segments, func(i, j int) bool { return segments[i].day.Before(segments[j].day) })
+	return segments, nil
+}
+
+// openSegment 打开某天的分段文件，调用方需持有锁
+func (s *Store) openSegment(day string) error {
+	if s.file != nil {
+		s.file.Close()
+		s.file = nil
+	}
+	path := filepath.Join(s.dir, segmentPrefix+day+segmentSuffix)
+	f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_APPEND, 0640)
+	if err != nil {
+		return fmt.Errorf("打开事件文件失败: %v", err)
+	}
+	s.file = f
+	s.day = day
+	return nil
+}
+
+// prune 删除超过保留期限的分段，调用方需持有锁
+func (s *Store) prune(now time.Time) error {
+	if s.retention <= 0 {
+		return nil
+	}
+	segments, err := s.segments()
+	if err != nil {
+		return err
+	}
+	for _, seg := range segments {
+		// 分段中最晚的事件也已超过保留期限
+		if now.Sub(seg.day.Add(24*time.Hour)) <= s.retention {
+			continue
+		}
+		if err := os.Remove(seg.path); err != nil {
+			return fmt.Errorf("删除过期事件文件失败: %v", err)
+		}
+	}
+	return nil
+}
+
+// readSegment 逐行读取分段，无法解析的行被跳过
+func readSegment(path string, fn func(Record)) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("读取事件文件失败: %v", err)
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+	for scanner.Scan() {
+		var r Record
+		if err := json.Unmarshal(scanner.Bytes(), &r); err != nil {
+			continue
+		}
+		fn(r)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("读取事件文件 %s 失败: %v", path, err)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package store
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+// at 2024-01-day hour:00 的本地时间
+func at(day, hour int) time.Time {
+	return time.Date(2024, 1, day, hour, 0, 0, 0, time.Local)
+}
+
+// segmentNames 目录中的分段文件名
+func segmentNames(t *testing.T, dir string) []string {
+	t.Helper()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, e := range entries {
+		names = append(names, e.Name())
+	}
+	sort.Strings(names)
+	return names
+}
+
+func TestAppendRotatesSegments(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "events")
+	s, err := Open(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for _, r := range []Record{
+		{Time: at(1, 10), Type: "fail", IP: "203.0.113.7"},
+		{Time: at(1, 23), Type: "fail", IP: "203.0.113.8"},
+		{Time: at(2, 0), Type: "success", User: "alice"},
+		// 时间较早的事件写回前一天的分段
+		{Time: at(1, 23).Add(30 * time.Minute), Type: "ban", IP: "203.0.113.7"},
+	} {
+		if err := s.Append(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	want := []string{"events-2024-01-01.jsonl", "events-2024-01-02.jsonl"}
+	if got := segmentNames(t, dir); !reflect.DeepEqual(got, want) {
+		t.Errorf("segments = %v, want %v", got, want)
+	}
+
+	var types []string
+	if err := readSegment(filepath.Join(dir, want[0]), func(r Record) { types = append(types, r.Type) }); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(types, []string{"fail", "fail", "ban"}) {
+		t.Errorf("2024-01-01 的事件 = %v", types)
+	}
+}
+
+func TestPrune(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, 2*24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	for day := 1; day <= 4; day++ {
+		if err := s.Append(Record{Time: at(day, 12), Type: "fail"}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 1 日的分段在切换到 3 日 12:00 的分段时尚未超过保留期限，切换到 4 日 12:00 的分段时已超过
+	want := []string{"events-2024-01-02.jsonl", "events-2024-01-03.jsonl", "events-2024-01-04.jsonl"}
+	if got := segmentNames(t, dir); !reflect.DeepEqual(got, want) {
+		t.Errorf("segments = %v, want %v", got, want)
+	}
+
+	if err := s.Prune(at(6, 0).Add(time.Second)); err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"events-2024-01-04.jsonl"}
+	if got := segmentNames(t, dir); !reflect.DeepEqual(got, want) {
+		t.Errorf("Prune() 后 segments = %v, want %v", got, want)
+	}
+}
+
+func TestPruneKeepsForever(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+	if err := s.Append(Record{Time: at(1, 12), Type: "fail"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := s.Prune(at(1, 12).AddDate(10, 0, 0)); err != nil {
+		t.Fatal(err)
+	}
+	if got := segmentNames(t, dir); len(got) != 1 {
+		t.Errorf("retention 为 0 时删除了分段: %v", got)
+	}
+}
+
+func TestQuery(t *testing.T) {
+	dir := t.TempDir()
+	s, err := Open(dir, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	records := []Record{
+		{Time: at(1, 10), Type: "fail", IP: "203.0.113.7", User: "root"},
+		{Time: at(1, 11), Type: "success", IP: "198.51.100.2", User: "alice"},
+		{Time: at(2, 9), Type: "ban", IP: "203.0.113.7"},
+		{Time: at(2, 10), Type: "fail", IP: "2001:db8::1", User: "admin"},
+		{Time: at(3, 8), Type: "fail", IP: "203.0.113.200", User: "root"},
+	}
+	for _, r := range records {
+		if err := s.Append(r); err != nil {
+			t.Fatal(err)
+		}
+	}
+	s.Close()
+
+	// 无法解析的行被跳过
+	f, err := os.OpenFile(filepath.Join(dir, "events-2024-01-02.jsonl"), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	f.WriteString("not json\n")
+	f.Close()
+	// 不是分段的文件被忽略
+	ioutil.WriteFile(filepath.Join(dir, "notes.txt"), []byte("x\n"), 0644)
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   []int // records 中的下标
+	}{
+		{"全部", Filter{}, []int{0, 1, 2, 3, 4}},
+		{"起始时间", Filter{Since: at(2, 9)}, []int{2, 3, 4}},
+		{"结束时间不含", Filter{Until: at(2, 10)}, []int{0, 1, 2}},
+		{"时间范围", Filter{Since: at(1, 11), Until: at(3, 0)}, []int{1, 2, 3}},
+		{"事件类型", Filter{Types: []string{"fail", "ban"}}, []int{0, 2, 3, 4}},
+		{"IP", Filter{IP: "203.0.113.7"}, []int{0, 2}},
+		{"网段", Filter{IP: "203.0.113.0/24"}, []int{0, 2, 4}},
+		{"IPv6 网段", Filter{IP: "2001:db8::/32"}, []int{3}},
+		{"用户", Filter{User: "root"}, []int{0, 4}},
+		{"组合条件", Filter{Types: []string{"fail"}, User: "root", Since: at(2, 0)}, []int{4}},
+		{"最近的条数", Filter{Limit: 2}, []int{3, 4}},
+		{"没有匹配", Filter{User: "nobody"}, nil},
+	}
+	for _, tt := range tests {
+		got, err := s.Query(tt.filter)
+		if err != nil {
+			t.Errorf("%s: Query() = %v", tt.name, err)
+			continue
+		}
+		var want []Record
+		for _, i := range tt.want {
+			want = append(want, records[i])
+		}
+		if len(got) != len(want) {
+			t.Errorf("%s: Query() 返回 %d 条, want %d", tt.name, len(got), len(want))
+			continue
+		}
+		for i := range got {
+			if !got[i].Time.Equal(want[i].Time) || got[i].Type != want[i].Type || got[i].IP != want[i].IP {
+				t.Errorf("%s: Query()[%d] = %s %s %s, want %s %s %s", tt.name, i, got[i].Time, got[i].Type, got[i].IP, want[i].Time, want[i].Type, want[i].IP)
+			}
+		}
+	}
+
+	if _, err := s.Query(Filter{IP: "203.0.113.0/33"}); err == nil {
+		t.Error("无效的网段没有返回错误")
+	}
+}
+
+func TestOpenReadOnly(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), "events")
+	s, err := OpenReadOnly(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// 不创建目录，目录不存在时查询结果为空
+	if _, err := os.Stat(dir); !os.IsNotExist(err) {
+		t.Errorf("OpenReadOnly() 创建了目录: %v", err)
+	}
+	if records, err := s.Query(Filter{}); err != nil || len(records) != 0 {
+		t.Errorf("Query() = %v %v, want 空结果", records, err)
+	}
+	if err := s.Append(Record{Time: at(1, 10), Type: "fail"}); err == nil {
+		t.Error("只读存储 Append() 没有返回错误")
+	}
+	if err := s.Prune(at(1, 10)); err == nil {
+		t.Error("只读存储 Prune() 没有返回错误")
+	}
+
+	// 读取已有的事件，不删除过期分段
+	w, err := Open(dir, time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	w.Append(Record{Time: at(1, 10), Type: "fail"})
+	w.Close()
+	records, err := s.Query(Filter{})
+	if err != nil || len(records) != 1 {
+		t.Errorf("Query() = %v %v, want 1 条", records, err)
+	}
+	if got := segmentNames(t, dir); len(got) != 1 {
+		t.Errorf("segments = %v", got)
+	}
+
+	file := filepath.Join(t.TempDir(), "file")
+	ioutil.WriteFile(file, nil, 0644)
+	if _, err := OpenReadOnly(file); err == nil {
+		t.Error("OpenReadOnly(文件) 没有返回错误")
+	}
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"loginfopush/config"
+	_func "loginfopush/func"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+)
+
+// testNotifyUsage test-notify 子命令的帮助信息
+const testNotifyUsage = `用法: loginfopush test-notify [选项]
+
+按事件配置的模板渲染一条示例事件并发送到通知渠道，检查各渠道的配置是否可用，例如:
+  loginfopush test-notify
+  loginfopush test-notify --type ban --notifier telegram,bark
+
+选项:
+`
+
+// runTestNotify 执行 test-notify 子命令
+func runTestNotify(args []string) error {
+	var g globalFlags
+	fs := newFlagSet("test-notify", testNotifyUsage, &g)
+	eventType := fs.String("type", string(config.EventTypeSuccess), "示例事件的类型，如 success、fail、ban、report 或自定义事件类型")
+	notifiers := fs.String("notifier", "", "通知渠道名称，多个用逗号分隔，默认为所有启用的通知渠道")
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+
+	cfg, _, err := g.load()
+	if err != nil {
+		return err
+	}
+	var names []string
+	for _, name := range strings.Split(*notifiers, ",") {
+		if name = strings.TrimSpace(name); name != "" {
+			names = append(names, name)
+		}
+	}
+
+	msg, results, err := _func.TestNotify(cfg, config.EventType(*eventType), names)
+	if err != nil {
+		return err
+	}
+	if len(results) == 0 {
+		return fmt.Errorf("没有启用的通知渠道")
+	}
+
+	fmt.Printf("标题: %s\n%s\n\n", msg.Title, msg.Content)
+
+	failed := 0
+	w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "通知渠道\t结果\t耗时\t错误")
+	for _, r := range results {
+		status, errText := "成功", "-"
+		if r.Err != nil {
+			failed++
+			status, errText = "失败", r.Err.Error()
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", r.Notifier, status, r.Latency.Round(time.Millisecond), errText)
+	}
+	w.Flush()
+
+	if failed > 0 {
+		return fmt.Errorf("%d 个通知渠道发送失败", failed)
+	}
+	return nil
+}